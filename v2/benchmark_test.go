@@ -226,6 +226,29 @@ func BenchmarkParse_Complex(b *testing.B) {
 	}
 }
 
+// plainQueryString has many values without percent-encoding or '+'.
+var plainQueryString = "name=John&city=Berlin&country=DE&lang=en&sort=asc&page=1&limit=50&fields=id&format=json&debug=false"
+
+func BenchmarkParse_PlainASCII(b *testing.B) {
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		_, err := Parse(plainQueryString)
+		if err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkParseFlatString_PlainASCII(b *testing.B) {
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		_, err := ParseFlatString(plainQueryString)
+		if err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
 func BenchmarkParse_Giant(b *testing.B) {
 	b.ReportAllocs()
 	for i := 0; i < b.N; i++ {
@@ -300,6 +323,57 @@ func BenchmarkStringify_Giant(b *testing.B) {
 	}
 }
 
+func BenchmarkStringifyBytes_Giant(b *testing.B) {
+	var buf []byte
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		var err error
+		buf, err = StringifyBytes(buf[:0], giantNestedData)
+		if err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkParseBytes(b *testing.B) {
+	data := []byte(giantNestedQueryString)
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		_, err := ParseBytes(data)
+		if err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkDecoder_Simple(b *testing.B) {
+	dec, err := NewDecoder()
+	if err != nil {
+		b.Fatal(err)
+	}
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		_, err := dec.Parse(simpleQueryString)
+		if err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkEncoder_Simple(b *testing.B) {
+	enc, err := NewEncoder()
+	if err != nil {
+		b.Fatal(err)
+	}
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		_, err := enc.Stringify(simpleData)
+		if err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
 // =============================================================================
 // Benchmarks: Parallel
 // =============================================================================
@@ -0,0 +1,85 @@
+// Copyright 2025 Zaytra
+// SPDX-License-Identifier: Apache-2.0
+
+package qs
+
+import "io"
+
+// Encoder stringifies values with options that NewEncoder validated and
+// normalized once, saving that work on every call. It holds no mutable
+// state, so it is safe for concurrent use as long as the functions and
+// maps set in its options are.
+type Encoder struct {
+	opts StringifyOptions
+}
+
+// NewEncoder returns an Encoder for the given options, or the error
+// Stringify would return for them.
+//
+// Example:
+//
+//	enc, err := qs.NewEncoder(qs.WithStringifyArrayFormat(qs.ArrayFormatBrackets))
+//	str, err := enc.Stringify(map[string]any{"a": []any{"b"}})
+//	// str = "a%5B%5D=b"
+func NewEncoder(opts ...StringifyOption) (*Encoder, error) {
+	options := applyStringifyOptions(opts...)
+
+	// Normalize options
+	normalizedOpts, err := normalizeStringifyOptions(&options)
+	if err != nil {
+		return nil, err
+	}
+	return &Encoder{opts: normalizedOpts}, nil
+}
+
+// Stringify is Stringify with the Encoder's options.
+func (e *Encoder) Stringify(obj any) (string, error) {
+	return stringifyNormalized(obj, nil, &e.opts)
+}
+
+// StringifyTo is StringifyTo with the Encoder's options.
+func (e *Encoder) StringifyTo(w io.Writer, obj map[string]any) error {
+	return writeTo(w, obj, &e.opts)
+}
+
+// StringifyBytes is StringifyBytes with the Encoder's options.
+func (e *Encoder) StringifyBytes(dst []byte, obj any) ([]byte, error) {
+	return appendPairs(dst, obj, &e.opts)
+}
+
+// Decoder parses query strings with options that NewDecoder validated and
+// normalized once, saving that work on every call. It holds no mutable
+// state, so it is safe for concurrent use as long as the functions and
+// maps set in its options are.
+type Decoder struct {
+	opts ParseOptions
+}
+
+// NewDecoder returns a Decoder for the given options, or the error Parse
+// would return for them.
+//
+// Example:
+//
+//	dec, err := qs.NewDecoder(qs.WithParseAllowDots(true))
+//	result, err := dec.Parse("a.b=c")
+//	// result = map[string]any{"a": map[string]any{"b": "c"}}
+func NewDecoder(opts ...ParseOption) (*Decoder, error) {
+	options := applyParseOptions(opts...)
+
+	// Normalize options
+	normalizedOpts, err := normalizeParseOptions(&options)
+	if err != nil {
+		return nil, err
+	}
+	return &Decoder{opts: normalizedOpts}, nil
+}
+
+// Parse is Parse with the Decoder's options.
+func (d *Decoder) Parse(str string) (map[string]any, error) {
+	return parseNormalized(str, &d.opts)
+}
+
+// ParseBytes is ParseBytes with the Decoder's options.
+func (d *Decoder) ParseBytes(b []byte) (map[string]any, error) {
+	return d.Parse(string(b))
+}
@@ -0,0 +1,104 @@
+// Copyright 2025 Zaytra
+// SPDX-License-Identifier: Apache-2.0
+
+package qs
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func TestEncoder(t *testing.T) {
+	obj := map[string]any{"a": []any{"1", "2"}, "b": map[string]any{"c": "d e"}}
+	opts := []StringifyOption{
+		WithStringifyArrayFormat(ArrayFormatBrackets),
+		WithStringifySort(SortKeysAsc()),
+		WithStringifyAddQueryPrefix(true),
+	}
+	want, err := Stringify(obj, opts...)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	enc, err := NewEncoder(opts...)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			got, err := enc.Stringify(obj)
+			if err != nil || got != want {
+				t.Errorf("got %q, %v, want %q", got, err, want)
+			}
+		}()
+	}
+	wg.Wait()
+
+	var b strings.Builder
+	if err := enc.StringifyTo(&b, obj); err != nil || b.String() != want {
+		t.Errorf("StringifyTo got %q, %v, want %q", b.String(), err, want)
+	}
+	if got, err := enc.StringifyBytes(nil, obj); err != nil || string(got) != want {
+		t.Errorf("StringifyBytes got %q, %v, want %q", got, err, want)
+	}
+
+	if _, err := NewEncoder(WithStringifyFormat("bogus")); !errors.Is(err, ErrInvalidFormat) {
+		t.Errorf("got error %v, want ErrInvalidFormat", err)
+	}
+}
+
+func TestDecoder(t *testing.T) {
+	input := "a.b=1&a.c=2&d[]=x"
+	opts := []ParseOption{WithParseAllowDots(true), WithParseParameterLimit(2)}
+	want, err := Parse(input, opts...)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	dec, err := NewDecoder(opts...)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			got, err := dec.Parse(input)
+			if err != nil || !reflect.DeepEqual(got, want) {
+				t.Errorf("got %v, %v, want %v", got, err, want)
+			}
+		}()
+	}
+	wg.Wait()
+
+	if got, err := dec.ParseBytes([]byte(input)); err != nil || !reflect.DeepEqual(got, want) {
+		t.Errorf("ParseBytes got %v, %v, want %v", got, err, want)
+	}
+
+	// Container hooks run as in Parse
+	var paths [][]string
+	hooked, err := NewDecoder(WithParseContainerHook(func(path []string, kind ContainerKind) error {
+		paths = append(paths, append([]string(nil), path...))
+		return nil
+	}))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, err := hooked.Parse("a[b]=1"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if want := [][]string{nil, {"a"}}; !reflect.DeepEqual(paths, want) {
+		t.Errorf("hook paths = %v, want %v", paths, want)
+	}
+
+	if _, err := NewDecoder(WithParseCharset("bogus")); !errors.Is(err, ErrInvalidCharset) {
+		t.Errorf("got error %v, want ErrInvalidCharset", err)
+	}
+}
@@ -0,0 +1,254 @@
+// Copyright 2025 Zaytra
+// SPDX-License-Identifier: Apache-2.0
+
+package qs
+
+import (
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// flattenEscaper escapes only the character Flatten uses to split each
+// stringified pair into its key and value.
+var flattenEscaper = strings.NewReplacer("%", "%25", "=", "%3D")
+
+// flattenUnescaper reverses flattenEscaper.
+var flattenUnescaper = strings.NewReplacer("%25", "%", "%3D", "=")
+
+// Flatten converts a nested map into a flat map of stringified keys to
+// values, using the same key syntax Stringify produces but without
+// URL-encoding. It is useful for feeding nested data into flat key/value
+// stores such as environment variables or config systems.
+//
+// Key-shaping options (ArrayFormat, AllowDots, EncodeDotInKeys,
+// AllowEmptyArrays, CommaRoundTrip, Filter, SkipNulls, SerializeDate) are
+// honored. Encoding, formatting, delimiter, prefix and charset options are
+// ignored, as are those applied to the joined query, such as SortPairs,
+// ASCIIOnly and ChecksumFunc. Null values become empty strings.
+//
+// Example:
+//
+//	flat, err := qs.Flatten(map[string]any{"a": map[string]any{"b": "c"}})
+//	// flat = map[string]string{"a[b]": "c"}
+//
+//	flat, err := qs.Flatten(map[string]any{"a": map[string]any{"b": "c"}}, qs.WithStringifyAllowDots(true))
+//	// flat = map[string]string{"a.b": "c"}
+func Flatten(m map[string]any, opts ...StringifyOption) (map[string]string, error) {
+	result := make(map[string]string)
+	err := stringifyPairs(m, opts, func(key, value string) {
+		result[key] = value
+	})
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// ToURLValues converts a nested map into url.Values, using the same key
+// syntax Stringify produces but without URL-encoding, for APIs that take
+// url.Values. Options are honored as in Flatten. Keys written more than
+// once, as with ArrayFormatRepeat, get one value per occurrence, and keys
+// written without a value, as null values with StrictNullHandling, get an
+// empty value.
+//
+// Example:
+//
+//	v, err := qs.ToURLValues(map[string]any{"a": []any{"b", "c"}}, qs.WithStringifyArrayFormat(qs.ArrayFormatRepeat))
+//	// v = url.Values{"a": {"b", "c"}}
+func ToURLValues(m map[string]any, opts ...StringifyOption) (url.Values, error) {
+	result := make(url.Values)
+	err := stringifyPairs(m, opts, func(key, value string) {
+		result.Add(key, value)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// stringifyPairs walks m as Stringify does, but without URL-encoding, and
+// calls add for each key/value pair in output order. Options that apply
+// to the joined query, such as SortPairs, ASCIIOnly and ChecksumFunc, have
+// no effect.
+func stringifyPairs(m map[string]any, opts []StringifyOption, add func(key, value string)) error {
+	options := applyStringifyOptions(opts...)
+
+	// Encode with an encoder that escapes only '=', so each pair can be
+	// split into its key and value and then unescaped.
+	options.Encode = true
+	options.EncodeValuesOnly = false
+	options.Encoder = func(str string, charset Charset, kind string, format Format) string {
+		return flattenEscaper.Replace(str)
+	}
+	options.Formatter = formatRFC3986
+	options.Delimiter = "&"
+
+	normalizedOpts, err := normalizeStringifyOptions(&options)
+	if err != nil {
+		return err
+	}
+	return walkPairs(m, nil, &normalizedOpts, func(pairs []string) error {
+		for _, pair := range pairs {
+			key, value, _ := strings.Cut(pair, "=")
+			add(flattenUnescaper.Replace(key), flattenUnescaper.Replace(value))
+		}
+		return nil
+	})
+}
+
+// Unflatten rebuilds a nested map from flat keys such as "a[b]" or "a.b",
+// applying the same nesting rules as Parse but without URL-decoding keys
+// or values. It is the inverse of Flatten.
+//
+// Nesting options (AllowDots, Depth, ArrayLimit, ParseArrays, Comma,
+// StrictNullHandling, ...) are honored. Decoding, delimiter, prefix and
+// charset options are ignored.
+//
+// Example:
+//
+//	m, err := qs.Unflatten(map[string]string{"a[b]": "c%20d"})
+//	// m = map[string]any{"a": map[string]any{"b": "c%20d"}}
+func Unflatten(flat map[string]string, opts ...ParseOption) (map[string]any, error) {
+	keys := make([]string, 0, len(flat))
+	for k := range flat {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	pairs := make([]flatPair, len(keys))
+	for i, k := range keys {
+		pairs[i] = flatPair{key: k, value: flat[k], hasValue: true}
+	}
+	return parseFlatPairs(pairs, opts)
+}
+
+// FromURLValues rebuilds a nested map from url.Values, such as those of
+// http.Request.URL.Query(), applying the same nesting rules as Parse to
+// the already decoded keys and values. It saves stringifying the values
+// only to parse them again.
+//
+// Nesting options are honored as in Unflatten. A key with several values
+// holds them as repeated keys do in Parse, following Duplicates. With
+// StrictNullHandling, empty values become nil, as keys without '=' do in
+// Parse. Keys are read in sorted order, since url.Values has none.
+//
+// Example:
+//
+//	m, err := qs.FromURLValues(url.Values{"a[b]": {"c"}, "d": {"e", "f"}})
+//	// m = map[string]any{"a": map[string]any{"b": "c"}, "d": []any{"e", "f"}}
+func FromURLValues(v url.Values, opts ...ParseOption) (map[string]any, error) {
+	keys := make([]string, 0, len(v))
+	for k := range v {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	strictNullHandling := applyParseOptions(opts...).StrictNullHandling
+
+	var pairs []flatPair
+	for _, k := range keys {
+		for _, value := range v[k] {
+			hasValue := value != "" || !strictNullHandling
+			pairs = append(pairs, flatPair{key: k, value: value, hasValue: hasValue})
+		}
+	}
+	return parseFlatPairs(pairs, opts)
+}
+
+// flatPair is a key/value pair read by Unflatten or FromURLValues.
+// hasValue is false for a key read as if written without '='.
+type flatPair struct {
+	key, value string
+	hasValue   bool
+}
+
+// parseFlatPairs nests already split and decoded pairs the way Parse nests
+// the pairs it splits from a query string. MaxInputLength applies to the
+// pairs joined as a query without further encoding.
+func parseFlatPairs(pairs []flatPair, opts []ParseOption) (map[string]any, error) {
+	options := applyParseOptions(opts...)
+	normalizedOpts, err := normalizeParseOptions(&options)
+	if err != nil {
+		return nil, err
+	}
+
+	size := 0
+	for i, pair := range pairs {
+		if i > 0 {
+			size++
+		}
+		size += len(pair.key)
+		if pair.hasValue {
+			size += 1 + len(pair.value)
+		}
+	}
+	if err := checkInputLength(size, &normalizedOpts); err != nil {
+		return nil, err
+	}
+
+	// Keys and values are decoded already, so nothing is decoded again
+	normalizedOpts.InterpretNumericEntities = false
+	decoder := func(str string, charset Charset, kind string) (string, error) {
+		return str, nil
+	}
+
+	p := newPairParser(len(pairs), normalizedOpts.Charset, decoder, &normalizedOpts)
+	for _, pair := range pairs {
+		if normalizedOpts.RejectControlChars {
+			for _, s := range []string{pair.key, pair.value} {
+				if err := checkControlChars(s, normalizedOpts.AllowedControlChars); err != nil {
+					return nil, err
+				}
+			}
+		}
+		wrapArray := pair.hasValue && strings.HasSuffix(pair.key, "[]")
+		if err := p.add(pair.key, pair.value, pair.hasValue, wrapArray); err != nil {
+			return nil, err
+		}
+	}
+
+	result, err := p.result()
+	if err != nil {
+		return nil, err
+	}
+	return finishParse(result, &normalizedOpts)
+}
+
+// flatKeyEscaper escapes the dots FlatResult joins key segments with, and
+// the brackets and '%' that could otherwise be confused with them, so that
+// distinct paths never share a flat key.
+var flatKeyEscaper = strings.NewReplacer("%", "%25", ".", "%2E", "[", "%5B", "]", "%5D")
+
+// flattenResult converts a parse result into a map keyed by dotted paths
+// for FlatResult. Array elements are keyed by index; empty maps and
+// arrays are kept as values. Map keys are escaped with flatKeyEscaper.
+func flattenResult(m map[string]any) map[string]any {
+	out := make(map[string]any, len(m))
+	var walk func(path string, v any)
+	walk = func(path string, v any) {
+		switch t := v.(type) {
+		case map[string]any:
+			if len(t) == 0 {
+				out[path] = t
+			}
+			for k, item := range t {
+				walk(path+"."+flatKeyEscaper.Replace(k), item)
+			}
+		case []any:
+			if len(t) == 0 {
+				out[path] = t
+			}
+			for i, item := range t {
+				walk(path+"."+strconv.Itoa(i), item)
+			}
+		default:
+			out[path] = v
+		}
+	}
+	for k, v := range m {
+		walk(flatKeyEscaper.Replace(k), v)
+	}
+	return out
+}
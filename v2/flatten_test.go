@@ -0,0 +1,376 @@
+// Copyright 2025 Zaytra
+// SPDX-License-Identifier: Apache-2.0
+
+package qs
+
+import (
+	"errors"
+	"net/url"
+	"reflect"
+	"strconv"
+	"testing"
+)
+
+func TestFlatten(t *testing.T) {
+	tests := []struct {
+		name  string
+		input map[string]any
+		opts  []StringifyOption
+		want  map[string]string
+	}{
+		{
+			name:  "nested map",
+			input: map[string]any{"a": map[string]any{"b": "c"}},
+			want:  map[string]string{"a[b]": "c"},
+		},
+		{
+			name:  "allow dots",
+			input: map[string]any{"a": map[string]any{"b": map[string]any{"c": "d"}}},
+			opts:  []StringifyOption{WithStringifyAllowDots(true)},
+			want:  map[string]string{"a.b.c": "d"},
+		},
+		{
+			name:  "arrays use indices by default",
+			input: map[string]any{"a": []any{"x", "y"}},
+			want:  map[string]string{"a[0]": "x", "a[1]": "y"},
+		},
+		{
+			name:  "comma array format",
+			input: map[string]any{"a": []any{"x", "y"}},
+			opts:  []StringifyOption{WithStringifyArrayFormat(ArrayFormatComma)},
+			want:  map[string]string{"a": "x,y"},
+		},
+		{
+			name:  "values are not encoded",
+			input: map[string]any{"a b": "c&d=e%f ü"},
+			want:  map[string]string{"a b": "c&d=e%f ü"},
+		},
+		{
+			name:  "encoding options ignored",
+			input: map[string]any{"a": "b c"},
+			opts:  []StringifyOption{WithStringifyFormat(FormatRFC1738), WithStringifyAddQueryPrefix(true), WithStringifyDelimiter(";")},
+			want:  map[string]string{"a": "b c"},
+		},
+		{
+			name:  "nulls become empty strings",
+			input: map[string]any{"a": nil, "b": ""},
+			opts:  []StringifyOption{WithStringifyStrictNullHandling(true)},
+			want:  map[string]string{"a": "", "b": ""},
+		},
+		{
+			name:  "skip nulls",
+			input: map[string]any{"a": nil, "b": "c"},
+			opts:  []StringifyOption{WithStringifySkipNulls(true)},
+			want:  map[string]string{"b": "c"},
+		},
+		{
+			name:  "empty input",
+			input: map[string]any{},
+			want:  map[string]string{},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Flatten(tt.input, tt.opts...)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestUnflatten(t *testing.T) {
+	tests := []struct {
+		name  string
+		input map[string]string
+		opts  []ParseOption
+		want  map[string]any
+	}{
+		{
+			name:  "bracket keys",
+			input: map[string]string{"a[b]": "c", "a[d]": "e"},
+			want:  map[string]any{"a": map[string]any{"b": "c", "d": "e"}},
+		},
+		{
+			name:  "dotted keys",
+			input: map[string]string{"a.b.c": "d"},
+			opts:  []ParseOption{WithParseAllowDots(true)},
+			want:  map[string]any{"a": map[string]any{"b": map[string]any{"c": "d"}}},
+		},
+		{
+			name:  "indexed arrays",
+			input: map[string]string{"a[1]": "y", "a[0]": "x"},
+			want:  map[string]any{"a": []any{"x", "y"}},
+		},
+		{
+			name:  "values are not decoded",
+			input: map[string]string{"a": "b%20c+d&e=f"},
+			want:  map[string]any{"a": "b%20c+d&e=f"},
+		},
+		{
+			name:  "keys are not decoded",
+			input: map[string]string{"a%5Bb%5D": "c", "x+y": "z"},
+			want:  map[string]any{"a%5Bb%5D": "c", "x+y": "z"},
+		},
+		{
+			name:  "decoding options ignored",
+			input: map[string]string{"?a": "b"},
+			opts:  []ParseOption{WithParseIgnoreQueryPrefix(true), WithParseDelimiter(";")},
+			want:  map[string]any{"?a": "b"},
+		},
+		{
+			name:  "depth honored",
+			input: map[string]string{"a[b][c]": "d"},
+			opts:  []ParseOption{WithParseDepth(1)},
+			want:  map[string]any{"a": map[string]any{"b": map[string]any{"[c]": "d"}}},
+		},
+		{
+			name:  "empty input",
+			input: map[string]string{},
+			want:  map[string]any{},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Unflatten(tt.input, tt.opts...)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFlattenRoundTrip(t *testing.T) {
+	input := map[string]any{
+		"a": map[string]any{"b": "c&d", "e": []any{"f", "g=h"}},
+		"i": "50% off",
+	}
+
+	flat, err := Flatten(input)
+	if err != nil {
+		t.Fatalf("Flatten: %v", err)
+	}
+	got, err := Unflatten(flat)
+	if err != nil {
+		t.Fatalf("Unflatten: %v", err)
+	}
+	if !reflect.DeepEqual(got, input) {
+		t.Errorf("got %v, want %v", got, input)
+	}
+}
+
+func TestUnflattenManyKeys(t *testing.T) {
+	flat := make(map[string]string, DefaultParameterLimit+10)
+	for i := 0; i < DefaultParameterLimit+10; i++ {
+		flat["k"+strconv.Itoa(i)] = "v"
+	}
+
+	got, err := Unflatten(flat)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(got) != len(flat) {
+		t.Errorf("got %d keys, want %d", len(got), len(flat))
+	}
+}
+
+func TestFromURLValues(t *testing.T) {
+	tests := []struct {
+		name  string
+		input url.Values
+		opts  []ParseOption
+		want  map[string]any
+	}{
+		{
+			name:  "brackets",
+			input: url.Values{"a[b]": {"c"}, "a[d][]": {"e", "f"}},
+			want:  map[string]any{"a": map[string]any{"b": "c", "d": []any{"e", "f"}}},
+		},
+		{
+			name:  "values are not decoded again",
+			input: url.Values{"a": {"100%&b=c+d"}},
+			want:  map[string]any{"a": "100%&b=c+d"},
+		},
+		{
+			name:  "keys are not decoded again",
+			input: url.Values{"a%25": {"b"}, "c[d%5D]": {"e"}},
+			want:  map[string]any{"a%25": "b", "c": map[string]any{"d%5D": "e"}},
+		},
+		{
+			name:  "dots",
+			input: url.Values{"a.b": {"c"}},
+			opts:  []ParseOption{WithParseAllowDots(true)},
+			want:  map[string]any{"a": map[string]any{"b": "c"}},
+		},
+		{
+			name:  "comma",
+			input: url.Values{"a": {"b,c"}},
+			opts:  []ParseOption{WithParseComma(true)},
+			want:  map[string]any{"a": []any{"b", "c"}},
+		},
+		{
+			name:  "array limit",
+			input: url.Values{"a[30]": {"b"}},
+			want:  map[string]any{"a": map[string]any{"30": "b"}},
+		},
+		{
+			name:  "multiple values combine",
+			input: url.Values{"a": {"b", "c"}},
+			want:  map[string]any{"a": []any{"b", "c"}},
+		},
+		{
+			name:  "multiple values keep first",
+			input: url.Values{"a": {"b", "c"}},
+			opts:  []ParseOption{WithParseDuplicates(DuplicateFirst)},
+			want:  map[string]any{"a": "b"},
+		},
+		{
+			name:  "multiple values keep last",
+			input: url.Values{"a": {"b", "c"}},
+			opts:  []ParseOption{WithParseDuplicates(DuplicateLast)},
+			want:  map[string]any{"a": "c"},
+		},
+		{
+			name:  "empty value",
+			input: url.Values{"a": {""}},
+			want:  map[string]any{"a": ""},
+		},
+		{
+			name:  "keys and values are not split again",
+			input: url.Values{"?a;b": {"c&d=e"}},
+			opts:  []ParseOption{WithParseIgnoreQueryPrefix(true), WithParseDelimiter(";")},
+			want:  map[string]any{"?a;b": "c&d=e"},
+		},
+		{
+			name:  "empty value with strict null handling",
+			input: url.Values{"a": {""}, "b": {"c"}},
+			opts:  []ParseOption{WithParseStrictNullHandling(true)},
+			want:  map[string]any{"a": nil, "b": "c"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := FromURLValues(tt.input, tt.opts...)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got %v, want %v", got, tt.want)
+			}
+		})
+	}
+
+	t.Run("MaxInputLength counts the input", func(t *testing.T) {
+		// "a=100%&b=" is 9 bytes; escaping '%' would make it longer
+		v := url.Values{"a": {"100%"}, "b": {""}}
+		if _, err := FromURLValues(v, WithParseMaxInputLength(9)); err != nil {
+			t.Errorf("unexpected error: %v", err)
+		}
+		if _, err := FromURLValues(v, WithParseMaxInputLength(8)); !errors.Is(err, ErrInputTooLong) {
+			t.Errorf("got error %v, want ErrInputTooLong", err)
+		}
+	})
+}
+
+func TestToURLValues(t *testing.T) {
+	tests := []struct {
+		name  string
+		input map[string]any
+		opts  []StringifyOption
+		want  url.Values
+	}{
+		{
+			name:  "nested",
+			input: map[string]any{"a": map[string]any{"b": "100%&c=d"}},
+			want:  url.Values{"a[b]": {"100%&c=d"}},
+		},
+		{
+			name:  "indices",
+			input: map[string]any{"a": []any{"b", "c"}},
+			want:  url.Values{"a[0]": {"b"}, "a[1]": {"c"}},
+		},
+		{
+			name:  "repeat",
+			input: map[string]any{"a": []any{"b", "c"}},
+			opts:  []StringifyOption{WithStringifyArrayFormat(ArrayFormatRepeat)},
+			want:  url.Values{"a": {"b", "c"}},
+		},
+		{
+			name:  "strict null handling",
+			input: map[string]any{"a": nil},
+			opts:  []StringifyOption{WithStringifyStrictNullHandling(true)},
+			want:  url.Values{"a": {""}},
+		},
+		{
+			name:  "empty",
+			input: map[string]any{},
+			want:  url.Values{},
+		},
+		{
+			name:  "query options ignored",
+			input: map[string]any{"a": "ü", "b": "c"},
+			opts: []StringifyOption{
+				WithStringifyASCIIOnly(true),
+				WithStringifyChecksum("sum", func([]byte) string { return "x" }),
+				WithStringifySortPairs(SortKeysDesc()),
+			},
+			want: url.Values{"a": {"ü"}, "b": {"c"}},
+		},
+		{
+			name:  "bracket keys and empty values",
+			input: map[string]any{"a[b]": "", "c": map[string]any{"d[e]": "f"}},
+			want:  url.Values{"a[b]": {""}, "c[d[e]]": {"f"}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ToURLValues(tt.input, tt.opts...)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got %v, want %v", got, tt.want)
+			}
+		})
+	}
+
+	t.Run("round trip", func(t *testing.T) {
+		m := map[string]any{"a": map[string]any{"b": []any{"c", "d"}}, "e": "f g"}
+		v, err := ToURLValues(m)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		got, err := FromURLValues(v)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if !reflect.DeepEqual(got, m) {
+			t.Errorf("got %v, want %v", got, m)
+		}
+	})
+
+	t.Run("encoded values parse back", func(t *testing.T) {
+		m := map[string]any{"x": map[string]any{"y": "z"}, "a": []any{"b"}}
+		v, err := ToURLValues(m)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		got, err := Parse(v.Encode())
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if !reflect.DeepEqual(got, m) {
+			t.Errorf("got %v, want %v", got, m)
+		}
+	})
+}
@@ -0,0 +1,146 @@
+// Copyright 2025 Zaytra
+// SPDX-License-Identifier: Apache-2.0
+
+package qs
+
+import (
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"errors"
+	"hash"
+	"sort"
+	"strings"
+)
+
+// Errors returned by VerifyChecksum.
+var (
+	ErrChecksumMissing  = errors.New("checksum parameter missing")
+	ErrChecksumMismatch = errors.New("checksum mismatch")
+)
+
+// Hash parses a query string and returns a hex SHA-256 digest of its
+// canonical form. Queries that differ only in key order or in the order of
+// scalar array elements produce the same hash, which makes the result
+// suitable for ETags and cache keys.
+//
+// Example:
+//
+//	h1, _ := qs.Hash("b=2&a=1&c[]=y&c[]=x")
+//	h2, _ := qs.Hash("c[]=x&a=1&c[]=y&b=2")
+//	// h1 == h2
+func Hash(query string, opts ...ParseOption) (string, error) {
+	return HashWith(query, sha256.New, opts...)
+}
+
+// HashWith is Hash with the digest computed by a hash from h.
+//
+// Example:
+//
+//	etag, _ := qs.HashWith(query, md5.New)
+func HashWith(query string, h func() hash.Hash, opts ...ParseOption) (string, error) {
+	options := applyParseOptions(opts...)
+
+	normalizedOpts, err := normalizeParseOptions(&options)
+	if err != nil {
+		return "", err
+	}
+
+	result, err := parseNormalized(query, &normalizedOpts)
+	if err != nil {
+		return "", err
+	}
+
+	canonical, err := Stringify(
+		canonicalize(result),
+		WithStringifySort(func(a, b string) bool { return a < b }),
+		WithStringifyArrayFormat(ArrayFormatIndices),
+		WithStringifyStrictNullHandling(true),
+	)
+	if err != nil {
+		return "", err
+	}
+
+	sum := h()
+	sum.Write([]byte(canonical))
+	return hex.EncodeToString(sum.Sum(nil)), nil
+}
+
+// canonicalize returns a copy of v in which every slice made up only of
+// scalars is sorted by the scalars' string form, nulls first among equal
+// ones, so numbers and booleans from ParseNumbers and ParseBooleans sort
+// like strings do. Maps need no reordering because Hash stringifies them
+// with sorted keys.
+func canonicalize(v any) any {
+	switch t := v.(type) {
+	case map[string]any:
+		out := make(map[string]any, len(t))
+		for k, item := range t {
+			out[k] = canonicalize(item)
+		}
+		return out
+	case []any:
+		out := make([]any, len(t))
+		scalars := true
+		for i, item := range t {
+			out[i] = canonicalize(item)
+			switch out[i].(type) {
+			case map[string]any, []any:
+				scalars = false
+			}
+		}
+		if scalars {
+			sort.SliceStable(out, func(i, j int) bool {
+				a, b := toString(out[i]), toString(out[j])
+				if a != b {
+					return a < b
+				}
+				return out[i] == nil && out[j] != nil
+			})
+		}
+		return out
+	default:
+		return v
+	}
+}
+
+// VerifyChecksum checks a query string written by Stringify with
+// WithStringifyChecksum and parses it without the checksum parameter.
+// The last parameter must be name=<checksum>; fn is applied to the bytes
+// before it, after any "?" prefix, exactly as received, and the result
+// must equal the checksum. Pairs are split on Delimiter.
+//
+// Unlike Hash, the content is not canonicalized: the checksum covers the
+// bytes Stringify wrote, so only a byte-identical query verifies. A query
+// whose pairs were reordered or re-encoded on the way, even to an
+// equivalent one, fails with ErrChecksumMismatch.
+//
+// Example:
+//
+//	str, _ := qs.Stringify(data, qs.WithStringifyChecksum("sig", sign))
+//	result, err := qs.VerifyChecksum(str, "sig", sign)
+func VerifyChecksum(query, name string, fn func(content []byte) string, opts ...ParseOption) (map[string]any, error) {
+	options := applyParseOptions(opts...)
+
+	normalizedOpts, err := normalizeParseOptions(&options)
+	if err != nil {
+		return nil, err
+	}
+
+	query = strings.TrimPrefix(query, "?")
+	content, pair := "", query
+	if i := strings.LastIndex(query, normalizedOpts.Delimiter); i >= 0 && normalizedOpts.Delimiter != "" {
+		content, pair = query[:i], query[i+len(normalizedOpts.Delimiter):]
+	}
+
+	key, sum, ok := strings.Cut(pair, "=")
+	if !ok || Decode(key, normalizedOpts.Charset) != name {
+		return nil, ErrChecksumMissing
+	}
+	want := fn([]byte(content))
+	if subtle.ConstantTimeCompare([]byte(Decode(sum, normalizedOpts.Charset)), []byte(want)) != 1 {
+		return nil, ErrChecksumMismatch
+	}
+
+	return parseNormalized(content, &normalizedOpts)
+}
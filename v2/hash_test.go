@@ -0,0 +1,168 @@
+// Copyright 2025 Zaytra
+// SPDX-License-Identifier: Apache-2.0
+
+package qs
+
+import (
+	"crypto/hmac"
+	"crypto/md5"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestHash(t *testing.T) {
+	equal := []struct {
+		name string
+		a, b string
+		opts []ParseOption
+	}{
+		{"reordered keys", "a=1&b=2&c=3", "c=3&a=1&b=2", nil},
+		{"reordered array elements", "a[]=x&a[]=y&a[]=z", "a[]=z&a[]=x&a[]=y", nil},
+		{"nested reorder", "a[b]=1&a[c][]=2&a[c][]=3", "a[c][]=3&a[c][]=2&a[b]=1", nil},
+		{"bracket and index forms", "a[]=x&a[]=y", "a[1]=y&a[0]=x", nil},
+		{"encoding differences", "a=hello%20world", "a=hello+world", nil},
+		{"with options", "a.b=1&a.c=2", "a.c=2&a.b=1", []ParseOption{WithParseAllowDots(true)}},
+		{"reordered numbers", "a=2&a=10&a=1", "a=1&a=2&a=10", []ParseOption{WithParseNumbers(true)}},
+		{"reordered booleans", "a=true&a=false", "a=false&a=true", []ParseOption{WithParseBooleans(true)}},
+		{"reordered mixed scalars", "a=x&a=1&a=true", "a=true&a=x&a=1", []ParseOption{WithParseNumbers(true), WithParseBooleans(true)}},
+	}
+	for _, tt := range equal {
+		t.Run(tt.name, func(t *testing.T) {
+			ha, err := Hash(tt.a, tt.opts...)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			hb, err := Hash(tt.b, tt.opts...)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if ha != hb {
+				t.Errorf("Hash(%q) = %s, Hash(%q) = %s; want equal", tt.a, ha, tt.b, hb)
+			}
+		})
+	}
+
+	different := []struct {
+		name string
+		a, b string
+		opts []ParseOption
+	}{
+		{"different values", "a=1", "a=2", nil},
+		{"different keys", "a=1", "b=1", nil},
+		{"scalar vs array", "a=1", "a[]=1", nil},
+		{"empty vs null", "a=", "a", []ParseOption{WithParseStrictNullHandling(true)}},
+		{"object array order kept", "a[0][b]=1&a[1][b]=2", "a[0][b]=2&a[1][b]=1", nil},
+	}
+	for _, tt := range different {
+		t.Run(tt.name, func(t *testing.T) {
+			ha, err := Hash(tt.a, tt.opts...)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			hb, err := Hash(tt.b, tt.opts...)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if ha == hb {
+				t.Errorf("Hash(%q) == Hash(%q); want different", tt.a, tt.b)
+			}
+		})
+	}
+}
+
+func TestHashAlgorithm(t *testing.T) {
+	t.Run("default is sha256", func(t *testing.T) {
+		got, err := Hash("b=2&a=1")
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		sum := sha256.Sum256([]byte("a=1&b=2"))
+		if want := hex.EncodeToString(sum[:]); got != want {
+			t.Errorf("got %s, want %s", got, want)
+		}
+	})
+
+	t.Run("custom hash", func(t *testing.T) {
+		got, err := HashWith("b=2&a=1", md5.New)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		sum := md5.Sum([]byte("a=1&b=2"))
+		if want := hex.EncodeToString(sum[:]); got != want {
+			t.Errorf("got %s, want %s", got, want)
+		}
+	})
+
+	t.Run("parse error", func(t *testing.T) {
+		_, err := Hash("a=1&b=2", WithParseParameterLimit(1), WithParseThrowOnLimitExceeded(true))
+		if err == nil {
+			t.Error("expected error")
+		}
+	})
+}
+
+func TestVerifyChecksum(t *testing.T) {
+	sign := func(content []byte) string {
+		mac := hmac.New(sha256.New, []byte("secret"))
+		mac.Write(content)
+		return hex.EncodeToString(mac.Sum(nil))
+	}
+	data := map[string]any{"b": "2", "a": []any{"x y", "z"}, "c": map[string]any{"d": "&="}}
+	byName := WithStringifySort(func(a, b string) bool { return a < b })
+
+	for name, opts := range map[string][]StringifyOption{
+		"default":      {byName},
+		"query prefix": {byName, WithStringifyAddQueryPrefix(true)},
+		"unsorted":     nil,
+	} {
+		t.Run(name, func(t *testing.T) {
+			str, err := Stringify(data, append(opts, WithStringifyChecksum("sig", sign))...)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			got, err := VerifyChecksum(str, "sig", sign)
+			if err != nil {
+				t.Fatalf("VerifyChecksum(%q): %v", str, err)
+			}
+			if !reflect.DeepEqual(got, data) {
+				t.Errorf("got %v, want %v", got, data)
+			}
+		})
+	}
+
+	str, err := Stringify(data, byName, WithStringifyDelimiter(";"), WithStringifyChecksum("sig", sign))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, err := VerifyChecksum(str, "sig", sign, WithParseDelimiter(";")); err != nil {
+		t.Errorf("custom delimiter: %v", err)
+	}
+
+	str, err = Stringify(map[string]any{}, WithStringifyChecksum("sig", sign))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got, err := VerifyChecksum(str, "sig", sign); err != nil || len(got) != 0 {
+		t.Errorf("empty: got %v, %v", got, err)
+	}
+
+	signed, _ := Stringify(map[string]any{"a": "1", "b": "2"}, byName, WithStringifyChecksum("sig", sign))
+	tampered := strings.Replace(signed, "a=1", "a=9", 1)
+	for input, wantErr := range map[string]error{
+		tampered:                        ErrChecksumMismatch,
+		"a=1&b=2":                       ErrChecksumMissing,
+		"a=1&sig":                       ErrChecksumMissing,
+		signed[:len(signed)-4] + "beef": ErrChecksumMismatch,
+		// Equivalent but not byte-identical content does not verify
+		strings.Replace(signed, "a=1&b=2", "b=2&a=1", 1): ErrChecksumMismatch,
+		strings.Replace(signed, "a=1", "%61=1", 1):       ErrChecksumMismatch,
+	} {
+		if _, err := VerifyChecksum(input, "sig", sign); !errors.Is(err, wantErr) {
+			t.Errorf("VerifyChecksum(%q) = %v, want %v", input, err, wantErr)
+		}
+	}
+}
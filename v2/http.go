@@ -0,0 +1,188 @@
+// Copyright 2025 Zaytra
+// SPDX-License-Identifier: Apache-2.0
+
+package qs
+
+import (
+	"errors"
+	"io"
+	"mime"
+	"net/http"
+	"strings"
+)
+
+// maxFormBodySize caps the form body read by ParseRequest, matching net/http.
+const maxFormBodySize = 10 << 20
+
+// ErrRequestBodyTooLarge is returned when a form body exceeds maxFormBodySize.
+var ErrRequestBodyTooLarge = errors.New("request body too large")
+
+// ParseRequest parses the URL query of r and, for POST, PUT and PATCH
+// requests with an application/x-www-form-urlencoded body, the body too.
+// Both are parsed with the same options and merged. When a key is present
+// in both, body params win unless BodyPrecedence is false; nested maps are
+// merged key by key. The request body is consumed.
+//
+// A charset parameter in the Content-Type header, such as
+// "application/x-www-form-urlencoded; charset=iso-8859-1", sets the
+// Charset the body is decoded with. Charsets other than UTF-8 and
+// ISO-8859-1 are ignored, leaving the configured Charset in place.
+//
+// MaxInputLength applies to the query and the body separately, and at
+// most one byte more than it is read from the body.
+//
+// Example:
+//
+//	// POST /items?a=1 with body "a=2&b=3"
+//	result, err := qs.ParseRequest(r)
+//	// result = map[string]any{"a": "2", "b": "3"}
+func ParseRequest(r *http.Request, opts ...ParseOption) (map[string]any, error) {
+	options := applyParseOptions(opts...)
+
+	normalizedOpts, err := normalizeParseOptions(&options)
+	if err != nil {
+		return nil, err
+	}
+
+	query := ""
+	if r.URL != nil {
+		query = r.URL.RawQuery
+	}
+	result, err := parseNormalized(query, &normalizedOpts)
+	if err != nil {
+		return nil, err
+	}
+
+	if !hasFormBody(r) {
+		return result, nil
+	}
+
+	// Read at most one byte past either limit, so an oversized body is
+	// rejected without being read into memory
+	limit := maxFormBodySize
+	if normalizedOpts.MaxInputLength > 0 && normalizedOpts.MaxInputLength < limit {
+		limit = normalizedOpts.MaxInputLength
+	}
+	data, err := io.ReadAll(io.LimitReader(r.Body, int64(limit)+1))
+	if err != nil {
+		return nil, err
+	}
+	if len(data) > maxFormBodySize {
+		return nil, ErrRequestBodyTooLarge
+	}
+
+	bodyOpts := normalizedOpts
+	if charset, ok := contentTypeCharset(r.Header.Get("Content-Type")); ok {
+		bodyOpts.Charset = charset
+	}
+	body, err := parseNormalized(string(data), &bodyOpts)
+	if err != nil {
+		return nil, err
+	}
+
+	if normalizedOpts.BodyPrecedence {
+		return mergeOverride(result, body), nil
+	}
+	return mergeOverride(body, result), nil
+}
+
+// ParseHeader parses a header value that uses query string syntax with
+// header parameter conventions, such as "key=val; other=x". It is like
+// Parse, but the delimiter defaults to ";" and spaces and tabs around
+// pairs, keys, and values are trimmed. Trimming follows Delimiter or
+// DelimiterRegexp; with Delimiters, pairs are parsed untrimmed.
+//
+// Example:
+//
+//	result, err := qs.ParseHeader("a=1; b = 2 ")
+//	// result = map[string]any{"a": "1", "b": "2"}
+func ParseHeader(value string, opts ...ParseOption) (map[string]any, error) {
+	options := applyParseOptions(append([]ParseOption{WithParseDelimiter(";")}, opts...)...)
+
+	normalizedOpts, err := normalizeParseOptions(&options)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(normalizedOpts.Delimiters) == 0 {
+		value = trimHeaderParams(value, &normalizedOpts)
+	}
+	return parseNormalized(value, &normalizedOpts)
+}
+
+// trimHeaderParams trims optional whitespace around each pair of value
+// and around the first '=' in it, keeping the delimiters in place.
+func trimHeaderParams(value string, opts *ParseOptions) string {
+	var seps [][]int
+	if opts.DelimiterRegexp != nil {
+		seps = opts.DelimiterRegexp.FindAllStringIndex(value, -1)
+	} else {
+		for i := 0; opts.Delimiter != ""; {
+			j := strings.Index(value[i:], opts.Delimiter)
+			if j < 0 {
+				break
+			}
+			seps = append(seps, []int{i + j, i + j + len(opts.Delimiter)})
+			i += j + len(opts.Delimiter)
+		}
+	}
+
+	var b strings.Builder
+	b.Grow(len(value))
+	start := 0
+	for _, sep := range append(seps, []int{len(value), len(value)}) {
+		pair := strings.Trim(value[start:sep[0]], " \t")
+		if key, val, ok := strings.Cut(pair, "="); ok {
+			pair = strings.TrimRight(key, " \t") + "=" + strings.TrimLeft(val, " \t")
+		}
+		b.WriteString(pair)
+		b.WriteString(value[sep[0]:sep[1]])
+		start = sep[1]
+	}
+	return b.String()
+}
+
+// hasFormBody reports whether r carries a URL-encoded form body.
+func hasFormBody(r *http.Request) bool {
+	if r.Body == nil || r.Body == http.NoBody {
+		return false
+	}
+	switch r.Method {
+	case http.MethodPost, http.MethodPut, http.MethodPatch:
+	default:
+		return false
+	}
+	mediaType, _, err := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	return err == nil && mediaType == "application/x-www-form-urlencoded"
+}
+
+// contentTypeCharset returns the supported charset named by the charset
+// parameter of a Content-Type header value.
+func contentTypeCharset(contentType string) (Charset, bool) {
+	_, params, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return "", false
+	}
+	switch strings.ToLower(strings.TrimSpace(params["charset"])) {
+	case "utf-8", "utf8":
+		return CharsetUTF8, true
+	case "iso-8859-1", "iso8859-1", "iso_8859-1", "latin1":
+		return CharsetISO88591, true
+	}
+	return "", false
+}
+
+// mergeOverride merges src into dst, with src winning on key collisions.
+// Maps present on both sides are merged recursively.
+func mergeOverride(dst, src map[string]any) map[string]any {
+	for k, v := range src {
+		if srcMap, ok := v.(map[string]any); ok {
+			if dstMap, ok := dst[k].(map[string]any); ok {
+				dst[k] = mergeOverride(dstMap, srcMap)
+				continue
+			}
+		}
+		dst[k] = v
+	}
+	return dst
+}
@@ -0,0 +1,201 @@
+// Copyright 2025 Zaytra
+// SPDX-License-Identifier: Apache-2.0
+
+package qs
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"regexp"
+	"strings"
+	"testing"
+)
+
+func newFormRequest(method, target, body string) *http.Request {
+	r := httptest.NewRequest(method, target, strings.NewReader(body))
+	r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	return r
+}
+
+func TestParseRequest(t *testing.T) {
+	tests := []struct {
+		name string
+		req  *http.Request
+		opts []ParseOption
+		want map[string]any
+	}{
+		{
+			name: "body wins by default",
+			req:  newFormRequest(http.MethodPost, "/?a=1", "a=2"),
+			want: map[string]any{"a": "2"},
+		},
+		{
+			name: "query wins without body precedence",
+			req:  newFormRequest(http.MethodPost, "/?a=1", "a=2"),
+			opts: []ParseOption{WithParseBodyPrecedence(false)},
+			want: map[string]any{"a": "1"},
+		},
+		{
+			name: "non-colliding keys merged",
+			req:  newFormRequest(http.MethodPost, "/?a=1", "b=2"),
+			want: map[string]any{"a": "1", "b": "2"},
+		},
+		{
+			name: "nested maps merged",
+			req:  newFormRequest(http.MethodPut, "/?f[a]=1&f[b]=1", "f[b]=2&f[c]=2"),
+			want: map[string]any{"f": map[string]any{"a": "1", "b": "2", "c": "2"}},
+		},
+		{
+			name: "options apply to both",
+			req:  newFormRequest(http.MethodPatch, "/?a.b=1", "c.d=2"),
+			opts: []ParseOption{WithParseAllowDots(true)},
+			want: map[string]any{"a": map[string]any{"b": "1"}, "c": map[string]any{"d": "2"}},
+		},
+		{
+			name: "GET body ignored",
+			req:  newFormRequest(http.MethodGet, "/?a=1", "a=2"),
+			want: map[string]any{"a": "1"},
+		},
+		{
+			name: "non-form body ignored",
+			req: func() *http.Request {
+				r := httptest.NewRequest(http.MethodPost, "/?a=1", strings.NewReader(`{"a":2}`))
+				r.Header.Set("Content-Type", "application/json")
+				return r
+			}(),
+			want: map[string]any{"a": "1"},
+		},
+		{
+			name: "content type with charset",
+			req: func() *http.Request {
+				r := newFormRequest(http.MethodPost, "/", "a=2")
+				r.Header.Set("Content-Type", "application/x-www-form-urlencoded; charset=utf-8")
+				return r
+			}(),
+			want: map[string]any{"a": "2"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseRequest(tt.req, tt.opts...)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got %v, want %v", got, tt.want)
+			}
+		})
+	}
+
+	t.Run("container hook", func(t *testing.T) {
+		var paths []string
+		hook := WithParseContainerHook(func(path []string, kind ContainerKind) error {
+			paths = append(paths, strings.Join(path, "."))
+			return nil
+		})
+		r := newFormRequest(http.MethodPost, "/?a[b]=1", "c[d]=2")
+		if _, err := ParseRequest(r, hook); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if want := []string{"", "a", "", "c"}; !reflect.DeepEqual(paths, want) {
+			t.Errorf("got %v, want %v", paths, want)
+		}
+	})
+
+	t.Run("body longer than MaxInputLength", func(t *testing.T) {
+		body := &countingReader{r: strings.NewReader("a=" + strings.Repeat("x", 1000))}
+		r := httptest.NewRequest(http.MethodPost, "/?a=1", body)
+		r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		if _, err := ParseRequest(r, WithParseMaxInputLength(10)); err != ErrInputTooLong {
+			t.Errorf("got %v, want %v", err, ErrInputTooLong)
+		}
+		if body.n > 11 {
+			t.Errorf("read %d bytes, want at most 11", body.n)
+		}
+	})
+
+	t.Run("body too large", func(t *testing.T) {
+		r := newFormRequest(http.MethodPost, "/", "a="+strings.Repeat("x", maxFormBodySize))
+		if _, err := ParseRequest(r); err != ErrRequestBodyTooLarge {
+			t.Errorf("got %v, want %v", err, ErrRequestBodyTooLarge)
+		}
+	})
+}
+
+// countingReader counts the bytes read from r.
+type countingReader struct {
+	r io.Reader
+	n int
+}
+
+func (c *countingReader) Read(p []byte) (int, error) {
+	n, err := c.r.Read(p)
+	c.n += n
+	return n, err
+}
+
+func TestParseRequestCharset(t *testing.T) {
+	tests := []struct {
+		name        string
+		contentType string
+		opts        []ParseOption
+		want        map[string]any
+	}{
+		{"iso-8859-1", "application/x-www-form-urlencoded; charset=iso-8859-1", nil,
+			map[string]any{"q": "\xf8", "b": "ø"}},
+		{"quoted latin1", `application/x-www-form-urlencoded; charset="Latin1"`, nil,
+			map[string]any{"q": "\xf8", "b": "ø"}},
+		{"utf-8 overrides option", "application/x-www-form-urlencoded; charset=UTF-8",
+			[]ParseOption{WithParseCharset(CharsetISO88591)}, map[string]any{"q": "ø", "b": "\xf8"}},
+		{"unknown charset ignored", "application/x-www-form-urlencoded; charset=koi8-r", nil,
+			map[string]any{"q": "\xf8", "b": "\xf8"}},
+		{"no charset", "application/x-www-form-urlencoded", nil,
+			map[string]any{"q": "\xf8", "b": "\xf8"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// The charset applies to the body only
+			r := newFormRequest(http.MethodPost, "/?q=%F8", "b=%F8")
+			r.Header.Set("Content-Type", tt.contentType)
+			got, err := ParseRequest(r, tt.opts...)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseHeader(t *testing.T) {
+	tests := []struct {
+		name  string
+		value string
+		opts  []ParseOption
+		want  map[string]any
+	}{
+		{"semicolon pairs", "a=1; b=2", nil, map[string]any{"a": "1", "b": "2"}},
+		{"whitespace around pairs and equals", " a = 1 ;\tb=2\t; ", nil, map[string]any{"a": "1", "b": "2"}},
+		{"inner whitespace kept", "a=x y; b", nil, map[string]any{"a": "x y", "b": ""}},
+		{"ampersand is literal", "a=x&y; b=2", nil, map[string]any{"a": "x&y", "b": "2"}},
+		{"nested keys", "f[a]=1; f[b]=2", nil, map[string]any{"f": map[string]any{"a": "1", "b": "2"}}},
+		{"delimiter override", "a=1 , b=2", []ParseOption{WithParseDelimiter(",")}, map[string]any{"a": "1", "b": "2"}},
+		{"regexp delimiter", "a=1 ;b=2 , c=3", []ParseOption{WithParseDelimiterRegexp(regexp.MustCompile(`[;,]`))}, map[string]any{"a": "1", "b": "2", "c": "3"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseHeader(tt.value, tt.opts...)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
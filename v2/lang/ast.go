@@ -181,6 +181,10 @@ type Arena struct {
 
 	// Scratch buffer for decoding - reused to avoid allocations.
 	scratch []byte
+
+	// sourceStr is the original input when parsing from a string. GetString
+	// slices it instead of copying, since strings are immutable.
+	sourceStr string
 }
 
 // NewArena allocates an arena with a capacity sized for typical inputs.
@@ -202,6 +206,7 @@ func NewArena(estimatedParams int) *Arena {
 func (a *Arena) Reset(source string) {
 	// Convert string to []byte. This is the only allocation for input.
 	a.Source = []byte(source)
+	a.sourceStr = source
 	a.Params = a.Params[:0]
 	a.Segments = a.Segments[:0]
 	a.Values = a.Values[:0]
@@ -214,6 +219,7 @@ func (a *Arena) Reset(source string) {
 // ResetBytes clears the arena for reuse with []byte input (zero-copy).
 func (a *Arena) ResetBytes(source []byte) {
 	a.Source = source
+	a.sourceStr = ""
 	a.Params = a.Params[:0]
 	a.Segments = a.Segments[:0]
 	a.Values = a.Values[:0]
@@ -237,7 +243,14 @@ func (a *Arena) GetBytes(s Span) []byte {
 }
 
 // GetString returns the raw substring referenced by span (no decoding).
-// This allocates a new string.
+// For string input, spans into Source are sliced from the original string
+// without allocating; otherwise a new string is allocated. A sliced string
+// shares the memory of the whole input, so it keeps the input alive for as
+// long as it is referenced; use strings.Clone to keep a short value of a
+// large input on its own.
 func (a *Arena) GetString(s Span) string {
+	if s.Off < a.synthOffset && len(a.sourceStr) == len(a.Source) {
+		return a.sourceStr[s.Off : s.Off+uint32(s.Len)]
+	}
 	return string(a.GetBytes(s))
 }
@@ -34,6 +34,8 @@ const (
 	FlagThrowOnLimitExceeded
 	FlagAllowDotsNoBracketConversion
 	FlagStrictMode
+	// FlagLenientSentinel ignores whitespace around the charset sentinel value.
+	FlagLenientSentinel
 )
 
 func (f Flags) Has(flag Flags) bool { return f&flag != 0 }
@@ -61,6 +63,10 @@ type Config struct {
 	ParseArrays bool
 
 	Charset Charset
+
+	// SentinelScanLimit restricts charset sentinel detection to the first
+	// SentinelScanLimit params. Zero means no limit.
+	SentinelScanLimit uint16
 }
 
 // DefaultConfig returns a config matching the language defaults.
@@ -227,8 +227,9 @@ func (p *Parser) emitParam(paramStart, keyEnd, paramEnd uint32, hasEquals bool)
 		return err
 	}
 
-	// Charset sentinel (only check first "utf8=" occurrence)
+	// Charset sentinel (only check first "utf8=" occurrence within the scan limit)
 	if hasEquals && p.cfg.Flags.Has(FlagCharsetSentinel) && !p.sentinelChecked &&
+		(p.cfg.SentinelScanLimit == 0 || len(p.arena.Params) < int(p.cfg.SentinelScanLimit)) &&
 		spanEqualsASCII(p.src, keySpan, "utf8") {
 		p.sentinelChecked = true
 
@@ -236,6 +237,9 @@ func (p *Parser) emitParam(paramStart, keyEnd, paramEnd uint32, hasEquals bool)
 		if err != nil {
 			return err
 		}
+		if p.cfg.Flags.Has(FlagLenientSentinel) {
+			valSpan = trimSpaceSpan(p.src, valSpan)
+		}
 
 		if spanEqualsFoldASCII(p.src, valSpan, utf8SentinelValue) {
 			p.detectedCharset = CharsetUTF8
@@ -994,6 +998,45 @@ func spanEqualsFoldASCII(src []byte, sp Span, s string) bool {
 	return true
 }
 
+// trimSpaceSpan narrows sp to exclude leading and trailing spaces and
+// tabs, raw or encoded as "+", "%20", or "%09".
+func trimSpaceSpan(src []byte, sp Span) Span {
+	start, end := int(sp.Off), int(sp.Off)+int(sp.Len)
+	for start < end {
+		n := spaceLen(src[start:end], false)
+		if n == 0 {
+			break
+		}
+		start += n
+	}
+	for start < end {
+		n := spaceLen(src[start:end], true)
+		if n == 0 {
+			break
+		}
+		end -= n
+	}
+	return Span{Off: uint32(start), Len: uint16(end - start)}
+}
+
+// spaceLen returns the length of the space or tab, raw or encoded, that b
+// starts with, or ends with if suffix is set, or 0 if there is none.
+func spaceLen(b []byte, suffix bool) int {
+	for _, sp := range [...]string{" ", "\t", "+", "%20", "%09"} {
+		if len(b) < len(sp) {
+			continue
+		}
+		at := 0
+		if suffix {
+			at = len(b) - len(sp)
+		}
+		if string(b[at:at+len(sp)]) == sp {
+			return len(sp)
+		}
+	}
+	return 0
+}
+
 func validatePercentEncoding(src []byte, sp Span) error {
 	start := int(sp.Off)
 	end := start + int(sp.Len)
@@ -367,3 +367,33 @@ func TestParse_StringAllocs(t *testing.T) {
 		t.Fatalf("allocs: expected 1 got %v", allocs)
 	}
 }
+
+func TestArena_GetStringNoAllocs(t *testing.T) {
+	arena := NewArena(4)
+	input := "a=plain&b=text"
+	if _, _, err := Parse(arena, input, DefaultConfig()); err != nil {
+		t.Fatalf("Parse: %v", err)
+	}
+	val := arena.Values[arena.Params[0].ValueIdx].Raw
+
+	// String input is sliced, not copied
+	allocs := testing.AllocsPerRun(1000, func() {
+		if arena.GetString(val) != "plain" {
+			panic("unexpected value")
+		}
+	})
+	if allocs != 0 {
+		t.Fatalf("allocs: got %v", allocs)
+	}
+
+	// []byte input may be mutated by the caller, so it is copied
+	src := []byte(input)
+	if _, _, err := ParseBytes(arena, src, DefaultConfig()); err != nil {
+		t.Fatalf("ParseBytes: %v", err)
+	}
+	got := arena.GetString(arena.Values[arena.Params[0].ValueIdx].Raw)
+	src[2] = 'X'
+	if got != "plain" {
+		t.Fatalf("GetString aliased []byte input: got %q", got)
+	}
+}
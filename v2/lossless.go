@@ -0,0 +1,67 @@
+// Copyright 2025 Zaytra
+// SPDX-License-Identifier: Apache-2.0
+
+package qs
+
+import "math"
+
+// WithStringifyLossless configures Stringify so that Parse with
+// WithParseLossless reads the output back as the input, e.g.
+// {a: nil, c: {d: ["x"]}} → "a&c%5Bd%5D%5B0%5D=x".
+//
+// Keys and values are fully percent-encoded, arrays are written with
+// indices, and nil is written as a bare key. This holds for values that
+// are strings, nil, non-empty maps, and non-empty arrays of any of these
+// except nil, which Stringify treats as a sparse slot. Other scalars come
+// back as their string form. Map keys must be non-empty, must not contain
+// brackets, and must not be array indices such as "0", since those are
+// read back as arrays. Empty maps and arrays are dropped, as qs syntax
+// has no unambiguous encoding for them.
+//
+// Options passed after the preset override it.
+func WithStringifyLossless() StringifyOption {
+	return func(o *StringifyOptions) {
+		o.AllowDots = false
+		o.EncodeDotInKeys = false
+		o.ArrayFormat = ArrayFormatIndices
+		o.NestedArrayFormat = ""
+		o.CompactIndices = false
+		o.LevelDelimiters = nil
+		o.Encode = true
+		o.EncodeValuesOnly = false
+		o.Format = FormatRFC3986
+		o.Charset = CharsetUTF8
+		o.CharsetSentinel = false
+		o.StrictNullHandling = true
+		o.SkipNulls = false
+		o.AllowEmptyArrays = false
+		o.NilSlices = NilSliceAsEmpty
+	}
+}
+
+// WithParseLossless configures Parse to read back the output of
+// Stringify with WithStringifyLossless; see there for which values round
+// trip. Besides matching its null handling, it raises
+// Depth, ArrayLimit, and ParameterLimit to the largest supported values
+// so deep or long inputs are not cut short.
+//
+// Options passed after the preset override it.
+func WithParseLossless() ParseOption {
+	return func(o *ParseOptions) {
+		o.AllowDots = false
+		o.DecodeDotInKeys = false
+		o.Comma = false
+		o.ParseArrays = true
+		o.LevelDelimiters = nil
+		o.KeySplitter = nil
+		o.GroupBracketObjects = false
+		o.Charset = CharsetUTF8
+		o.CharsetSentinel = false
+		o.StrictNullHandling = true
+		o.AllowEmptyArrays = false
+		o.Duplicates = DuplicateCombine
+		o.Depth = math.MaxUint16
+		o.ArrayLimit = math.MaxUint16
+		o.ParameterLimit = math.MaxUint16
+	}
+}
@@ -0,0 +1,109 @@
+// Copyright 2025 Zaytra
+// SPDX-License-Identifier: Apache-2.0
+
+package qs
+
+import (
+	"math/rand"
+	"reflect"
+	"strconv"
+	"testing"
+)
+
+// losslessChars mixes plain characters with ones that are structural in
+// query strings, except brackets, which keys may not contain.
+var losslessChars = []rune("abcXYZ019 &=%+.#?/~!'()*,;:@$\"\\é€😀\t\n")
+
+func randomLosslessString(r *rand.Rand, key bool) string {
+	n := r.Intn(6)
+	if key {
+		n++
+	}
+	chars := losslessChars
+	if !key {
+		chars = append([]rune("[]"), losslessChars...)
+	}
+	s := make([]rune, n)
+	for i := range s {
+		s[i] = chars[r.Intn(len(chars))]
+	}
+	str := string(s)
+	if _, err := strconv.Atoi(str); key && err == nil {
+		str = "k" + str
+	}
+	return str
+}
+
+func randomLosslessValue(r *rand.Rand, depth int, inArray bool) any {
+	kind := r.Intn(4)
+	if depth == 0 {
+		kind = 0
+	}
+	switch kind {
+	case 1:
+		if inArray {
+			return randomLosslessString(r, false)
+		}
+		return nil
+	case 2:
+		return randomLosslessMap(r, depth-1)
+	case 3:
+		arr := make([]any, r.Intn(3)+1)
+		for i := range arr {
+			arr[i] = randomLosslessValue(r, depth-1, true)
+		}
+		return arr
+	}
+	return randomLosslessString(r, false)
+}
+
+func randomLosslessMap(r *rand.Rand, depth int) map[string]any {
+	m := make(map[string]any)
+	for n := r.Intn(4) + 1; len(m) < n; {
+		m[randomLosslessString(r, true)] = randomLosslessValue(r, depth, false)
+	}
+	return m
+}
+
+func TestLosslessRoundTrip(t *testing.T) {
+	r := rand.New(rand.NewSource(1))
+	for i := 0; i < 2000; i++ {
+		m := randomLosslessMap(r, 4)
+
+		str, err := Stringify(m, WithStringifyLossless())
+		if err != nil {
+			t.Fatalf("Stringify(%#v): %v", m, err)
+		}
+		got, err := Parse(str, WithParseLossless())
+		if err != nil {
+			t.Fatalf("Parse(%q): %v", str, err)
+		}
+		if !reflect.DeepEqual(got, m) {
+			t.Fatalf("round trip of %#v through %q gave %#v", m, str, got)
+		}
+	}
+}
+
+func TestLosslessLimits(t *testing.T) {
+	deep := map[string]any{"v": "x"}
+	for i := 0; i < 10; i++ {
+		deep = map[string]any{"k": deep}
+	}
+	long := make([]any, 50)
+	for i := range long {
+		long[i] = strconv.Itoa(i)
+	}
+	m := map[string]any{"deep": deep, "long": long}
+
+	str, err := Stringify(m, WithStringifyLossless())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	got, err := Parse(str, WithParseLossless())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !reflect.DeepEqual(got, m) {
+		t.Errorf("got %v, want %v", got, m)
+	}
+}
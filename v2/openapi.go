@@ -0,0 +1,127 @@
+// Copyright 2025 Zaytra
+// SPDX-License-Identifier: Apache-2.0
+
+package qs
+
+// WithParseDeepObjectStyle configures Parse for the OpenAPI deepObject
+// parameter style (style: deepObject, explode: true), e.g.
+// "color[R]=100&color[G]=200" → {color: {R: "100", G: "200"}}.
+//
+// Only brackets denote nesting; dots and commas are literal. Bracketed
+// segments are always object keys, so "a[0]=x" yields {a: {"0": "x"}}.
+// The spec leaves arrays undefined; repeated keys are combined into a
+// slice, matching WithStringifyDeepObjectStyle.
+//
+// Options passed after the preset override it.
+func WithParseDeepObjectStyle() ParseOption {
+	return func(o *ParseOptions) {
+		o.AllowDots = false
+		o.DecodeDotInKeys = false
+		o.Comma = false
+		o.ParseArrays = false
+		o.Duplicates = DuplicateCombine
+	}
+}
+
+// WithStringifyDeepObjectStyle configures Stringify for the OpenAPI
+// deepObject parameter style (style: deepObject, explode: true), e.g.
+// {color: {R: 100, G: 200}} → "color[R]=100&color[G]=200".
+//
+// Brackets are written literally and only values are percent-encoded.
+// The spec leaves arrays undefined; they are written as repeated keys
+// without indices ("a[b]=1&a[b]=2"), which WithParseDeepObjectStyle
+// reads back as a slice.
+//
+// Options passed after the preset override it.
+func WithStringifyDeepObjectStyle() StringifyOption {
+	return func(o *StringifyOptions) {
+		o.AllowDots = false
+		o.EncodeDotInKeys = false
+		o.ArrayFormat = ArrayFormatRepeat
+		o.Encode = true
+		o.EncodeValuesOnly = true
+		o.Format = FormatRFC3986
+	}
+}
+
+// WithParseFormStyle configures Parse for arrays in the OpenAPI form
+// parameter style. With explode, arrays are repeated keys
+// ("id=3&id=4"); without it, they are comma-separated ("id=3,4").
+//
+// Options passed after the preset override it.
+func WithParseFormStyle(explode bool) ParseOption {
+	return func(o *ParseOptions) {
+		o.Comma = !explode
+		o.CommaDelimiter = ","
+		o.Duplicates = DuplicateCombine
+	}
+}
+
+// WithStringifyFormStyle configures Stringify for arrays in the OpenAPI
+// form parameter style. With explode, arrays are written as repeated keys
+// ("id=3&id=4"); without it, as one comma-separated value ("id=3,4") in
+// which only the elements are percent-encoded.
+//
+// Options passed after the preset override it.
+func WithStringifyFormStyle(explode bool) StringifyOption {
+	return func(o *StringifyOptions) {
+		if explode {
+			o.ArrayFormat = ArrayFormatRepeat
+			return
+		}
+		o.ArrayFormat = ArrayFormatComma
+		o.CommaDelimiter = ","
+		o.EncodeValuesOnly = true
+	}
+}
+
+// WithParseSpaceDelimitedStyle configures Parse for arrays in the OpenAPI
+// spaceDelimited parameter style ("id=3%204%205"). Values are split on
+// the raw "%20", so an element cannot itself contain an encoded space.
+//
+// Options passed after the preset override it.
+func WithParseSpaceDelimitedStyle() ParseOption {
+	return func(o *ParseOptions) {
+		o.Comma = true
+		o.CommaDelimiter = "%20"
+	}
+}
+
+// WithStringifySpaceDelimitedStyle configures Stringify for arrays in the
+// OpenAPI spaceDelimited parameter style ("id=3%204%205"). Only the
+// elements are percent-encoded.
+//
+// Options passed after the preset override it.
+func WithStringifySpaceDelimitedStyle() StringifyOption {
+	return func(o *StringifyOptions) {
+		o.ArrayFormat = ArrayFormatComma
+		o.CommaDelimiter = "%20"
+		o.EncodeValuesOnly = true
+		o.Format = FormatRFC3986
+	}
+}
+
+// WithParsePipeDelimitedStyle configures Parse for arrays in the OpenAPI
+// pipeDelimited parameter style ("id=3|4|5"). Values are split on the raw
+// "|"; an encoded "%7C" stays part of the element.
+//
+// Options passed after the preset override it.
+func WithParsePipeDelimitedStyle() ParseOption {
+	return func(o *ParseOptions) {
+		o.Comma = true
+		o.CommaDelimiter = "|"
+	}
+}
+
+// WithStringifyPipeDelimitedStyle configures Stringify for arrays in the
+// OpenAPI pipeDelimited parameter style ("id=3|4|5"). Only the elements
+// are percent-encoded, so a "|" inside an element becomes "%7C".
+//
+// Options passed after the preset override it.
+func WithStringifyPipeDelimitedStyle() StringifyOption {
+	return func(o *StringifyOptions) {
+		o.ArrayFormat = ArrayFormatComma
+		o.CommaDelimiter = "|"
+		o.EncodeValuesOnly = true
+	}
+}
@@ -0,0 +1,125 @@
+// Copyright 2025 Zaytra
+// SPDX-License-Identifier: Apache-2.0
+
+package qs
+
+import (
+	"reflect"
+	"testing"
+)
+
+// Examples from the OpenAPI 3 "Style Examples" table for deepObject.
+func TestDeepObjectStyle(t *testing.T) {
+	// Stringify sorts keys into the order the examples use
+	keyOrder := map[string]int{"R": 1, "G": 2, "B": 3, "min": 4, "max": 5}
+	sortKeys := WithStringifySort(func(a, b string) bool { return keyOrder[a] < keyOrder[b] })
+
+	tests := []struct {
+		name  string
+		query string
+		value map[string]any
+	}{
+		{
+			"spec example",
+			"color[R]=100&color[G]=200&color[B]=150",
+			map[string]any{"color": map[string]any{"R": "100", "G": "200", "B": "150"}},
+		},
+		{
+			"nested object",
+			"filter[price][min]=10&filter[price][max]=20",
+			map[string]any{"filter": map[string]any{"price": map[string]any{"min": "10", "max": "20"}}},
+		},
+		{
+			"array as repeated keys",
+			"filter[tags]=a&filter[tags]=b",
+			map[string]any{"filter": map[string]any{"tags": []any{"a", "b"}}},
+		},
+		{
+			"values encoded",
+			"filter[name]=a%20b%26c",
+			map[string]any{"filter": map[string]any{"name": "a b&c"}},
+		},
+		{
+			"numeric segment is an object key",
+			"filter[0]=x",
+			map[string]any{"filter": map[string]any{"0": "x"}},
+		},
+		{
+			"dots are literal",
+			"filter[a.b]=c",
+			map[string]any{"filter": map[string]any{"a.b": "c"}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Parse(tt.query, WithParseDeepObjectStyle())
+			if err != nil {
+				t.Fatalf("Parse: unexpected error: %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.value) {
+				t.Errorf("Parse = %v, want %v", got, tt.value)
+			}
+
+			str, err := Stringify(tt.value, WithStringifyDeepObjectStyle(), sortKeys)
+			if err != nil {
+				t.Fatalf("Stringify: unexpected error: %v", err)
+			}
+			if str != tt.query {
+				t.Errorf("Stringify = %q, want %q", str, tt.query)
+			}
+		})
+	}
+
+	// Later options override the preset
+	str, err := Stringify(map[string]any{"a": []any{"x", "y"}},
+		WithStringifyDeepObjectStyle(), WithStringifyArrayFormat(ArrayFormatBrackets))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if str != "a[]=x&a[]=y" {
+		t.Errorf("got %q, want %q", str, "a[]=x&a[]=y")
+	}
+}
+
+// Array examples from the OpenAPI 3 "Style Examples" table, plus elements
+// that need encoding.
+func TestArrayStyles(t *testing.T) {
+	colors := map[string]any{"color": []any{"blue", "black", "brown"}}
+	special := map[string]any{"q": []any{"a|b", "c,d", "e&f"}}
+
+	tests := []struct {
+		name      string
+		parse     ParseOption
+		stringify StringifyOption
+		value     map[string]any
+		query     string
+	}{
+		{"form explode", WithParseFormStyle(true), WithStringifyFormStyle(true), colors, "color=blue&color=black&color=brown"},
+		{"form", WithParseFormStyle(false), WithStringifyFormStyle(false), colors, "color=blue,black,brown"},
+		{"spaceDelimited", WithParseSpaceDelimitedStyle(), WithStringifySpaceDelimitedStyle(), colors, "color=blue%20black%20brown"},
+		{"pipeDelimited", WithParsePipeDelimitedStyle(), WithStringifyPipeDelimitedStyle(), colors, "color=blue|black|brown"},
+		{"form encoded elements", WithParseFormStyle(false), WithStringifyFormStyle(false), special, "q=a%7Cb,c%2Cd,e%26f"},
+		{"pipeDelimited encoded elements", WithParsePipeDelimitedStyle(), WithStringifyPipeDelimitedStyle(), special, "q=a%7Cb|c%2Cd|e%26f"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			str, err := Stringify(tt.value, tt.stringify)
+			if err != nil {
+				t.Fatalf("Stringify: unexpected error: %v", err)
+			}
+			if str != tt.query {
+				t.Errorf("Stringify = %q, want %q", str, tt.query)
+			}
+
+			got, err := Parse(tt.query, tt.parse)
+			if err != nil {
+				t.Fatalf("Parse: unexpected error: %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.value) {
+				t.Errorf("Parse = %v, want %v", got, tt.value)
+			}
+		})
+	}
+}
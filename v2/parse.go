@@ -5,9 +5,15 @@ package qs
 
 import (
 	"errors"
+	"fmt"
+	"math"
 	"regexp"
+	"slices"
+	"sort"
 	"strconv"
 	"strings"
+	"time"
+	"unicode/utf8"
 
 	"github.com/zaytracom/qs/v2/lang"
 )
@@ -31,6 +37,129 @@ const (
 	DuplicateLast DuplicateHandling = "last"
 )
 
+// InvalidUTF8Mode specifies how the default decoder handles decoded bytes
+// that are not valid UTF-8 under CharsetUTF8.
+type InvalidUTF8Mode string
+
+const (
+	// InvalidUTF8Preserve keeps the invalid bytes in the string (default).
+	InvalidUTF8Preserve InvalidUTF8Mode = "preserve"
+	// InvalidUTF8Replace replaces each run of invalid bytes with U+FFFD.
+	InvalidUTF8Replace InvalidUTF8Mode = "replace"
+	// InvalidUTF8Error returns an error wrapping ErrInvalidUTF8.
+	InvalidUTF8Error InvalidUTF8Mode = "error"
+)
+
+// ContainerKind identifies the type of container reported to a ContainerHookFunc.
+type ContainerKind string
+
+const (
+	// ContainerMap is a map[string]any.
+	ContainerMap ContainerKind = "map"
+	// ContainerSlice is a []any.
+	ContainerSlice ContainerKind = "slice"
+)
+
+// ContainerHookFunc is called for each map or slice in a parse result.
+// Parameters:
+//   - path: the keys leading to the container (empty for the root);
+//     slice elements use their index as the key
+//   - kind: the container type
+//
+// Returning an error stops the parse, which returns that error.
+// The path slice is reused between calls; copy it to retain it.
+type ContainerHookFunc func(path []string, kind ContainerKind) error
+
+// Trace events reported to a TraceFunc, with the type of their detail.
+const (
+	// TraceSegment reports a parameter split from the input, as written
+	// (e.g., "a[b]=c"). Detail: string.
+	TraceSegment = "segment"
+	// TraceKey reports a parameter's decoded key. Detail: string.
+	TraceKey = "key"
+	// TracePath reports the segments a key was split into, up to Depth
+	// (e.g., ["a", "[b]", "[]"]). Detail: []string.
+	TracePath = "path"
+	// TraceContainer reports whether a path segment built an array or an
+	// object, from the innermost segment out. Detail: TraceContainerDetail.
+	TraceContainer = "container"
+	// TraceDepth reports the part of a key beyond Depth, which is kept as
+	// one literal segment, dropped, or rejected per DepthOverflowMode
+	// and StrictDepth. Detail: string.
+	TraceDepth = "depth"
+)
+
+// TraceContainerDetail is the detail of a TraceContainer event.
+type TraceContainerDetail struct {
+	// Segment is the path segment, e.g. "[0]" or "[b]".
+	Segment string
+	// Kind is the container the segment built.
+	Kind ContainerKind
+}
+
+// TraceFunc receives parse trace events. The event is one of the Trace
+// constants and detail is typed as documented there.
+//
+// Repeated keys are traced for each occurrence as TraceSegment and
+// TraceKey, but their path is built once, after all values are read, so
+// the TraceContainer events of a key, and depending on the options its
+// TracePath, follow the events of later parameters. Go maps have no prototype, so
+// unlike JS qs there are no prototype keys to block or trace.
+type TraceFunc func(event string, detail any)
+
+// DepthOverflowMode specifies how key segments beyond Depth are represented.
+type DepthOverflowMode string
+
+const (
+	// DepthOverflowLiteral keeps the overflow as one bracketed key (default):
+	// "a[b][c]" with depth 1 → {a: {b: {"[c]": ...}}}
+	DepthOverflowLiteral DepthOverflowMode = "literal"
+	// DepthOverflowDot joins the overflow segments with dots:
+	// "a[b][c][d]" with depth 1 → {a: {b: {"c.d": ...}}}
+	DepthOverflowDot DepthOverflowMode = "dot"
+	// DepthOverflowDrop discards the overflow, so the value lands at the
+	// deepest allowed key: "a[b][c]" with depth 1 → {a: {b: ...}}
+	DepthOverflowDrop DepthOverflowMode = "drop"
+)
+
+// NestedEmptyBracketsMode specifies how keys ending in two or more empty
+// brackets, such as "a[][]", build nested arrays.
+type NestedEmptyBracketsMode string
+
+const (
+	// NestedEmptyBracketsFlatten collects all values in one flat array,
+	// as JS qs does (default): "a[][]=b&a[][]=c" → {a: [b, c]}
+	NestedEmptyBracketsFlatten NestedEmptyBracketsMode = "flatten"
+	// NestedEmptyBracketsAppendInner appends all values to one inner array:
+	// "a[][]=b&a[][]=c" → {a: [[b, c]]}
+	NestedEmptyBracketsAppendInner NestedEmptyBracketsMode = "append"
+	// NestedEmptyBracketsNewInner starts a new inner array for each value:
+	// "a[][]=b&a[][]=c" → {a: [[b], [c]]}
+	NestedEmptyBracketsNewInner NestedEmptyBracketsMode = "new"
+)
+
+// ValueFormat describes a value packed as a list of key/value pairs, such
+// as "k1:v1,k2:v2", for ValueSubParse.
+type ValueFormat struct {
+	// ElementSeparator separates the pairs. Default: ","
+	ElementSeparator string
+	// PairSeparator separates a key from its value. Default: ":"
+	PairSeparator string
+}
+
+// separators returns the element and pair separators of f with defaults
+// filled in.
+func (f ValueFormat) separators() (string, string) {
+	elem, pair := f.ElementSeparator, f.PairSeparator
+	if elem == "" {
+		elem = ","
+	}
+	if pair == "" {
+		pair = ":"
+	}
+	return elem, pair
+}
+
 // DecoderFunc is a custom decoder function signature.
 // Parameters:
 //   - str: the string to decode
@@ -40,6 +169,12 @@ const (
 // Returns the decoded string and any error.
 type DecoderFunc func(str string, charset Charset, kind string) (string, error)
 
+// DefaultDecoder is the decoder Parse uses when none is set. It
+// percent-decodes str and turns + into spaces; see Decode.
+func DefaultDecoder(str string, charset Charset, kind string) (string, error) {
+	return Decode(str, charset), nil
+}
+
 // ParseOptions configures the behavior of the Parse function.
 type ParseOptions struct {
 	// AllowDots enables dot notation parsing (e.g., "a.b.c" → {a: {b: {c: ...}}}).
@@ -61,19 +196,120 @@ type ParseOptions struct {
 	// Default: 20
 	ArrayLimit int
 
+	// ArrayLimitFor overrides ArrayLimit for specific top-level keys, e.g.
+	// {"items": 1000} lets "items[500]=x" build an array while other keys
+	// keep the global limit. The limit applies to every array under the
+	// key.
+	// Default: nil
+	ArrayLimitFor map[string]int
+
+	// MaxArrayIndex caps the index that builds an array, whatever
+	// ArrayLimit and ArrayLimitFor allow, so a single parameter such as
+	// "a[1000000000]=b" cannot allocate a huge slice. Larger indices
+	// become object keys, as indices beyond ArrayLimit do. Zero means no
+	// cap.
+	// Default: 0
+	MaxArrayIndex int
+
+	// NestedEmptyBrackets controls the arrays built by keys ending in two
+	// or more empty brackets, e.g. "a[][]". Each further bracket adds a
+	// level of nesting around the inner arrays, and a comma-split value
+	// (see Comma) fills one inner array. It has no effect on keys ending
+	// in a single "[]", or when ParseArrays is false.
+	// Default: NestedEmptyBracketsFlatten
+	NestedEmptyBrackets NestedEmptyBracketsMode
+
 	// Charset specifies the character encoding to use.
 	// Default: CharsetUTF8
 	Charset Charset
 
+	// InvalidUTF8 sets what happens when a decoded key or value is not valid
+	// UTF-8 under CharsetUTF8, e.g. from the truncated "%E2%82". It applies to the
+	// default decoder only; a custom Decoder handles its own output.
+	// Default: InvalidUTF8Preserve
+	InvalidUTF8 InvalidUTF8Mode
+
+	// DecodePasses repairs keys and values that clients encoded more than
+	// once by decoding them up to DecodePasses times, stopping early once a
+	// pass changes nothing, so "%2520" with 2 passes parses as " ". Passes
+	// after the first only decode percent escapes and keep "+" as is. Keys
+	// are fully decoded before they are split into segments, so
+	// "a%255Bb%255D=1" with 2 passes nests as {"a": {"b": "1"}}. Use
+	// it only for clients known to double-encode: a value meant to contain
+	// a literal escape, such as "%2541" for "%41", is decoded too far. It
+	// applies to the default decoder only; values below 2 decode once.
+	// Default: 1
+	DecodePasses int
+
 	// CharsetSentinel enables automatic charset detection via utf8=✓ parameter.
 	// Default: false
 	CharsetSentinel bool
 
+	// SentinelScanLimit restricts charset sentinel detection to the first
+	// SentinelScanLimit parameters, where the sentinel conventionally appears.
+	// A sentinel beyond the limit is treated as a regular parameter.
+	// Default: 0 (scan all parameters)
+	SentinelScanLimit int
+
+	// LenientSentinel ignores spaces and tabs around the charset sentinel
+	// value, raw or encoded as "+", "%20", or "%09", so "utf8=%e2%9c%93%20"
+	// is still detected. The hex digits of the value match without regard
+	// to case either way, and unknown values remain regular parameters.
+	// Default: false
+	LenientSentinel bool
+
+	// CharsetSentinels adds charset sentinel values, keyed by the charset
+	// they select, for charsets other than utf-8 and iso-8859-1, e.g.
+	// {"shift_jis": "%81%E3"} for "utf8=%81%E3". The value is matched like
+	// the built-in ones and selects the charset passed to Decoder; the
+	// default decoder treats unknown charsets as utf-8. Values for the
+	// built-in charsets are recognized in addition to the defaults.
+	// Default: nil
+	CharsetSentinels map[Charset]string
+
 	// Comma enables parsing comma-separated values as arrays.
 	// e.g., "a=1,2,3" → {a: ["1", "2", "3"]}
+	// As in JS qs, a repeated key combines its elements into one array,
+	// "a[b]=1,2&a[b]=3,4" → {a: {b: ["1", "2", "3", "4"]}}, while under a
+	// trailing "[]" each occurrence stays its own array,
+	// "a[]=1,2&a[]=3,4" → {a: [["1", "2"], ["3", "4"]]}.
 	// Default: false
 	Comma bool
 
+	// CommaDelimiter is the separator Comma splits values on, matched
+	// against the raw value before decoding, e.g. "|" or "%20".
+	// Default: ","
+	CommaDelimiter string
+
+	// SeparatorByKey sets the separator for splitting the values of
+	// specific keys into arrays, e.g. {"ids": ",", "tags": "|"} parses
+	// "ids=1,2&tags=a|b" into {ids: ["1", "2"], tags: ["a", "b"]}. Keys
+	// are matched against the decoded key as written, such as "ids" or
+	// "filter[ids]", and their values are split whether or not Comma is
+	// set. Other keys follow Comma and CommaDelimiter. Separators are
+	// matched against the raw value, like CommaDelimiter.
+	// Default: nil
+	SeparatorByKey map[string]string
+
+	// DoubleDecodeKeys lists keys whose values are decoded twice, for
+	// values known to be encoded twice, such as a query embedded as a
+	// value: "next=%253Fa%253D1" parses into {next: "?a=1"}. Keys are
+	// matched like SeparatorByKey. Values get exactly one extra pass with
+	// the same Decoder, so a "+" decoded from "%2B" turns into a space on
+	// the second pass. Other keys are decoded once.
+	// Default: nil
+	DoubleDecodeKeys []string
+
+	// ValueSubParse parses the values of specific keys as packed key/value
+	// lists into nested maps, e.g. {"meta": {}} parses "meta=k1:v1,k2:v2"
+	// into {meta: {k1: "v1", k2: "v2"}}. Keys are matched like
+	// SeparatorByKey, and separators are matched against the raw value.
+	// Each packed key and value is decoded on its own; a pair without the
+	// pair separator gets an empty value, and a repeated packed key keeps
+	// its last value. Empty values are left as "".
+	// Default: nil
+	ValueSubParse map[string]ValueFormat
+
 	// DecodeDotInKeys decodes %2E as . in keys.
 	// Default: false
 	DecodeDotInKeys bool
@@ -92,6 +328,28 @@ type ParseOptions struct {
 	// Default: nil
 	DelimiterRegexp *regexp.Regexp
 
+	// KeyRegexp keeps only top-level keys matching the pattern, e.g.
+	// regexp.MustCompile(`^utm_`). Other parameters are dropped before
+	// their nested structure is built. The pattern is matched against the
+	// decoded key up to its first bracket, or dot with AllowDots.
+	// RejectDuplicates still applies to dropped keys.
+	// Default: nil
+	KeyRegexp *regexp.Regexp
+
+	// Delimiters splits key-value pairs on any of several literal strings,
+	// without needing a regexp. When delimiters overlap at the same position,
+	// the one listed first wins.
+	// If set, Delimiter and DelimiterRegexp are ignored.
+	// Default: nil
+	Delimiters []string
+
+	// DelimiterEscape is a rune that, placed before a delimiter, makes it part
+	// of the value instead of a separator (e.g., with \ and ";",
+	// a=1\;2;b=3 → {a: "1;2", b: "3"}). A doubled escape rune stands for itself.
+	// Intended for non-URL contexts; in URLs prefer percent-encoding.
+	// Default: 0 (disabled)
+	DelimiterEscape rune
+
 	// Depth is the maximum depth for nested object parsing.
 	// Set to 0 to disable nested parsing entirely.
 	// Default: 5
@@ -101,6 +359,30 @@ type ParseOptions struct {
 	// Default: DuplicateCombine
 	Duplicates DuplicateHandling
 
+	// RejectDuplicates returns an error wrapping ErrDuplicateKey, naming the
+	// key, when the same key appears more than once, whatever Duplicates
+	// says. Keys are compared as written after percent-decoding, so
+	// "a[b]=1&a[c]=2" is accepted: distinct nested paths under one parent
+	// are not duplicates, while "a[b]=1&a[b]=2" is rejected. Keys ending in
+	// "[]" append to an array and may repeat. Different spellings of one
+	// path, such as "a[b]" and "a.b" with AllowDots, are not detected.
+	// Default: false
+	RejectDuplicates bool
+
+	// LevelDelimiters splits keys on a separator per nesting level,
+	// mirroring the Stringify option: the Nth delimiter separates a segment
+	// at level N from its parent, and the last one is used for deeper
+	// levels. e.g., [".", "/"] parses "a.b/c/d=v" like "a[b][c][d]=v".
+	// Empty segments append to arrays like "[]". Any bracket part of a key
+	// is kept and parsed as usual.
+	// Default: nil
+	LevelDelimiters []string
+
+	// DepthOverflowMode controls how key segments beyond Depth are
+	// represented when StrictDepth is false.
+	// Default: DepthOverflowLiteral
+	DepthOverflowMode DepthOverflowMode
+
 	// IgnoreQueryPrefix strips a leading ? from the input string.
 	// Default: false
 	IgnoreQueryPrefix bool
@@ -115,11 +397,28 @@ type ParseOptions struct {
 	// Default: 1000
 	ParameterLimit int
 
+	// MaxInputLength makes parsing return ErrInputTooLong when the query
+	// string is longer than this many bytes, before any other work is done.
+	// Unlike ParameterLimit it also bounds the size of single long values.
+	// Zero or less means no limit.
+	// Default: 0
+	MaxInputLength int
+
 	// ParseArrays enables array parsing (e.g., "a[0]=b" or "a[]=b").
 	// When false, brackets are preserved as literal characters in keys.
 	// Default: true
 	ParseArrays bool
 
+	// GroupBracketObjects starts a new array element when a sub-key repeats
+	// under empty brackets, as tabular forms produce:
+	// "p[][name]=A&p[][age]=30&p[][name]=B" gives
+	// {"p": [{"name": "A", "age": "30"}, {"name": "B"}]}. By default, as in
+	// JS qs, such keys merge into a single element: [{"name": ["A", "B"],
+	// "age": "30"}]. Only the first "[]" of a key is grouped, and elements
+	// are subject to ArrayLimit like explicit indices.
+	// Default: false
+	GroupBracketObjects bool
+
 	// StrictDepth returns an error when input depth exceeds Depth option.
 	// When false, excess depth is preserved as a literal key.
 	// Default: false
@@ -143,6 +442,134 @@ type ParseOptions struct {
 	// - Leading/trailing/consecutive dots (when AllowDots is true)
 	// Default: false
 	StrictMode bool
+
+	// TopLevelArray makes ParseValue return a []any when every top-level key
+	// is an array index (e.g., "[0]=a&[1]=b" → ["a", "b"]).
+	// Parse always returns a map and ignores this option.
+	// Default: false
+	TopLevelArray bool
+
+	// BraceExpansion splits brace-wrapped, comma-separated values into arrays.
+	// e.g., "a={1,2,3}" → {a: ["1", "2", "3"]}, while "a=1,2" stays a string.
+	// Values without a comma inside the braces (e.g., "{x}") are left as-is.
+	// Default: false
+	BraceExpansion bool
+
+	// ContainerHook is called once for every map and slice in the parsed result,
+	// parents before children, with map keys visited in sorted order.
+	// It runs once the pairs are nested, before any further shaping such
+	// as FlatResult or TopLevelArray, and an error from it stops the
+	// parse. ParseRequest reports the query and the body separately.
+	// Useful for tracing or enforcing custom structure limits.
+	// Default: nil (no hook)
+	ContainerHook ContainerHookFunc
+
+	// Trace is called with events describing how the input is parsed:
+	// parameter splitting, key decoding, key paths, array-versus-object
+	// decisions, and depth truncation. It is meant for debugging
+	// unexpected results; leaving it nil costs nothing.
+	// Default: nil (no tracing)
+	Trace TraceFunc
+
+	// MaxArrayDepth limits how many array levels a single key may nest
+	// (e.g., "a[0][0][0]" has array depth 3), independently of Depth.
+	// Exceeding it returns ErrArrayDepthExceeded. Zero disables the check.
+	// Default: 0
+	MaxArrayDepth int
+
+	// MaxDistinctKeys limits the number of distinct top-level keys in the
+	// result. Unlike ParameterLimit it does not count repeated keys, so
+	// "a=1&a=2&a=3" has one key. Exceeding it returns ErrTooManyKeys.
+	// Zero disables the check.
+	// Default: 0
+	MaxDistinctKeys int
+
+	// FixedArraySize declares top-level keys that hold arrays of at most
+	// the given size, e.g. {"coords": 3}. An index at or beyond the size, or
+	// more elements than it allows, returns an error wrapping
+	// ErrArrayIndexOutOfRange that names the key and index. The arrays are
+	// allocated with the declared capacity.
+	// Default: nil
+	FixedArraySize map[string]int
+
+	// AlwaysArrayKeys lists top-level keys whose value is always an array,
+	// so "tag=a" gives {"tag": ["a"]} just as "tag=a&tag=b" gives
+	// {"tag": ["a", "b"]}. Nested values (e.g., from "tag[x]=a") are left
+	// as they are.
+	// Default: nil
+	AlwaysArrayKeys []string
+
+	// FlatResult returns a flat map keyed by dotted paths instead of nested
+	// maps: "a[b][c]=d&e[]=f" gives {"a.b.c": "d", "e.0": "f"}. The result is
+	// otherwise what Parse would return, flattened with array elements
+	// keyed by index; empty maps and arrays are kept as values. Dots,
+	// brackets and '%' within a key are escaped as %2E, %5B, %5D and %25,
+	// so "a.b=1&a[b]=2" gives {"a%2Eb": "1", "a.b": "2"}. The nested
+	// result is still built first, so this saves no allocations.
+	// Default: false
+	FlatResult bool
+
+	// StripQuotes removes one layer of matching single or double quotes
+	// around each decoded value (e.g., a="b c" → {a: "b c"}).
+	// Unmatched quotes are kept.
+	// Default: false
+	StripQuotes bool
+
+	// BodyPrecedence makes form body params override query params with the
+	// same key in ParseRequest. When false, query params win.
+	// It has no effect on Parse.
+	// Default: true
+	BodyPrecedence bool
+
+	// ParseDuration converts values such as "5s" or "1h30m" to time.Duration.
+	// With Comma, each element is converted separately. Values that are not
+	// valid durations with a unit (including a bare "0") stay strings.
+	// Default: false
+	ParseDuration bool
+
+	// ParseNumbers converts values that are integers or decimal fractions
+	// written in their shortest form, such as "2", "-15" or "0.5", to int64
+	// and float64. Values that would not be written back the same way stay
+	// strings, among them "007", "+1", "-0", "1.50", "1e3", "0x1f" and
+	// integers beyond the int64 range. With Comma, each element is
+	// converted separately.
+	// Default: false
+	ParseNumbers bool
+
+	// ParseBooleans converts the values "true" and "false" to bool. Other
+	// spellings, such as "True", "1" or "yes", stay strings. With Comma,
+	// each element is converted separately.
+	// Default: false
+	ParseBooleans bool
+
+	// KeySplitter replaces the built-in bracket and dot key parsing. It
+	// receives each decoded key and returns its path segments, e.g.
+	// "a/b/0" → ["a", "b", "0"]. The first segment is the top-level key;
+	// later segments that are array indices within ArrayLimit build arrays,
+	// and empty later segments append (like "[]"). Segments beyond Depth are
+	// kept as one literal key, or rejected when StrictDepth is set.
+	// A nil or empty result skips the parameter.
+	// Default: nil (built-in parsing)
+	KeySplitter func(key string) []string
+
+	// RejectControlChars returns an error wrapping ErrControlCharacter when
+	// the raw, undecoded input contains an ASCII control character
+	// (0x00-0x1F) not listed in AllowedControlChars. Percent-encoded
+	// control characters (e.g., %0A) are not rejected.
+	// Default: false
+	RejectControlChars bool
+
+	// AllowedControlChars lists control characters that RejectControlChars
+	// lets through, e.g., "\t".
+	// Default: ""
+	AllowedControlChars string
+
+	// TagName is the struct tag ParseToStruct, ParseInto and Unmarshal
+	// read field names, defaults and the rest option from, as TagName does
+	// for Marshal. Fields without it fall back to their qs tag, then their
+	// json tag, then the lowercase field name.
+	// Default: "query"
+	TagName string
 }
 
 // Default values for ParseOptions
@@ -168,22 +595,59 @@ func DefaultParseOptions() ParseOptions {
 		AllowEmptyArrays:         false,
 		AllowSparse:              false,
 		ArrayLimit:               DefaultArrayLimit,
+		ArrayLimitFor:            nil,
+		MaxArrayIndex:            0,
+		NestedEmptyBrackets:      NestedEmptyBracketsFlatten,
 		Charset:                  CharsetUTF8,
+		InvalidUTF8:              InvalidUTF8Preserve,
+		DecodePasses:             1,
 		CharsetSentinel:          false,
+		SentinelScanLimit:        0,
+		LenientSentinel:          false,
+		CharsetSentinels:         nil,
 		Comma:                    false,
+		CommaDelimiter:           ",",
+		SeparatorByKey:           nil,
+		DoubleDecodeKeys:         nil,
+		ValueSubParse:            nil,
 		DecodeDotInKeys:          false,
 		Decoder:                  nil,
 		Delimiter:                DefaultDelimiter,
 		DelimiterRegexp:          nil,
+		KeyRegexp:                nil,
+		Delimiters:               nil,
+		DelimiterEscape:          0,
 		Depth:                    DefaultDepth,
 		Duplicates:               DuplicateCombine,
+		DepthOverflowMode:        DepthOverflowLiteral,
+		LevelDelimiters:          nil,
 		IgnoreQueryPrefix:        false,
 		InterpretNumericEntities: false,
 		ParameterLimit:           DefaultParameterLimit,
+		MaxInputLength:           0,
 		ParseArrays:              true,
 		StrictDepth:              false,
 		StrictNullHandling:       false,
 		ThrowOnLimitExceeded:     false,
+		TopLevelArray:            false,
+		BraceExpansion:           false,
+		ContainerHook:            nil,
+		Trace:                    nil,
+		MaxArrayDepth:            0,
+		MaxDistinctKeys:          0,
+		FixedArraySize:           nil,
+		AlwaysArrayKeys:          nil,
+		FlatResult:               false,
+		StripQuotes:              false,
+		BodyPrecedence:           true,
+		ParseDuration:            false,
+		ParseNumbers:             false,
+		ParseBooleans:            false,
+		KeySplitter:              nil,
+		RejectDuplicates:         false,
+		RejectControlChars:       false,
+		AllowedControlChars:      "",
+		TagName:                  defaultTagName,
 	}
 }
 
@@ -193,11 +657,28 @@ var (
 	ErrInvalidDecodeDotInKeys  = errors.New("decodeDotInKeys option must be a boolean")
 	ErrInvalidDecoder          = errors.New("decoder must be a function")
 	ErrInvalidCharset          = errors.New("charset must be utf-8 or iso-8859-1")
+	ErrInvalidUTF8Mode         = errors.New("invalidUTF8 must be preserve, replace, or error")
 	ErrInvalidDuplicates       = errors.New("duplicates must be combine, first, or last")
+	ErrInvalidDepthOverflow    = errors.New("depthOverflowMode must be literal, dot, or drop")
+	ErrInvalidLevelDelimiters  = errors.New("levelDelimiters must be non-empty strings")
 	ErrInvalidThrowOnLimit     = errors.New("throwOnLimitExceeded option must be a boolean")
+	ErrInvalidDelimiters       = errors.New("delimiters must be non-empty strings")
 	ErrParameterLimitExceeded  = errors.New("parameter limit exceeded")
+	ErrInputTooLong            = errors.New("input too long")
 	ErrArrayLimitExceeded      = errors.New("array limit exceeded")
 	ErrDepthLimitExceeded      = errors.New("depth limit exceeded")
+	ErrArrayDepthExceeded      = errors.New("array depth limit exceeded")
+	ErrTooManyKeys             = errors.New("too many distinct keys")
+	ErrArrayIndexOutOfRange    = errors.New("array index out of range")
+	ErrInvalidFixedArraySize   = errors.New("fixedArraySize sizes must be non-negative")
+	ErrInvalidSeparatorByKey   = errors.New("separatorByKey separators must be non-empty strings")
+	ErrInvalidCharsetSentinels = errors.New("charsetSentinels charsets and values must be non-empty strings")
+	ErrInvalidValueSubParse    = errors.New("valueSubParse element and pair separators must differ")
+	ErrControlCharacter        = errors.New("control character in input")
+	ErrDuplicateKey            = errors.New("duplicate key")
+	ErrNestedKey               = errors.New("nested key in flat query")
+	ErrInvalidUTF8             = errors.New("invalid UTF-8 in decoded input")
+	ErrInvalidNestedBrackets   = errors.New("nestedEmptyBrackets must be flatten, append, or new")
 )
 
 // Strict mode errors (re-exported from lang package)
@@ -211,13 +692,17 @@ var (
 	ErrTrailingDot            = lang.ErrTrailingDot
 )
 
-// Charset sentinel values for auto-detection
-const (
-	// isoSentinel is what browsers submit when ✓ appears in iso-8859-1 encoded form
-	isoSentinel = "utf8=%26%2310003%3B" // encodeURIComponent('&#10003;')
-	// charsetSentinel is the percent-encoded utf-8 octets for ✓
-	charsetSentinel = "utf8=%E2%9C%93" // encodeURIComponent('✓')
-)
+// Charset sentinel values for auto-detection, the value of the utf8
+// parameter browsers submit for each charset, checked in order.
+var charsetSentinels = []struct {
+	charset Charset
+	value   string
+}{
+	// the percent-encoded utf-8 octets for ✓
+	{CharsetUTF8, "%E2%9C%93"}, // encodeURIComponent('✓')
+	// what browsers submit when ✓ appears in iso-8859-1 encoded form
+	{CharsetISO88591, "%26%2310003%3B"}, // encodeURIComponent('&#10003;')
+}
 
 // normalizeParseOptions validates and fills in defaults for ParseOptions.
 // It returns a new ParseOptions with all fields properly set.
@@ -235,6 +720,14 @@ func normalizeParseOptions(opts *ParseOptions) (ParseOptions, error) {
 		return result, ErrInvalidCharset
 	}
 
+	if result.InvalidUTF8 == "" {
+		result.InvalidUTF8 = InvalidUTF8Preserve
+	} else if result.InvalidUTF8 != InvalidUTF8Preserve &&
+		result.InvalidUTF8 != InvalidUTF8Replace &&
+		result.InvalidUTF8 != InvalidUTF8Error {
+		return result, ErrInvalidUTF8Mode
+	}
+
 	// Validate duplicates
 	if result.Duplicates == "" {
 		result.Duplicates = DuplicateCombine
@@ -244,6 +737,52 @@ func normalizeParseOptions(opts *ParseOptions) (ParseOptions, error) {
 		return result, ErrInvalidDuplicates
 	}
 
+	for _, size := range result.FixedArraySize {
+		if size < 0 {
+			return result, ErrInvalidFixedArraySize
+		}
+	}
+	for _, sep := range result.SeparatorByKey {
+		if sep == "" {
+			return result, ErrInvalidSeparatorByKey
+		}
+	}
+	for _, format := range result.ValueSubParse {
+		if elem, pair := format.separators(); elem == pair {
+			return result, ErrInvalidValueSubParse
+		}
+	}
+	for charset, value := range result.CharsetSentinels {
+		if charset == "" || value == "" {
+			return result, ErrInvalidCharsetSentinels
+		}
+	}
+
+	// Validate level delimiters
+	for _, d := range result.LevelDelimiters {
+		if d == "" {
+			return result, ErrInvalidLevelDelimiters
+		}
+	}
+
+	// Validate depth overflow mode
+	if result.DepthOverflowMode == "" {
+		result.DepthOverflowMode = DepthOverflowLiteral
+	} else if result.DepthOverflowMode != DepthOverflowLiteral &&
+		result.DepthOverflowMode != DepthOverflowDot &&
+		result.DepthOverflowMode != DepthOverflowDrop {
+		return result, ErrInvalidDepthOverflow
+	}
+
+	// Validate nested empty brackets mode
+	if result.NestedEmptyBrackets == "" {
+		result.NestedEmptyBrackets = NestedEmptyBracketsFlatten
+	} else if result.NestedEmptyBrackets != NestedEmptyBracketsFlatten &&
+		result.NestedEmptyBrackets != NestedEmptyBracketsAppendInner &&
+		result.NestedEmptyBrackets != NestedEmptyBracketsNewInner {
+		return result, ErrInvalidNestedBrackets
+	}
+
 	// Set defaults for numeric fields if they are not explicitly set (sentinel value)
 	// This allows explicit 0 values to be preserved
 	if result.ArrayLimit == notSetArrayLimit {
@@ -256,10 +795,20 @@ func normalizeParseOptions(opts *ParseOptions) (ParseOptions, error) {
 		result.ParameterLimit = DefaultParameterLimit
 	}
 
+	// Validate delimiters
+	for _, d := range result.Delimiters {
+		if d == "" {
+			return result, ErrInvalidDelimiters
+		}
+	}
+
 	// Set default delimiter
 	if result.Delimiter == "" && result.DelimiterRegexp == nil {
 		result.Delimiter = DefaultDelimiter
 	}
+	if result.CommaDelimiter == "" {
+		result.CommaDelimiter = ","
+	}
 
 	// If DecodeDotInKeys is true, AllowDots should also be true
 	if result.DecodeDotInKeys && !result.AllowDots {
@@ -300,6 +849,27 @@ func WithParseArrayLimit(v int) ParseOption {
 	}
 }
 
+// WithParseArrayLimitFor sets per-key array limits for top-level keys.
+func WithParseArrayLimitFor(v map[string]int) ParseOption {
+	return func(o *ParseOptions) {
+		o.ArrayLimitFor = v
+	}
+}
+
+// WithParseNestedEmptyBrackets sets how keys like "a[][]" build nested arrays.
+func WithParseNestedEmptyBrackets(v NestedEmptyBracketsMode) ParseOption {
+	return func(o *ParseOptions) {
+		o.NestedEmptyBrackets = v
+	}
+}
+
+// WithParseMaxArrayIndex caps the index that builds an array.
+func WithParseMaxArrayIndex(v int) ParseOption {
+	return func(o *ParseOptions) {
+		o.MaxArrayIndex = v
+	}
+}
+
 // WithParseCharset sets the character encoding to use.
 func WithParseCharset(v Charset) ParseOption {
 	return func(o *ParseOptions) {
@@ -307,6 +877,20 @@ func WithParseCharset(v Charset) ParseOption {
 	}
 }
 
+// WithParseInvalidUTF8 sets how invalid UTF-8 in decoded input is handled.
+func WithParseInvalidUTF8(v InvalidUTF8Mode) ParseOption {
+	return func(o *ParseOptions) {
+		o.InvalidUTF8 = v
+	}
+}
+
+// WithParseDecodePasses decodes keys and values up to n times to repair double encoding.
+func WithParseDecodePasses(n int) ParseOption {
+	return func(o *ParseOptions) {
+		o.DecodePasses = n
+	}
+}
+
 // WithParseCharsetSentinel enables automatic charset detection via utf8=✓ parameter.
 func WithParseCharsetSentinel(v bool) ParseOption {
 	return func(o *ParseOptions) {
@@ -314,6 +898,27 @@ func WithParseCharsetSentinel(v bool) ParseOption {
 	}
 }
 
+// WithParseSentinelScanLimit limits charset sentinel detection to the first n parameters.
+func WithParseSentinelScanLimit(v int) ParseOption {
+	return func(o *ParseOptions) {
+		o.SentinelScanLimit = v
+	}
+}
+
+// WithParseLenientSentinel ignores whitespace around the charset sentinel value.
+func WithParseLenientSentinel(v bool) ParseOption {
+	return func(o *ParseOptions) {
+		o.LenientSentinel = v
+	}
+}
+
+// WithParseCharsetSentinels adds charset sentinel values for other charsets.
+func WithParseCharsetSentinels(v map[Charset]string) ParseOption {
+	return func(o *ParseOptions) {
+		o.CharsetSentinels = v
+	}
+}
+
 // WithParseComma enables parsing comma-separated values as arrays.
 func WithParseComma(v bool) ParseOption {
 	return func(o *ParseOptions) {
@@ -321,6 +926,45 @@ func WithParseComma(v bool) ParseOption {
 	}
 }
 
+// WithParseCommaDelimiter sets the separator Comma splits values on.
+func WithParseCommaDelimiter(v string) ParseOption {
+	return func(o *ParseOptions) {
+		o.CommaDelimiter = v
+	}
+}
+
+// WithParseArrayValueDelimiter splits values on v into arrays, enabling
+// Comma with v as its separator, e.g. "|" parses "ids=1|2|3" into
+// {ids: ["1", "2", "3"]}. v is matched against the raw value, so "%20"
+// splits space-delimited values.
+func WithParseArrayValueDelimiter(v string) ParseOption {
+	return func(o *ParseOptions) {
+		o.Comma = true
+		o.CommaDelimiter = v
+	}
+}
+
+// WithParseSeparatorByKey sets per-key separators for splitting values into arrays.
+func WithParseSeparatorByKey(v map[string]string) ParseOption {
+	return func(o *ParseOptions) {
+		o.SeparatorByKey = v
+	}
+}
+
+// WithParseDoubleDecodeKeys decodes the values of the given keys twice.
+func WithParseDoubleDecodeKeys(keys ...string) ParseOption {
+	return func(o *ParseOptions) {
+		o.DoubleDecodeKeys = keys
+	}
+}
+
+// WithParseValueSubParse parses the values of the given keys into nested maps.
+func WithParseValueSubParse(v map[string]ValueFormat) ParseOption {
+	return func(o *ParseOptions) {
+		o.ValueSubParse = v
+	}
+}
+
 // WithParseDecodeDotInKeys decodes %2E as . in keys.
 func WithParseDecodeDotInKeys(v bool) ParseOption {
 	return func(o *ParseOptions) {
@@ -338,11 +982,37 @@ func WithParseDecoder(v DecoderFunc) ParseOption {
 	}
 }
 
+// WithParseDecoders sets a pipeline of decoders applied in order, each
+// receiving the output of the previous one. The first error stops the
+// pipeline and is returned from Parse. Include DefaultDecoder to keep
+// percent-decoding, e.g.:
+//
+//	qs.WithParseDecoders(qs.DefaultDecoder, trim, lower)
+func WithParseDecoders(v ...DecoderFunc) ParseOption {
+	decoders := append([]DecoderFunc(nil), v...)
+	return func(o *ParseOptions) {
+		if len(decoders) == 0 {
+			o.Decoder = nil
+			return
+		}
+		o.Decoder = func(str string, charset Charset, kind string) (string, error) {
+			for _, d := range decoders {
+				var err error
+				if str, err = d(str, charset, kind); err != nil {
+					return "", err
+				}
+			}
+			return str, nil
+		}
+	}
+}
+
 // WithParseDelimiter sets the string used to split key-value pairs.
 func WithParseDelimiter(v string) ParseOption {
 	return func(o *ParseOptions) {
 		o.Delimiter = v
 		o.DelimiterRegexp = nil
+		o.Delimiters = nil
 	}
 }
 
@@ -351,6 +1021,30 @@ func WithParseDelimiterRegexp(v *regexp.Regexp) ParseOption {
 	return func(o *ParseOptions) {
 		o.DelimiterRegexp = v
 		o.Delimiter = ""
+		o.Delimiters = nil
+	}
+}
+
+// WithParseKeyRegexp keeps only top-level keys matching re.
+func WithParseKeyRegexp(re *regexp.Regexp) ParseOption {
+	return func(o *ParseOptions) {
+		o.KeyRegexp = re
+	}
+}
+
+// WithParseDelimiters sets several literal strings used to split key-value pairs.
+func WithParseDelimiters(v []string) ParseOption {
+	return func(o *ParseOptions) {
+		o.Delimiters = v
+		o.Delimiter = ""
+		o.DelimiterRegexp = nil
+	}
+}
+
+// WithParseDelimiterEscape sets a rune that escapes a literal delimiter in values.
+func WithParseDelimiterEscape(v rune) ParseOption {
+	return func(o *ParseOptions) {
+		o.DelimiterEscape = v
 	}
 }
 
@@ -368,6 +1062,27 @@ func WithParseDuplicates(v DuplicateHandling) ParseOption {
 	}
 }
 
+// WithParseRejectDuplicates rejects input where a key appears more than once.
+func WithParseRejectDuplicates(v bool) ParseOption {
+	return func(o *ParseOptions) {
+		o.RejectDuplicates = v
+	}
+}
+
+// WithParseLevelDelimiters sets a key separator per nesting level.
+func WithParseLevelDelimiters(v ...string) ParseOption {
+	return func(o *ParseOptions) {
+		o.LevelDelimiters = v
+	}
+}
+
+// WithParseDepthOverflowMode sets how key segments beyond Depth are represented.
+func WithParseDepthOverflowMode(v DepthOverflowMode) ParseOption {
+	return func(o *ParseOptions) {
+		o.DepthOverflowMode = v
+	}
+}
+
 // WithParseIgnoreQueryPrefix strips a leading ? from the input string.
 func WithParseIgnoreQueryPrefix(v bool) ParseOption {
 	return func(o *ParseOptions) {
@@ -389,6 +1104,13 @@ func WithParseParameterLimit(v int) ParseOption {
 	}
 }
 
+// WithParseMaxInputLength sets the maximum length of the query string in bytes.
+func WithParseMaxInputLength(v int) ParseOption {
+	return func(o *ParseOptions) {
+		o.MaxInputLength = v
+	}
+}
+
 // WithParseArrays enables or disables array parsing.
 func WithParseArrays(v bool) ParseOption {
 	return func(o *ParseOptions) {
@@ -396,6 +1118,13 @@ func WithParseArrays(v bool) ParseOption {
 	}
 }
 
+// WithParseGroupBracketObjects starts a new element when a key under "[]" repeats.
+func WithParseGroupBracketObjects(v bool) ParseOption {
+	return func(o *ParseOptions) {
+		o.GroupBracketObjects = v
+	}
+}
+
 // WithParseStrictDepth returns an error when input depth exceeds Depth option.
 func WithParseStrictDepth(v bool) ParseOption {
 	return func(o *ParseOptions) {
@@ -426,69 +1155,197 @@ func WithParseStrictMode(v bool) ParseOption {
 	}
 }
 
-// applyParseOptions applies functional options to a ParseOptions struct.
-func applyParseOptions(opts ...ParseOption) ParseOptions {
-	// Start with defaults but use sentinel values for numeric fields
-	// so we can distinguish "not set" from "explicitly set to 0"
-	o := DefaultParseOptions()
-	o.ArrayLimit = notSetArrayLimit
-	o.Depth = notSetDepth
-	o.ParameterLimit = notSetParameterLimit
-	for _, opt := range opts {
-		opt(&o)
+// WithParseTopLevelArray makes ParseValue return a []any when all top-level
+// keys are array indices.
+func WithParseTopLevelArray(v bool) ParseOption {
+	return func(o *ParseOptions) {
+		o.TopLevelArray = v
 	}
-	return o
 }
 
-// interpretNumericEntities converts HTML numeric entities (&#NNN;) to characters.
-// e.g., "&#9786;" → "☺"
-func interpretNumericEntitiesFunc(str string) string {
-	return numericEntityRe.ReplaceAllStringFunc(str, func(match string) string {
-		// Extract the number between &# and ;
-		numStr := match[2 : len(match)-1]
-		num, err := strconv.Atoi(numStr)
-		if err != nil {
-			return match
-		}
-		return string(rune(num))
-	})
+// WithParseBraceExpansion splits brace-wrapped values like "{1,2,3}" into arrays.
+func WithParseBraceExpansion(v bool) ParseOption {
+	return func(o *ParseOptions) {
+		o.BraceExpansion = v
+	}
 }
 
-// decodeBrackets decodes URL-encoded brackets (%5B/%5D) in a single pass.
-func decodeBrackets(s string) string {
-	if !strings.Contains(s, "%5") {
-		return s
+// WithParseContainerHook sets a callback invoked for every map and slice in the result.
+// An error from the callback stops the parse.
+func WithParseContainerHook(v ContainerHookFunc) ParseOption {
+	return func(o *ParseOptions) {
+		o.ContainerHook = v
 	}
+}
 
-	var b strings.Builder
-	b.Grow(len(s))
+// WithParseTrace sets a callback that receives parse trace events.
+func WithParseTrace(v TraceFunc) ParseOption {
+	return func(o *ParseOptions) {
+		o.Trace = v
+	}
+}
 
-	for i := 0; i < len(s); {
-		if i+2 < len(s) && s[i] == '%' && s[i+1] == '5' {
-			switch s[i+2] {
-			case 'B', 'b':
-				b.WriteByte('[')
-				i += 3
-				continue
-			case 'D', 'd':
-				b.WriteByte(']')
-				i += 3
-				continue
-			}
-		}
-		b.WriteByte(s[i])
-		i++
+// WithParseMaxArrayDepth limits the number of nested array levels per key.
+func WithParseMaxArrayDepth(v int) ParseOption {
+	return func(o *ParseOptions) {
+		o.MaxArrayDepth = v
 	}
-	return b.String()
 }
 
-// splitByDelimiter splits a string by either a string delimiter or regexp.
-// Unlike Go's SplitN (which keeps remainder in last element), this matches JS behavior:
-// JS "a&b&c".split("&", 2) returns ["a", "b"] - exactly limit parts, remainder discarded.
-func splitByDelimiter(str string, delimiter string, delimiterRegexp *regexp.Regexp, limit int) []string {
-	if limit <= 0 {
-		// No limit - split all
-		if delimiterRegexp != nil {
+// WithParseMaxDistinctKeys limits the number of distinct top-level keys.
+func WithParseMaxDistinctKeys(v int) ParseOption {
+	return func(o *ParseOptions) {
+		o.MaxDistinctKeys = v
+	}
+}
+
+// WithParseAlwaysArrayKeys makes the listed top-level keys always parse to arrays.
+func WithParseAlwaysArrayKeys(keys []string) ParseOption {
+	return func(o *ParseOptions) {
+		o.AlwaysArrayKeys = keys
+	}
+}
+
+// WithParseFlatResult returns a flat map keyed by dotted paths.
+func WithParseFlatResult(v bool) ParseOption {
+	return func(o *ParseOptions) {
+		o.FlatResult = v
+	}
+}
+
+// WithParseFixedArraySize declares top-level array keys with a maximum size.
+func WithParseFixedArraySize(v map[string]int) ParseOption {
+	return func(o *ParseOptions) {
+		o.FixedArraySize = v
+	}
+}
+
+// WithParseStripQuotes removes one layer of matching quotes around decoded values.
+func WithParseStripQuotes(v bool) ParseOption {
+	return func(o *ParseOptions) {
+		o.StripQuotes = v
+	}
+}
+
+// WithParseBodyPrecedence sets whether body params override query params in ParseRequest.
+func WithParseBodyPrecedence(v bool) ParseOption {
+	return func(o *ParseOptions) {
+		o.BodyPrecedence = v
+	}
+}
+
+// WithParseDuration converts duration values like "5s" to time.Duration.
+func WithParseDuration(v bool) ParseOption {
+	return func(o *ParseOptions) {
+		o.ParseDuration = v
+	}
+}
+
+// WithParseNumbers converts numeric values like "2" or "0.5" to int64 and float64.
+func WithParseNumbers(v bool) ParseOption {
+	return func(o *ParseOptions) {
+		o.ParseNumbers = v
+	}
+}
+
+// WithParseBooleans converts the values "true" and "false" to bool.
+func WithParseBooleans(v bool) ParseOption {
+	return func(o *ParseOptions) {
+		o.ParseBooleans = v
+	}
+}
+
+// WithParseKeySplitter sets a function that splits decoded keys into path segments.
+func WithParseKeySplitter(v func(key string) []string) ParseOption {
+	return func(o *ParseOptions) {
+		o.KeySplitter = v
+	}
+}
+
+// WithParseRejectControlChars rejects input containing raw ASCII control characters.
+func WithParseRejectControlChars(v bool) ParseOption {
+	return func(o *ParseOptions) {
+		o.RejectControlChars = v
+	}
+}
+
+// WithParseAllowedControlChars sets the control characters RejectControlChars allows.
+func WithParseAllowedControlChars(v string) ParseOption {
+	return func(o *ParseOptions) {
+		o.AllowedControlChars = v
+	}
+}
+
+// WithParseTagName sets the struct tag used to name fields when parsing into structs.
+func WithParseTagName(v string) ParseOption {
+	return func(o *ParseOptions) {
+		o.TagName = v
+	}
+}
+
+// applyParseOptions applies functional options to a ParseOptions struct.
+func applyParseOptions(opts ...ParseOption) ParseOptions {
+	// Start with defaults but use sentinel values for numeric fields
+	// so we can distinguish "not set" from "explicitly set to 0"
+	o := DefaultParseOptions()
+	o.ArrayLimit = notSetArrayLimit
+	o.Depth = notSetDepth
+	o.ParameterLimit = notSetParameterLimit
+	for _, opt := range opts {
+		opt(&o)
+	}
+	return o
+}
+
+// interpretNumericEntities converts HTML numeric entities (&#NNN;) to characters.
+// e.g., "&#9786;" → "☺"
+func interpretNumericEntitiesFunc(str string) string {
+	return numericEntityRe.ReplaceAllStringFunc(str, func(match string) string {
+		// Extract the number between &# and ;
+		numStr := match[2 : len(match)-1]
+		num, err := strconv.Atoi(numStr)
+		if err != nil {
+			return match
+		}
+		return string(rune(num))
+	})
+}
+
+// decodeBrackets decodes URL-encoded brackets (%5B/%5D) in a single pass.
+func decodeBrackets(s string) string {
+	if !strings.Contains(s, "%5") {
+		return s
+	}
+
+	var b strings.Builder
+	b.Grow(len(s))
+
+	for i := 0; i < len(s); {
+		if i+2 < len(s) && s[i] == '%' && s[i+1] == '5' {
+			switch s[i+2] {
+			case 'B', 'b':
+				b.WriteByte('[')
+				i += 3
+				continue
+			case 'D', 'd':
+				b.WriteByte(']')
+				i += 3
+				continue
+			}
+		}
+		b.WriteByte(s[i])
+		i++
+	}
+	return b.String()
+}
+
+// splitByDelimiter splits a string by either a string delimiter or regexp.
+// Unlike Go's SplitN (which keeps remainder in last element), this matches JS behavior:
+// JS "a&b&c".split("&", 2) returns ["a", "b"] - exactly limit parts, remainder discarded.
+func splitByDelimiter(str string, delimiter string, delimiterRegexp *regexp.Regexp, limit int) []string {
+	if limit <= 0 {
+		// No limit - split all
+		if delimiterRegexp != nil {
 			return delimiterRegexp.Split(str, -1)
 		}
 		return strings.Split(str, delimiter)
@@ -510,6 +1367,99 @@ func splitByDelimiter(str string, delimiter string, delimiterRegexp *regexp.Rege
 	return parts
 }
 
+// splitEscaped splits a string like splitByDelimiter or splitByDelimiters,
+// but skips delimiters preceded by the escape rune. Escaped delimiters and
+// doubled escape runes are unescaped in the returned parts.
+func splitEscaped(str string, opts *ParseOptions, limit int) []string {
+	escape := string(opts.DelimiterEscape)
+
+	// For regexp delimiters, record where each match starts and ends
+	var regexpMatches map[int]int
+	if opts.DelimiterRegexp != nil && len(opts.Delimiters) == 0 {
+		regexpMatches = make(map[int]int)
+		for _, loc := range opts.DelimiterRegexp.FindAllStringIndex(str, -1) {
+			if loc[1] > loc[0] {
+				regexpMatches[loc[0]] = loc[1]
+			}
+		}
+	}
+	delimiters := opts.Delimiters
+	if len(delimiters) == 0 && regexpMatches == nil {
+		delimiters = []string{opts.Delimiter}
+	}
+
+	// matchAt returns the length of the delimiter starting at i, or 0
+	matchAt := func(i int) int {
+		if regexpMatches != nil {
+			return regexpMatches[i] - i
+		}
+		for _, d := range delimiters {
+			if d != "" && strings.HasPrefix(str[i:], d) {
+				return len(d)
+			}
+		}
+		return 0
+	}
+
+	var parts []string
+	var current strings.Builder
+	for i := 0; i < len(str); {
+		if strings.HasPrefix(str[i:], escape) {
+			next := i + len(escape)
+			if strings.HasPrefix(str[next:], escape) {
+				current.WriteString(escape)
+				i = next + len(escape)
+				continue
+			}
+			if n := matchAt(next); n > 0 {
+				current.WriteString(str[next : next+n])
+				i = next + n
+				continue
+			}
+		}
+		if n := matchAt(i); n > 0 {
+			parts = append(parts, current.String())
+			current.Reset()
+			if limit > 0 && len(parts) == limit {
+				return parts
+			}
+			i += n
+			continue
+		}
+		current.WriteByte(str[i])
+		i++
+	}
+	return append(parts, current.String())
+}
+
+// splitByDelimiters splits a string on any of the given literal delimiters.
+// At each position the first matching delimiter in the list is used.
+// limit behaves as in splitByDelimiter.
+func splitByDelimiters(str string, delimiters []string, limit int) []string {
+	var parts []string
+	start := 0
+	for i := 0; i < len(str); {
+		matched := 0
+		for _, d := range delimiters {
+			if strings.HasPrefix(str[i:], d) {
+				matched = len(d)
+				break
+			}
+		}
+		if matched == 0 {
+			i++
+			continue
+		}
+		parts = append(parts, str[start:i])
+		if limit > 0 && len(parts) == limit {
+			return parts
+		}
+		i += matched
+		start = i
+	}
+	return append(parts, str[start:])
+}
+
 // parseObject builds a nested object structure from a chain of keys.
 // chain is like ["a", "[b]", "[c]"] and val is the leaf value.
 // It builds from the leaf up: {c: val} -> {b: {c: val}} -> {a: {b: {c: val}}}
@@ -519,14 +1469,33 @@ func parseObject(chain []string, val any, opts *ParseOptions, valuesParsed bool)
 	}
 
 	leaf := val
+	arrayLimit := arrayLimitFor(chain[0], opts)
+	start := len(chain) - 1
+
+	// Trailing empty brackets build their arrays at once, see NestedEmptyBrackets
+	if n := emptyBracketRun(chain, opts); n >= 2 {
+		leaf = nestEmptyBrackets(leaf, n, opts)
+		if opts.Trace != nil {
+			for j := 0; j < n; j++ {
+				opts.Trace(TraceContainer, TraceContainerDetail{Segment: "[]", Kind: ContainerSlice})
+			}
+		}
+		start -= n
+		if start == 0 {
+			leaf = growFixedArray(leaf, chain, opts)
+		}
+	}
 
 	// Build from the end of chain backwards
-	for i := len(chain) - 1; i >= 0; i-- {
+	for i := start; i >= 0; i-- {
 		var obj any
 		root := chain[i]
 
 		if root == "[]" && opts.ParseArrays {
 			// Empty brackets means array
+			if opts.Trace != nil {
+				opts.Trace(TraceContainer, TraceContainerDetail{Segment: root, Kind: ContainerSlice})
+			}
 			if opts.AllowEmptyArrays && (leaf == "" || IsExplicitNull(leaf)) {
 				obj = []any{}
 			} else if IsExplicitNull(leaf) {
@@ -559,7 +1528,7 @@ func parseObject(chain []string, val any, opts *ParseOptions, valuesParsed bool)
 			if !opts.ParseArrays && decodedRoot == "" {
 				// When parseArrays is false and key is empty, use "0"
 				obj = map[string]any{"0": leaf}
-			} else if isValidIndex && opts.ParseArrays && index <= opts.ArrayLimit {
+			} else if isValidIndex && opts.ParseArrays && index <= arrayLimit {
 				// Create array with value at index
 				arr := make([]any, index+1)
 				arr[index] = leaf
@@ -569,6 +1538,18 @@ func parseObject(chain []string, val any, opts *ParseOptions, valuesParsed bool)
 				objMap[decodedRoot] = leaf
 				obj = objMap
 			}
+			if opts.Trace != nil {
+				kind := ContainerMap
+				if _, ok := obj.([]any); ok {
+					kind = ContainerSlice
+				}
+				opts.Trace(TraceContainer, TraceContainerDetail{Segment: root, Kind: kind})
+			}
+		}
+
+		// Allocate declared fixed-size arrays up front
+		if i == 1 {
+			obj = growFixedArray(obj, chain, opts)
 		}
 
 		leaf = obj
@@ -577,6 +1558,73 @@ func parseObject(chain []string, val any, opts *ParseOptions, valuesParsed bool)
 	return leaf
 }
 
+// growFixedArray grows the array under the top-level key of chain to the
+// capacity declared in FixedArraySize.
+func growFixedArray(obj any, chain []string, opts *ParseOptions) any {
+	if opts.FixedArraySize == nil {
+		return obj
+	}
+	if arr, ok := obj.([]any); ok {
+		if size := opts.FixedArraySize[chain[0]]; cap(arr) < size {
+			grown := make([]any, len(arr), size)
+			copy(grown, arr)
+			return grown
+		}
+	}
+	return obj
+}
+
+// emptyBracketRun returns the number of empty brackets ending chain after
+// its top-level key, or 0 if NestedEmptyBrackets flattens them.
+func emptyBracketRun(chain []string, opts *ParseOptions) int {
+	if !opts.ParseArrays || opts.NestedEmptyBrackets == NestedEmptyBracketsFlatten {
+		return 0
+	}
+	n := 0
+	for i := len(chain) - 1; i >= 1 && chain[i] == "[]"; i-- {
+		n++
+	}
+	return n
+}
+
+// nestEmptyBrackets builds the arrays for a key ending in n empty
+// brackets from its accumulated values.
+func nestEmptyBrackets(val any, n int, opts *ParseOptions) any {
+	values, ok := val.([]any)
+	if !ok {
+		values = []any{val}
+	}
+
+	inner := func(v any) any {
+		if arr, ok := v.([]any); ok {
+			return arr
+		}
+		if opts.AllowEmptyArrays && (v == "" || IsExplicitNull(v)) {
+			return []any{}
+		}
+		return []any{v}
+	}
+	wrap := func(v any) any {
+		for i := 2; i < n; i++ {
+			v = []any{v}
+		}
+		return v
+	}
+
+	if opts.NestedEmptyBrackets == NestedEmptyBracketsNewInner {
+		outer := make([]any, len(values))
+		for i, v := range values {
+			outer[i] = wrap(inner(v))
+		}
+		return outer
+	}
+	var all any = []any{}
+	for _, v := range values {
+		all = Combine(all, inner(v))
+	}
+	return []any{wrap(all)}
+}
+
 // parseKeys parses a key like "a[b][c]" into nested structure with value.
 // It handles bracket notation, dot notation, depth limits, and prototype protection.
 func parseKeys(givenKey string, val any, opts *ParseOptions, valuesParsed bool) (any, error) {
@@ -584,12 +1632,19 @@ func parseKeys(givenKey string, val any, opts *ParseOptions, valuesParsed bool)
 		return nil, nil
 	}
 
+	if opts.KeySplitter != nil {
+		return parseSplitterKey(givenKey, val, opts, valuesParsed)
+	}
+
 	// Transform dot notation to bracket notation if allowDots is enabled
 	key := givenKey
 	if opts.AllowDots {
 		// Replace .foo with [foo], but not dots inside brackets
 		key = dotNotationRe.ReplaceAllString(key, "[$1]")
 	}
+	if len(opts.LevelDelimiters) > 0 {
+		key = levelDelimitersToBrackets(key, opts.LevelDelimiters)
+	}
 
 	// Find first bracket segment
 	segment := bracketRe.FindStringIndex(key)
@@ -629,21 +1684,281 @@ func parseKeys(givenKey string, val any, opts *ParseOptions, valuesParsed bool)
 
 	// If there's remaining content after depth limit
 	if remaining != "" {
+		if opts.Trace != nil {
+			opts.Trace(TraceDepth, remaining)
+		}
 		if opts.StrictDepth {
 			return nil, ErrDepthLimitExceeded
 		}
 		// Wrap remainder in extra brackets so it becomes a literal key
 		// e.g., "[g]" becomes "[[g]]", which parseObject strips outer brackets
 		// leaving "[g]" as the actual key name
-		keys = append(keys, "["+remaining+"]")
+		if seg, ok := depthOverflowKey(remaining, opts.DepthOverflowMode); ok {
+			keys = append(keys, seg)
+		}
+	}
+
+	if !keepKey(keys, opts) {
+		return nil, nil
+	}
+	if opts.Trace != nil {
+		opts.Trace(TracePath, append([]string(nil), keys...))
+	}
+	if err := checkArrayDepth(keys, opts); err != nil {
+		return nil, err
+	}
+	if err := checkFixedArrayIndex(keys, opts); err != nil {
+		return nil, err
+	}
+
+	return parseObject(keys, val, opts, valuesParsed), nil
+}
+
+// keepKey reports whether the top-level key of chain matches KeyRegexp.
+func keepKey(chain []string, opts *ParseOptions) bool {
+	if opts.KeyRegexp == nil {
+		return true
+	}
+	if len(chain) == 0 {
+		return false
+	}
+	root := chain[0]
+	if len(root) >= 2 && root[0] == '[' && root[len(root)-1] == ']' {
+		root = root[1 : len(root)-1]
+	}
+	return opts.KeyRegexp.MatchString(root)
+}
+
+// levelDelimitersToBrackets rewrites the part of key before any bracket
+// from level-delimited form into bracket notation, e.g. "a.b/c" with
+// [".", "/"] becomes "a[b][c]".
+func levelDelimitersToBrackets(key string, delimiters []string) string {
+	head, tail := key, ""
+	if i := strings.IndexByte(key, '['); i >= 0 {
+		head, tail = key[:i], key[i:]
+	}
+
+	idx := strings.Index(head, delimiters[0])
+	if idx < 0 {
+		return key
+	}
+
+	var b strings.Builder
+	b.WriteString(head[:idx])
+	rest := head[idx+len(delimiters[0]):]
+	for level := 1; ; level++ {
+		d := delimiters[min(level, len(delimiters)-1)]
+		idx := strings.Index(rest, d)
+		if idx < 0 {
+			b.WriteString("[" + rest + "]")
+			break
+		}
+		b.WriteString("[" + rest[:idx] + "]")
+		rest = rest[idx+len(d):]
+	}
+	b.WriteString(tail)
+	return b.String()
+}
+
+// parseSplitterKey builds a nested value from the segments KeySplitter returns for key.
+func parseSplitterKey(key string, val any, opts *ParseOptions, valuesParsed bool) (any, error) {
+	segments := opts.KeySplitter(key)
+	if len(segments) == 0 {
+		return nil, nil
+	}
+
+	keys := make([]string, 0, len(segments))
+	keys = append(keys, segments[0])
+	rest := segments[1:]
+	depth := max(opts.Depth, 0)
+	if len(rest) > depth {
+		if opts.Trace != nil {
+			opts.Trace(TraceDepth, "["+strings.Join(rest[depth:], "][")+"]")
+		}
+		if opts.StrictDepth {
+			return nil, ErrDepthLimitExceeded
+		}
+		// Represent the excess like the built-in parser
+		var b strings.Builder
+		for _, seg := range rest[depth:] {
+			b.WriteString("[" + seg + "]")
+		}
+		for _, seg := range rest[:depth] {
+			keys = append(keys, "["+seg+"]")
+		}
+		if seg, ok := depthOverflowKey(b.String(), opts.DepthOverflowMode); ok {
+			keys = append(keys, seg)
+		}
+	} else {
+		for _, seg := range rest {
+			keys = append(keys, "["+seg+"]")
+		}
+	}
+
+	if !keepKey(keys, opts) {
+		return nil, nil
+	}
+	if opts.Trace != nil {
+		opts.Trace(TracePath, append([]string(nil), keys...))
+	}
+	if err := checkArrayDepth(keys, opts); err != nil {
+		return nil, err
+	}
+	if err := checkFixedArrayIndex(keys, opts); err != nil {
+		return nil, err
 	}
 
 	return parseObject(keys, val, opts, valuesParsed), nil
 }
 
+// depthOverflowKey returns the chain segment for the part of a key beyond
+// Depth, such as "[g][h]", or false if mode drops it. The segment is wrapped
+// in an extra pair of brackets so parseObject treats it as one literal key.
+func depthOverflowKey(remainder string, mode DepthOverflowMode) (string, bool) {
+	switch mode {
+	case DepthOverflowDrop:
+		return "", false
+	case DepthOverflowDot:
+		var parts []string
+		rest := remainder
+		for strings.HasPrefix(rest, "[") {
+			end := strings.IndexByte(rest, ']')
+			if end < 0 {
+				break
+			}
+			parts = append(parts, rest[1:end])
+			rest = rest[end+1:]
+		}
+		return "[" + strings.Join(parts, ".") + rest + "]", true
+	default:
+		return "[" + remainder + "]", true
+	}
+}
+
+// checkFixedArrayIndex returns an error wrapping ErrArrayIndexOutOfRange if
+// chain indexes a FixedArraySize array at or beyond its size.
+func checkFixedArrayIndex(chain []string, opts *ParseOptions) error {
+	if len(chain) < 2 || opts.FixedArraySize == nil {
+		return nil
+	}
+	size, ok := opts.FixedArraySize[chain[0]]
+	if !ok || len(chain[1]) < 2 || chain[1][0] != '[' || chain[1][len(chain[1])-1] != ']' {
+		return nil
+	}
+	inner := chain[1][1 : len(chain[1])-1]
+	index, err := strconv.Atoi(inner)
+	if err != nil || index < 0 || strconv.Itoa(index) != inner {
+		return nil
+	}
+	if index >= size {
+		return fixedArrayError(chain[0], index, size)
+	}
+	return nil
+}
+
+// wrapArrayKeys wraps the scalar values of AlwaysArrayKeys in result in
+// one-element arrays.
+func wrapArrayKeys(result map[string]any, opts *ParseOptions) {
+	for _, key := range opts.AlwaysArrayKeys {
+		switch v := result[key].(type) {
+		case nil, []any, map[string]any:
+		default:
+			result[key] = []any{v}
+		}
+	}
+}
+
+// checkFixedArrayLengths returns an error wrapping ErrArrayIndexOutOfRange
+// if a FixedArraySize array in result has more elements than its size,
+// as happens with repeated keys or "[]" pushes.
+func checkFixedArrayLengths(result map[string]any, opts *ParseOptions) error {
+	if opts.FixedArraySize == nil {
+		return nil
+	}
+	keys := make([]string, 0, len(opts.FixedArraySize))
+	for key := range opts.FixedArraySize {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		size := opts.FixedArraySize[key]
+		if arr, ok := result[key].([]any); ok && len(arr) > size {
+			return fixedArrayError(key, size, size)
+		}
+	}
+	return nil
+}
+
+// fixedArrayError returns the error for index exceeding a fixed array size.
+func fixedArrayError(key string, index, size int) error {
+	return fmt.Errorf("%w: %q index %d (size %d)", ErrArrayIndexOutOfRange, key, index, size)
+}
+
+// arrayLimitFor returns the array limit for arrays under the top-level key.
+func arrayLimitFor(key string, opts *ParseOptions) int {
+	if limit, ok := opts.ArrayLimitFor[key]; ok {
+		return capArrayIndex(limit, opts)
+	}
+	return capArrayIndex(opts.ArrayLimit, opts)
+}
+
+// capArrayIndex lowers the array limit to MaxArrayIndex if it is set.
+func capArrayIndex(limit int, opts *ParseOptions) int {
+	if opts.MaxArrayIndex > 0 {
+		return min(limit, opts.MaxArrayIndex)
+	}
+	return limit
+}
+
+// checkArrayDepth returns ErrArrayDepthExceeded if chain nests more arrays
+// than MaxArrayDepth allows. It counts the segments parseObject would turn
+// into arrays: "[]" and in-limit indices.
+func checkArrayDepth(chain []string, opts *ParseOptions) error {
+	if opts.MaxArrayDepth <= 0 || !opts.ParseArrays {
+		return nil
+	}
+
+	depth := 0
+	arrayLimit := arrayLimitFor(chain[0], opts)
+	for _, root := range chain {
+		if len(root) < 2 || root[0] != '[' || root[len(root)-1] != ']' {
+			continue
+		}
+		inner := root[1 : len(root)-1]
+		if inner != "" {
+			index, err := strconv.Atoi(inner)
+			if err != nil || index < 0 || index > arrayLimit || strconv.Itoa(index) != inner {
+				continue
+			}
+		}
+		depth++
+		if depth > opts.MaxArrayDepth {
+			return ErrArrayDepthExceeded
+		}
+	}
+	return nil
+}
+
+// checkControlChars returns an error wrapping ErrControlCharacter for the
+// first byte in str that is an ASCII control character not in allowed.
+func checkControlChars(str string, allowed string) error {
+	for i := 0; i < len(str); i++ {
+		c := str[i]
+		if c < 0x20 && strings.IndexByte(allowed, c) < 0 {
+			return fmt.Errorf("%w at offset %d: 0x%02X", ErrControlCharacter, i, c)
+		}
+	}
+	return nil
+}
+
 // Parse parses a URL query string into a map.
 // It supports nested objects, arrays, and various encoding options.
 //
+// Keys and values that need no decoding share memory with str instead of
+// being copied, so holding on to any of them keeps all of str in memory.
+// Clone values with strings.Clone when keeping a few small ones from a
+// large input.
+//
 // Example:
 //
 //	result, err := qs.Parse("a=b&c=d")
@@ -663,13 +1978,281 @@ func Parse(str string, opts ...ParseOption) (map[string]any, error) {
 		return nil, err
 	}
 
+	return parseNormalized(str, &normalizedOpts)
+}
+
+// ParseBytes is Parse for a query string held in b, such as a request
+// body read into a buffer. b is copied once up front, so the result never
+// refers to b and b may be reused afterwards.
+//
+// Example:
+//
+//	result, err := qs.ParseBytes(body)
+func ParseBytes(b []byte, opts ...ParseOption) (map[string]any, error) {
+	return Parse(string(b), opts...)
+}
+
+// ParseValue parses a URL query string like Parse, but the root value may be
+// either a map[string]any or a []any.
+//
+// With TopLevelArray enabled, a query whose top-level keys are all array
+// indices within ArrayLimit is returned as a slice. Any other query is
+// returned as the map Parse would produce.
+//
+// Example:
+//
+//	result, err := qs.ParseValue("[0]=a&[1]=b", qs.WithParseTopLevelArray(true))
+//	// result = []any{"a", "b"}
+func ParseValue(str string, opts ...ParseOption) (any, error) {
+	options := applyParseOptions(opts...)
+
+	normalizedOpts, err := normalizeParseOptions(&options)
+	if err != nil {
+		return nil, err
+	}
+
+	result, err := parseNormalized(str, &normalizedOpts)
+	if err != nil {
+		return nil, err
+	}
+
+	var value any = result
+	if normalizedOpts.TopLevelArray {
+		if arr, ok := topLevelArray(result, &normalizedOpts); ok {
+			value = arr
+		}
+	}
+	return value, nil
+}
+
+// ParseFlatString parses a query string without nested keys into a
+// map[string]string, avoiding the interface values Parse stores for the
+// common flat case.
+//
+// A key that Parse would nest, one with brackets or, with AllowDots, a
+// dot, returns an error wrapping ErrNestedKey, and a repeated key returns
+// one wrapping ErrDuplicateKey; both name the key. Options for splitting
+// and decoding apply as in Parse, while those building non-string values,
+// such as Comma, BraceExpansion, and ParseDuration, have no effect. A key
+// without a value maps to "".
+//
+// Example:
+//
+//	result, err := qs.ParseFlatString("page=2&q=go+lang")
+//	// result = map[string]string{"page": "2", "q": "go lang"}
+func ParseFlatString(str string, opts ...ParseOption) (map[string]string, error) {
+	options := applyParseOptions(opts...)
+
+	normalizedOpts, err := normalizeParseOptions(&options)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := checkInputLength(len(str), &normalizedOpts); err != nil {
+		return nil, err
+	}
+	if normalizedOpts.RejectControlChars {
+		if err := checkControlChars(str, normalizedOpts.AllowedControlChars); err != nil {
+			return nil, err
+		}
+	}
+	if normalizedOpts.IgnoreQueryPrefix {
+		str = strings.TrimPrefix(str, "?")
+	}
+	if str == "" {
+		return map[string]string{}, nil
+	}
+
+	limit := normalizedOpts.ParameterLimit
+	if normalizedOpts.ThrowOnLimitExceeded {
+		limit++
+	}
+	parts := splitParams(str, &normalizedOpts, limit)
+	if normalizedOpts.ThrowOnLimitExceeded && len(parts) > normalizedOpts.ParameterLimit {
+		return nil, ErrParameterLimitExceeded
+	}
+
+	charset, skipIndex := detectSentinel(parts, &normalizedOpts)
+	decoder := getDecoder(&normalizedOpts)
+
+	result := make(map[string]string, len(parts))
+	for i, part := range parts {
+		if i == skipIndex || part == "" {
+			continue
+		}
+
+		key, val, _ := strings.Cut(part, "=")
+		key, err := decoder(decodeBrackets(key), charset, "key")
+		if err != nil {
+			return nil, err
+		}
+		if key == "" {
+			continue
+		}
+		if strings.ContainsAny(key, "[]") || (normalizedOpts.AllowDots && strings.Contains(key, ".")) {
+			return nil, fmt.Errorf("%w: %q", ErrNestedKey, key)
+		}
+		if _, exists := result[key]; exists {
+			return nil, fmt.Errorf("%w: %q", ErrDuplicateKey, key)
+		}
+
+		val, err = decoder(val, charset, "value")
+		if err == nil && slices.Contains(normalizedOpts.DoubleDecodeKeys, key) {
+			val, err = decoder(val, charset, "value")
+		}
+		if err != nil {
+			return nil, err
+		}
+		if normalizedOpts.InterpretNumericEntities && charset == CharsetISO88591 {
+			val = interpretNumericEntitiesFunc(val)
+		}
+		if normalizedOpts.StripQuotes {
+			val = stripQuotes(val)
+		}
+		result[key] = val
+	}
+	return result, nil
+}
+
+// walkContainers reports v and every nested map or slice to hook in
+// pre-order, stopping at the first error hook returns.
+func walkContainers(v any, path []string, hook ContainerHookFunc) error {
+	switch t := v.(type) {
+	case map[string]any:
+		if err := hook(path, ContainerMap); err != nil {
+			return err
+		}
+		keys := make([]string, 0, len(t))
+		for k := range t {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			if err := walkContainers(t[k], append(path, k), hook); err != nil {
+				return err
+			}
+		}
+	case []any:
+		if err := hook(path, ContainerSlice); err != nil {
+			return err
+		}
+		for i, item := range t {
+			if err := walkContainers(item, append(path, strconv.Itoa(i)), hook); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// topLevelArray converts a parse result with only index keys into a slice.
+// It reports false if any key is not a canonical index within ArrayLimit.
+func topLevelArray(m map[string]any, opts *ParseOptions) ([]any, bool) {
+	if len(m) == 0 || !opts.ParseArrays {
+		return nil, false
+	}
+
+	maxIndex := -1
+	for k := range m {
+		idx, err := strconv.Atoi(k)
+		if err != nil || idx < 0 || idx > capArrayIndex(opts.ArrayLimit, opts) || strconv.Itoa(idx) != k {
+			return nil, false
+		}
+		if idx > maxIndex {
+			maxIndex = idx
+		}
+	}
+
+	arr := make([]any, maxIndex+1)
+	for k, v := range m {
+		idx, _ := strconv.Atoi(k)
+		arr[idx] = v
+	}
+
+	if opts.AllowSparse {
+		return arr, true
+	}
+
+	// Drop gaps, matching how nested sparse arrays are compacted
+	compacted := make([]any, 0, len(m))
+	for i, v := range arr {
+		if _, exists := m[strconv.Itoa(i)]; exists {
+			compacted = append(compacted, v)
+		}
+	}
+	return compacted, true
+}
+
+// needsSplitParse reports whether opts require the split-based parser
+// instead of lang.Parse.
+func needsSplitParse(opts *ParseOptions) bool {
+	return opts.DelimiterRegexp != nil ||
+		len(opts.Delimiter) > 1 ||
+		len(opts.Delimiters) > 0 ||
+		opts.DelimiterEscape != 0 ||
+		opts.KeySplitter != nil ||
+		len(opts.LevelDelimiters) > 0 ||
+		opts.GroupBracketObjects ||
+		len(opts.SeparatorByKey) > 0 ||
+		len(opts.DoubleDecodeKeys) > 0 ||
+		len(opts.ValueSubParse) > 0 ||
+		len(opts.CharsetSentinels) > 0 ||
+		opts.DecodePasses > 1 ||
+		(opts.Comma && opts.CommaDelimiter != ",")
+}
+
+// parseNormalized parses str using already normalized options.
+func parseNormalized(str string, opts *ParseOptions) (map[string]any, error) {
+	result, err := parseNested(str, opts)
+	if err != nil {
+		return nil, err
+	}
+	return finishParse(result, opts)
+}
+
+// finishParse reports the containers of a nested parse result to
+// ContainerHook and applies FlatResult.
+func finishParse(result map[string]any, opts *ParseOptions) (map[string]any, error) {
+	if opts.ContainerHook != nil {
+		if err := walkContainers(result, nil, opts.ContainerHook); err != nil {
+			return nil, err
+		}
+	}
+	if opts.FlatResult {
+		return flattenResult(result), nil
+	}
+	return result, nil
+}
+
+// checkInputLength returns ErrInputTooLong if an input of n bytes is
+// longer than MaxInputLength.
+func checkInputLength(n int, opts *ParseOptions) error {
+	if opts.MaxInputLength > 0 && n > opts.MaxInputLength {
+		return ErrInputTooLong
+	}
+	return nil
+}
+
+// parseNested parses str into nested maps using already normalized options.
+func parseNested(str string, opts *ParseOptions) (map[string]any, error) {
+	normalizedOpts := *opts
+
+	if err := checkInputLength(len(str), &normalizedOpts); err != nil {
+		return nil, err
+	}
+	if normalizedOpts.RejectControlChars {
+		if err := checkControlChars(str, normalizedOpts.AllowedControlChars); err != nil {
+			return nil, err
+		}
+	}
+
 	// Handle empty input
 	if str == "" {
 		return make(map[string]any), nil
 	}
 
-	// For regexp delimiter or multi-char string delimiter, fall back to split-based parsing
-	if normalizedOpts.DelimiterRegexp != nil || len(normalizedOpts.Delimiter) > 1 {
+	// For delimiters or key handling lang.Parse does not support, fall back to split-based parsing
+	if needsSplitParse(&normalizedOpts) {
 		return parseWithRegexpDelimiter(str, &normalizedOpts)
 	}
 
@@ -704,12 +2287,35 @@ func Parse(str string, opts ...ParseOption) (map[string]any, error) {
 	}
 	keyOrder := make([]string, 0, qs.ParamLen)
 	keyData := make(map[string]*accumulated, qs.ParamLen)
+	var seenKeys map[string]bool
+	if normalizedOpts.RejectDuplicates {
+		seenKeys = make(map[string]bool, qs.ParamLen)
+	}
 
 	for i := uint16(0); i < qs.ParamLen; i++ {
 		param := arena.Params[i]
 		rawKey := arena.GetString(param.Key.Raw)
 
+		if normalizedOpts.Trace != nil {
+			if err := traceParam(arena, param, rawKey, charset, &normalizedOpts); err != nil {
+				return nil, err
+			}
+		}
+
+		if seenKeys != nil && param.Key.SegLen > 0 {
+			decodedKey, err := getDecoder(&normalizedOpts)(rawKey, charset, "key")
+			if err != nil {
+				return nil, err
+			}
+			if err := checkDuplicateKey(seenKeys, decodedKey); err != nil {
+				return nil, err
+			}
+		}
+
 		if existing, exists := keyData[rawKey]; exists {
+			if existing == nil {
+				continue // dropped by KeyRegexp
+			}
 			// Key already seen - just accumulate value
 			val, err := extractValue(arena, param, charset, &normalizedOpts)
 			if err != nil {
@@ -723,7 +2329,7 @@ func Parse(str string, opts ...ParseOption) (map[string]any, error) {
 				existing.val = val
 			default:
 				if normalizedOpts.ThrowOnLimitExceeded {
-					if arr, isArr := existing.val.([]any); isArr && len(arr) >= normalizedOpts.ArrayLimit {
+					if arr, isArr := existing.val.([]any); isArr && len(arr) >= arrayLimitFor(existing.chain[0], &normalizedOpts) {
 						return nil, ErrArrayLimitExceeded
 					}
 				}
@@ -738,6 +2344,19 @@ func Parse(str string, opts ...ParseOption) (map[string]any, error) {
 			if info == nil {
 				continue
 			}
+			if !keepKey(info.chain, &normalizedOpts) {
+				keyData[rawKey] = nil
+				continue
+			}
+			if normalizedOpts.Trace != nil {
+				normalizedOpts.Trace(TracePath, append([]string(nil), info.chain...))
+			}
+			if err := checkArrayDepth(info.chain, &normalizedOpts); err != nil {
+				return nil, err
+			}
+			if err := checkFixedArrayIndex(info.chain, &normalizedOpts); err != nil {
+				return nil, err
+			}
 			keyOrder = append(keyOrder, rawKey)
 			keyData[rawKey] = &accumulated{chain: info.chain, val: info.val}
 		}
@@ -748,13 +2367,25 @@ func Parse(str string, opts ...ParseOption) (map[string]any, error) {
 	for _, rawKey := range keyOrder {
 		data := keyData[rawKey]
 		newObj := parseObject(data.chain, data.val, &normalizedOpts, true)
+		if arr, ok := newObj.([]any); ok {
+			// Root-level index (e.g. "[5]=b"): keep only populated slots,
+			// like JS Object.keys on a sparse array
+			newObj = ArrayToObject(arr)
+		}
 		if newObj != nil {
 			merged := Merge(result, newObj)
 			if m, ok := merged.(map[string]any); ok {
 				result = m
 			}
+			if normalizedOpts.MaxDistinctKeys > 0 && len(result) > normalizedOpts.MaxDistinctKeys {
+				return nil, ErrTooManyKeys
+			}
 		}
 	}
+	wrapArrayKeys(result, &normalizedOpts)
+	if err := checkFixedArrayLengths(result, &normalizedOpts); err != nil {
+		return nil, err
+	}
 
 	// Compact sparse arrays if AllowSparse is false
 	if !normalizedOpts.AllowSparse {
@@ -765,8 +2396,31 @@ func Parse(str string, opts ...ParseOption) (map[string]any, error) {
 	} else {
 		convertExplicitNulls(result)
 	}
-
-	return result, nil
+
+	return result, nil
+}
+
+// traceParam reports the TraceSegment and TraceKey events for a parameter
+// parsed by lang.Parse.
+func traceParam(arena *lang.Arena, param lang.Param, rawKey string, charset Charset, opts *ParseOptions) error {
+	segment := rawKey
+	if param.HasEquals {
+		segment += "="
+		if param.ValueIdx != 0xFFFF {
+			segment += arena.GetString(arena.Values[param.ValueIdx].Raw)
+		}
+	}
+	opts.Trace(TraceSegment, segment)
+
+	if param.Key.SegLen == 0 {
+		return nil
+	}
+	decodedKey, err := getDecoder(opts)(rawKey, charset, "key")
+	if err != nil {
+		return err
+	}
+	opts.Trace(TraceKey, decodedKey)
+	return nil
 }
 
 // buildLangConfig converts ParseOptions to lang.Config.
@@ -792,6 +2446,10 @@ func buildLangConfig(opts *ParseOptions) lang.Config {
 	cfg.ParseArrays = opts.ParseArrays
 	cfg.Charset = charsetToLang(opts.Charset)
 
+	if opts.SentinelScanLimit > 0 {
+		cfg.SentinelScanLimit = uint16(min(opts.SentinelScanLimit, math.MaxUint16))
+	}
+
 	// Flags
 	if opts.AllowDots {
 		cfg.Flags |= lang.FlagAllowDots
@@ -811,6 +2469,9 @@ func buildLangConfig(opts *ParseOptions) lang.Config {
 	if opts.CharsetSentinel {
 		cfg.Flags |= lang.FlagCharsetSentinel
 	}
+	if opts.LenientSentinel {
+		cfg.Flags |= lang.FlagLenientSentinel
+	}
 	if opts.DecodeDotInKeys {
 		cfg.Flags |= lang.FlagDecodeDotInKeys
 	}
@@ -830,13 +2491,102 @@ type keyInfoResult struct {
 	val   any
 }
 
+// bracketGroups assigns explicit indices to "[]" keys for
+// GroupBracketObjects, tracking the current element of each array.
+type bracketGroups struct {
+	current map[string]*bracketGroup
+}
+
+type bracketGroup struct {
+	index int
+	seen  map[string]bool
+}
+
+// index rewrites the first "[]" in key that is followed by a sub-key to
+// the index of the array element the sub-key belongs to, starting a new
+// element when the current one already has it: "p[][a]" becomes "p[0][a]",
+// then "p[1][a]" the next time it appears.
+func (g *bracketGroups) index(key string) string {
+	i := strings.Index(key, "[]")
+	if i <= 0 || i+2 == len(key) {
+		return key
+	}
+	parent, rest := key[:i], key[i+2:]
+
+	if g.current == nil {
+		g.current = make(map[string]*bracketGroup)
+	}
+	group, ok := g.current[parent]
+	if !ok {
+		group = &bracketGroup{seen: make(map[string]bool)}
+		g.current[parent] = group
+	} else if group.seen[rest] {
+		group.index++
+		group.seen = make(map[string]bool)
+	}
+	group.seen[rest] = true
+
+	return parent + "[" + strconv.Itoa(group.index) + "]" + rest
+}
+
+// checkDuplicateKey records key in seen and returns an error wrapping
+// ErrDuplicateKey if it was already there. Keys ending in "[]" may repeat.
+func checkDuplicateKey(seen map[string]bool, key string) error {
+	if strings.HasSuffix(key, "[]") {
+		return nil
+	}
+	if seen[key] {
+		return fmt.Errorf("%w: %q", ErrDuplicateKey, key)
+	}
+	seen[key] = true
+	return nil
+}
+
 // getDecoder returns the decoder function from options or default.
 func getDecoder(opts *ParseOptions) DecoderFunc {
 	if opts.Decoder != nil {
 		return opts.Decoder
 	}
-	return func(s string, cs Charset, kind string) (string, error) {
-		return Decode(s, cs), nil
+	decoder := DefaultDecoder
+	switch opts.InvalidUTF8 {
+	case InvalidUTF8Replace:
+		decoder = func(str string, charset Charset, kind string) (string, error) {
+			decoded := Decode(str, charset)
+			if charset == CharsetUTF8 && !utf8.ValidString(decoded) {
+				decoded = strings.ToValidUTF8(decoded, "\uFFFD")
+			}
+			return decoded, nil
+		}
+	case InvalidUTF8Error:
+		decoder = func(str string, charset Charset, kind string) (string, error) {
+			decoded := Decode(str, charset)
+			if charset == CharsetUTF8 && !utf8.ValidString(decoded) {
+				return "", fmt.Errorf("%w in %s %q", ErrInvalidUTF8, kind, str)
+			}
+			return decoded, nil
+		}
+	}
+	if opts.DecodePasses > 1 {
+		decoder = repeatDecoder(decoder, opts.DecodePasses)
+	}
+	return decoder
+}
+
+// repeatDecoder returns a decoder applying decode up to passes times, see
+// DecodePasses.
+func repeatDecoder(decode DecoderFunc, passes int) DecoderFunc {
+	return func(str string, charset Charset, kind string) (string, error) {
+		decoded, err := decode(str, charset, kind)
+		for i := 1; i < passes && err == nil && strings.Contains(decoded, "%"); i++ {
+			var next string
+			// Spaces were decoded by the first pass, so keep "+" literal
+			next, err = decode(strings.ReplaceAll(decoded, "+", "%2B"), charset, kind)
+			if next == decoded {
+				break
+			}
+			decoded = next
+		}
+		return decoded, err
 	}
 }
 
@@ -854,37 +2604,66 @@ func extractValue(arena *lang.Arena, param lang.Param, charset Charset, opts *Pa
 		}
 	} else if param.ValueIdx != 0xFFFF {
 		v := arena.Values[param.ValueIdx]
-		switch v.Kind {
-		case lang.ValNull:
-			if opts.StrictNullHandling {
-				val = ExplicitNullValue
-			} else {
-				val = ""
+		expanded := false
+		if opts.BraceExpansion && v.Kind != lang.ValNull {
+			parts, ok, err := expandBraces(arena.GetString(v.Raw), charset, decoder)
+			if err != nil {
+				return nil, err
 			}
-		case lang.ValComma:
-			parts := make([]any, v.PartsLen)
-			for j := uint8(0); j < v.PartsLen; j++ {
-				partSpan := arena.ValueParts[int(v.PartsOff)+int(j)]
-				decoded, err := decoder(arena.GetString(partSpan), charset, "value")
+			if ok {
+				val = parts
+				expanded = true
+			}
+		}
+		if !expanded {
+			switch v.Kind {
+			case lang.ValNull:
+				if opts.StrictNullHandling {
+					val = ExplicitNullValue
+				} else {
+					val = ""
+				}
+			case lang.ValComma:
+				parts := make([]any, v.PartsLen)
+				for j := uint8(0); j < v.PartsLen; j++ {
+					partSpan := arena.ValueParts[int(v.PartsOff)+int(j)]
+					decoded, err := decoder(arena.GetString(partSpan), charset, "value")
+					if err != nil {
+						return nil, err
+					}
+					parts[j] = decoded
+				}
+				val = parts
+			default:
+				decoded, err := decoder(arena.GetString(v.Raw), charset, "value")
 				if err != nil {
 					return nil, err
 				}
-				parts[j] = decoded
-			}
-			val = parts
-		default:
-			decoded, err := decoder(arena.GetString(v.Raw), charset, "value")
-			if err != nil {
-				return nil, err
+				val = decoded
 			}
-			val = decoded
 		}
 	} else {
 		val = ""
 	}
 
 	if opts.InterpretNumericEntities && charset == CharsetISO88591 {
-		val = applyNumericEntities(val)
+		val = mapStringValues(val, interpretNumericEntitiesValue)
+	}
+
+	if opts.StripQuotes {
+		val = mapStringValues(val, stripQuotesValue)
+	}
+
+	if opts.ParseDuration {
+		val = mapStringValues(val, parseDurationValue)
+	}
+
+	if opts.ParseNumbers {
+		val = mapStringValues(val, parseNumberValue)
+	}
+
+	if opts.ParseBooleans {
+		val = mapStringValues(val, parseBoolValue)
 	}
 
 	// Wrap comma-split array if key ends with []
@@ -900,21 +2679,158 @@ func extractValue(arena *lang.Arena, param lang.Param, charset Charset, opts *Pa
 	return val, nil
 }
 
-// applyNumericEntities interprets numeric entities in value.
-func applyNumericEntities(val any) any {
+// stripQuotes removes matching single or double quotes around s.
+func stripQuotes(s string) string {
+	if len(s) >= 2 && (s[0] == '"' || s[0] == '\'') && s[len(s)-1] == s[0] {
+		return s[1 : len(s)-1]
+	}
+	return s
+}
+
+// mapStringValues replaces a string value, or each string element of a
+// comma-split value, with f applied to it. Other values are returned as is.
+func mapStringValues(val any, f func(s string) any) any {
 	if s, ok := val.(string); ok {
-		return interpretNumericEntitiesFunc(s)
+		return f(s)
 	}
 	if arr, ok := val.([]any); ok {
 		for i, v := range arr {
 			if s, ok := v.(string); ok {
-				arr[i] = interpretNumericEntitiesFunc(s)
+				arr[i] = f(s)
 			}
 		}
 	}
 	return val
 }
 
+// decodeValueAgain decodes a value, or each element of a comma-split
+// value, a second time, see DoubleDecodeKeys.
+func decodeValueAgain(val any, charset Charset, decoder DecoderFunc) (any, error) {
+	var err error
+	val = mapStringValues(val, func(s string) any {
+		if err != nil {
+			return s
+		}
+		decoded, decodeErr := decoder(s, charset, "value")
+		if decodeErr != nil {
+			err = decodeErr
+			return s
+		}
+		return decoded
+	})
+	if err != nil {
+		return nil, err
+	}
+	return val, nil
+}
+
+// interpretNumericEntitiesValue is interpretNumericEntitiesFunc for
+// mapStringValues.
+func interpretNumericEntitiesValue(s string) any {
+	return interpretNumericEntitiesFunc(s)
+}
+
+// stripQuotesValue is stripQuotes for mapStringValues.
+func stripQuotesValue(s string) any {
+	return stripQuotes(s)
+}
+
+// subParseValue parses a packed key/value list into a map, decoding each
+// key and value.
+func subParseValue(val string, format ValueFormat, charset Charset, decoder DecoderFunc) (map[string]any, error) {
+	elemSep, pairSep := format.separators()
+	result := make(map[string]any)
+	for _, elem := range strings.Split(val, elemSep) {
+		if elem == "" {
+			continue
+		}
+		k, v, _ := strings.Cut(elem, pairSep)
+		key, err := decoder(k, charset, "key")
+		if err != nil {
+			return nil, err
+		}
+		value, err := decoder(v, charset, "value")
+		if err != nil {
+			return nil, err
+		}
+		result[key] = value
+	}
+	return result, nil
+}
+
+// parseDurationValue returns s as a time.Duration if it is a valid duration
+// ending in a unit, or s unchanged otherwise.
+func parseDurationValue(s string) any {
+	if s == "" {
+		return s
+	}
+	if last := s[len(s)-1]; last < 'a' || last > 'z' {
+		return s
+	}
+	d, err := time.ParseDuration(s)
+	if err != nil {
+		return s
+	}
+	return d
+}
+
+// parseNumberValue returns s as an int64 or float64 if formatting the
+// number gives s back, or s unchanged otherwise.
+func parseNumberValue(s string) any {
+	if !strings.Contains(s, ".") {
+		if n, err := strconv.ParseInt(s, 10, 64); err == nil && strconv.FormatInt(n, 10) == s {
+			return n
+		}
+		return s
+	}
+	if f, err := strconv.ParseFloat(s, 64); err == nil && strconv.FormatFloat(f, 'f', -1, 64) == s {
+		return f
+	}
+	return s
+}
+
+// parseBoolValue returns s as a bool if it is "true" or "false", or s
+// unchanged otherwise.
+func parseBoolValue(s string) any {
+	switch s {
+	case "true":
+		return true
+	case "false":
+		return false
+	}
+	return s
+}
+
+// expandBraces splits a raw brace-wrapped value like "{1,2,3}" into its
+// decoded elements. Braces may be literal or percent-encoded (%7B/%7D).
+// It reports false if raw is not brace-wrapped or has no comma inside.
+func expandBraces(raw string, charset Charset, decoder DecoderFunc) ([]any, bool, error) {
+	var inner string
+	switch {
+	case len(raw) >= 2 && raw[0] == '{' && raw[len(raw)-1] == '}':
+		inner = raw[1 : len(raw)-1]
+	case len(raw) >= 6 && strings.EqualFold(raw[:3], "%7B") && strings.EqualFold(raw[len(raw)-3:], "%7D"):
+		inner = raw[3 : len(raw)-3]
+	default:
+		return nil, false, nil
+	}
+
+	if !strings.Contains(inner, ",") {
+		return nil, false, nil
+	}
+
+	rawParts := strings.Split(inner, ",")
+	parts := make([]any, len(rawParts))
+	for i, p := range rawParts {
+		decoded, err := decoder(p, charset, "value")
+		if err != nil {
+			return nil, false, err
+		}
+		parts[i] = decoded
+	}
+	return parts, true, nil
+}
+
 // buildKeyInfo extracts key chain and value from AST param.
 func buildKeyInfo(arena *lang.Arena, param lang.Param, charset Charset, opts *ParseOptions) (*keyInfoResult, error) {
 	key := param.Key
@@ -943,7 +2859,12 @@ func buildKeyInfo(arena *lang.Arena, param lang.Param, charset Charset, opts *Pa
 		case lang.SegEmpty:
 			chain = append(chain, "[]")
 		case lang.SegLiteral:
-			chain = append(chain, "["+decoded+"]")
+			if opts.Trace != nil {
+				opts.Trace(TraceDepth, decoded)
+			}
+			if seg, ok := depthOverflowKey(decoded, opts.DepthOverflowMode); ok {
+				chain = append(chain, seg)
+			}
 		default: // SegIdent, SegIndex
 			if seg.Notation == lang.NotationRoot {
 				chain = append(chain, decoded)
@@ -956,7 +2877,7 @@ func buildKeyInfo(arena *lang.Arena, param lang.Param, charset Charset, opts *Pa
 	return &keyInfoResult{chain: chain, val: val}, nil
 }
 
-// parseWithRegexpDelimiter handles parsing when a regexp or multi-char delimiter is used.
+// parseWithRegexpDelimiter handles parsing when a regexp, multi-char or multiple delimiters are used.
 // This falls back to the split-based approach since lang.Parse only supports single-byte delimiters.
 func parseWithRegexpDelimiter(str string, opts *ParseOptions) (map[string]any, error) {
 	// Strip query prefix if requested
@@ -971,8 +2892,8 @@ func parseWithRegexpDelimiter(str string, opts *ParseOptions) (map[string]any, e
 		limit = opts.ParameterLimit + 1
 	}
 
-	// Split by regexp delimiter
-	parts := splitByDelimiter(cleanStr, opts.Delimiter, opts.DelimiterRegexp, limit)
+	// Split by delimiter
+	parts := splitParams(cleanStr, opts, limit)
 
 	// Check parameter limit
 	if opts.ThrowOnLimitExceeded && len(parts) > opts.ParameterLimit {
@@ -980,37 +2901,17 @@ func parseWithRegexpDelimiter(str string, opts *ParseOptions) (map[string]any, e
 	}
 
 	// Detect charset from sentinel
-	charset := opts.Charset
-	skipIndex := -1
-	if opts.CharsetSentinel {
-		for i, part := range parts {
-			if strings.HasPrefix(part, "utf8=") {
-				if part == charsetSentinel {
-					charset = CharsetUTF8
-					skipIndex = i
-				} else if part == isoSentinel {
-					charset = CharsetISO88591
-					skipIndex = i
-				}
-				break
-			}
-		}
-	}
-
-	// Setup decoder
-	decoder := opts.Decoder
-	if decoder == nil {
-		decoder = func(s string, cs Charset, kind string) (string, error) {
-			return Decode(s, cs), nil
-		}
-	}
+	charset, skipIndex := detectSentinel(parts, opts)
 
-	// Parse each part
-	result := make(map[string]any)
+	// Parse each part, accumulating values by key as lang.Parse does
+	p := newPairParser(len(parts), charset, getDecoder(opts), opts)
 	for i, part := range parts {
 		if i == skipIndex || part == "" {
 			continue
 		}
+		if opts.Trace != nil {
+			opts.Trace(TraceSegment, part)
+		}
 
 		// Find the = separator (respecting brackets)
 		eqIdx := findEqualsOutsideBrackets(part)
@@ -1028,85 +2929,205 @@ func parseWithRegexpDelimiter(str string, opts *ParseOptions) (map[string]any, e
 		// Decode brackets in key
 		key = decodeBrackets(key)
 
-		// Decode key
-		decodedKey, err := decoder(key, charset, "key")
-		if err != nil {
+		if err := p.add(key, val, hasEquals, strings.Contains(part, "[]=")); err != nil {
 			return nil, err
 		}
-		if decodedKey == "" {
-			continue
+	}
+
+	return p.result()
+}
+
+// pairParser accumulates the key/value pairs of a query by decoded key, as
+// lang.Parse does, and nests them into a parse result. It serves the
+// split-based parser and callers such as FromURLValues whose pairs are
+// already split.
+type pairParser struct {
+	opts     *ParseOptions
+	charset  Charset
+	decoder  DecoderFunc
+	keyOrder []string
+	values   map[string]any
+	seenKeys map[string]bool
+	groups   *bracketGroups
+}
+
+// newPairParser returns a pairParser for about n pairs, decoded with
+// decoder in charset.
+func newPairParser(n int, charset Charset, decoder DecoderFunc, opts *ParseOptions) *pairParser {
+	p := &pairParser{
+		opts:     opts,
+		charset:  charset,
+		decoder:  decoder,
+		keyOrder: make([]string, 0, n),
+		values:   make(map[string]any, n),
+	}
+	if opts.RejectDuplicates {
+		p.seenKeys = make(map[string]bool, n)
+	}
+	if opts.GroupBracketObjects && opts.ParseArrays {
+		p.groups = &bracketGroups{}
+	}
+	return p
+}
+
+// add decodes a pair and accumulates its value under its key. hasEquals
+// reports whether the pair had a value at all, and wrapArray whether a
+// value split into an array is itself one element, as in "a[]=b,c".
+func (p *pairParser) add(key, val string, hasEquals, wrapArray bool) error {
+	opts, charset, decoder := p.opts, p.charset, p.decoder
+
+	// Decode key
+	decodedKey, err := decoder(key, charset, "key")
+	if err != nil {
+		return err
+	}
+	if decodedKey == "" {
+		return nil
+	}
+	if opts.Trace != nil {
+		opts.Trace(TraceKey, decodedKey)
+	}
+	separator, split := opts.CommaDelimiter, opts.Comma
+	if sep, ok := opts.SeparatorByKey[decodedKey]; ok {
+		separator, split = sep, true
+	}
+	decodeTwice := slices.Contains(opts.DoubleDecodeKeys, decodedKey)
+	format, subParse := opts.ValueSubParse[decodedKey]
+	if p.groups != nil {
+		decodedKey = p.groups.index(decodedKey)
+	}
+	if p.seenKeys != nil {
+		if err := checkDuplicateKey(p.seenKeys, decodedKey); err != nil {
+			return err
 		}
+	}
 
-		// Handle value
-		var parsedVal any
-		if !hasEquals {
-			if opts.StrictNullHandling {
-				parsedVal = ExplicitNullValue
-			} else {
-				parsedVal = ""
-			}
+	// Handle value
+	var parsedVal any
+	if !hasEquals {
+		if opts.StrictNullHandling {
+			parsedVal = ExplicitNullValue
 		} else {
-			// Handle comma values
-			if val != "" && opts.Comma && strings.Contains(val, ",") {
-				valParts := strings.Split(val, ",")
-				arr := make([]any, len(valParts))
-				for j, p := range valParts {
-					decoded, err := decoder(p, charset, "value")
-					if err != nil {
-						return nil, err
-					}
-					arr[j] = decoded
-				}
-				parsedVal = arr
-			} else {
-				decoded, err := decoder(val, charset, "value")
+			parsedVal = ""
+		}
+	} else {
+		// Handle brace-wrapped and comma values
+		var braceParts []any
+		expanded := false
+		if opts.BraceExpansion {
+			braceParts, expanded, err = expandBraces(val, charset, decoder)
+			if err != nil {
+				return err
+			}
+		}
+		if expanded {
+			parsedVal = braceParts
+		} else if val != "" && subParse {
+			parsedVal, err = subParseValue(val, format, charset, decoder)
+			if err != nil {
+				return err
+			}
+		} else if val != "" && split && strings.Contains(val, separator) {
+			valParts := strings.Split(val, separator)
+			arr := make([]any, len(valParts))
+			for j, part := range valParts {
+				decoded, err := decoder(part, charset, "value")
 				if err != nil {
-					return nil, err
+					return err
 				}
-				parsedVal = decoded
+				arr[j] = decoded
 			}
+			parsedVal = arr
+		} else {
+			decoded, err := decoder(val, charset, "value")
+			if err != nil {
+				return err
+			}
+			parsedVal = decoded
+		}
 
-			// Interpret numeric entities if enabled
-			if opts.InterpretNumericEntities && charset == CharsetISO88591 {
-				if s, ok := parsedVal.(string); ok {
-					parsedVal = interpretNumericEntitiesFunc(s)
-				} else if arr, ok := parsedVal.([]any); ok {
-					for j, v := range arr {
-						if s, ok := v.(string); ok {
-							arr[j] = interpretNumericEntitiesFunc(s)
-						}
-					}
-				}
+		if decodeTwice {
+			if parsedVal, err = decodeValueAgain(parsedVal, charset, decoder); err != nil {
+				return err
 			}
+		}
 
-			// Handle []= pattern
-			if strings.Contains(part, "[]=") {
-				if arr, ok := parsedVal.([]any); ok {
-					parsedVal = []any{arr}
-				}
+		// Interpret numeric entities if enabled
+		if opts.InterpretNumericEntities && charset == CharsetISO88591 {
+			parsedVal = mapStringValues(parsedVal, interpretNumericEntitiesValue)
+		}
+
+		if opts.StripQuotes {
+			parsedVal = mapStringValues(parsedVal, stripQuotesValue)
+		}
+
+		if opts.ParseDuration {
+			parsedVal = mapStringValues(parsedVal, parseDurationValue)
+		}
+
+		if opts.ParseNumbers {
+			parsedVal = mapStringValues(parsedVal, parseNumberValue)
+		}
+
+		if opts.ParseBooleans {
+			parsedVal = mapStringValues(parsedVal, parseBoolValue)
+		}
+
+		// Handle []= pattern
+		if wrapArray {
+			if arr, ok := parsedVal.([]any); ok {
+				parsedVal = []any{arr}
 			}
 		}
+	}
+
+	existing, exists := p.values[decodedKey]
+	if !exists {
+		p.keyOrder = append(p.keyOrder, decodedKey)
+		p.values[decodedKey] = parsedVal
+		return nil
+	}
+	switch opts.Duplicates {
+	case DuplicateFirst:
+		// Keep existing
+	case DuplicateLast:
+		p.values[decodedKey] = parsedVal
+	default:
+		p.values[decodedKey] = Combine(existing, parsedVal)
+	}
+	return nil
+}
+
+// result nests the accumulated values into a parse result.
+func (p *pairParser) result() (map[string]any, error) {
+	opts := p.opts
 
-		// Build nested structure
-		newObj, err := parseKeys(decodedKey, parsedVal, opts, true)
+	// Build nested structure from accumulated values
+	result := make(map[string]any)
+	for _, key := range p.keyOrder {
+		newObj, err := parseKeys(key, p.values[key], opts, true)
 		if err != nil {
 			return nil, err
 		}
 
+		if arr, ok := newObj.([]any); ok {
+			newObj = ArrayToObject(arr)
+		}
+
 		if newObj != nil {
-			switch opts.Duplicates {
-			case DuplicateFirst:
-				result = mergeKeepFirst(result, newObj)
-			case DuplicateLast:
-				result = mergeKeepLast(result, newObj)
-			default:
-				merged := Merge(result, newObj)
-				if m, ok := merged.(map[string]any); ok {
-					result = m
-				}
+			merged := Merge(result, newObj)
+			if m, ok := merged.(map[string]any); ok {
+				result = m
+			}
+			if opts.MaxDistinctKeys > 0 && len(result) > opts.MaxDistinctKeys {
+				return nil, ErrTooManyKeys
 			}
 		}
 	}
+	wrapArrayKeys(result, opts)
+	if err := checkFixedArrayLengths(result, opts); err != nil {
+		return nil, err
+	}
 
 	// Compact sparse arrays if AllowSparse is false
 	if !opts.AllowSparse {
@@ -1121,6 +3142,83 @@ func parseWithRegexpDelimiter(str string, opts *ParseOptions) (map[string]any, e
 	return result, nil
 }
 
+// detectSentinel returns the charset of split parts, from a charset
+// sentinel if CharsetSentinel is set, and the index of the sentinel part,
+// or -1 if there is none.
+func detectSentinel(parts []string, opts *ParseOptions) (Charset, int) {
+	if !opts.CharsetSentinel {
+		return opts.Charset, -1
+	}
+	for i, part := range parts {
+		if opts.SentinelScanLimit > 0 && i >= opts.SentinelScanLimit {
+			break
+		}
+		if strings.HasPrefix(part, "utf8=") {
+			if charset, ok := sentinelCharset(part, opts); ok {
+				return charset, i
+			}
+			break
+		}
+	}
+	return opts.Charset, -1
+}
+
+// sentinelCharset returns the charset a charset sentinel parameter such
+// as "utf8=%E2%9C%93" names, or false if part is not a known sentinel,
+// either built in or from CharsetSentinels. Hex digits match without
+// regard to case, as in lang.Parse.
+func sentinelCharset(part string, opts *ParseOptions) (Charset, bool) {
+	value, ok := strings.CutPrefix(part, "utf8=")
+	if !ok {
+		return "", false
+	}
+	if opts.LenientSentinel {
+		value = trimEncodedSpace(value)
+	}
+	for _, sentinel := range charsetSentinels {
+		if strings.EqualFold(value, sentinel.value) {
+			return sentinel.charset, true
+		}
+	}
+	for charset, sentinel := range opts.CharsetSentinels {
+		if strings.EqualFold(value, sentinel) {
+			return charset, true
+		}
+	}
+	return "", false
+}
+
+// trimEncodedSpace removes leading and trailing spaces and tabs from s,
+// raw or encoded as "+", "%20", or "%09".
+func trimEncodedSpace(s string) string {
+	spaces := [...]string{" ", "\t", "+", "%20", "%09"}
+	for trimmed := true; trimmed; {
+		trimmed = false
+		for _, sp := range spaces {
+			if strings.HasPrefix(s, sp) {
+				s, trimmed = s[len(sp):], true
+			}
+			if strings.HasSuffix(s, sp) {
+				s, trimmed = s[:len(s)-len(sp)], true
+			}
+		}
+	}
+	return s
+}
+
+// splitParams splits str into parameters on the delimiter opts specify,
+// returning at most limit parts.
+func splitParams(str string, opts *ParseOptions, limit int) []string {
+	switch {
+	case opts.DelimiterEscape != 0:
+		return splitEscaped(str, opts, limit)
+	case len(opts.Delimiters) > 0:
+		return splitByDelimiters(str, opts.Delimiters, limit)
+	default:
+		return splitByDelimiter(str, opts.Delimiter, opts.DelimiterRegexp, limit)
+	}
+}
+
 // findEqualsOutsideBrackets finds the index of '=' that is not inside brackets.
 func findEqualsOutsideBrackets(s string) int {
 	depth := 0
@@ -1170,65 +3268,6 @@ func estimateParams(s string) int {
 	return n
 }
 
-// mergeKeepFirst merges newObj into result, keeping first values for duplicates.
-func mergeKeepFirst(result map[string]any, newObj any) map[string]any {
-	newMap, ok := newObj.(map[string]any)
-	if !ok {
-		return result
-	}
-
-	for key, newVal := range newMap {
-		existingVal, exists := result[key]
-		if !exists {
-			result[key] = newVal
-			continue
-		}
-
-		// Handle nested objects - recurse
-		existingMap, existingIsMap := existingVal.(map[string]any)
-		newValMap, newIsMap := newVal.(map[string]any)
-		if existingIsMap && newIsMap {
-			result[key] = mergeKeepFirst(existingMap, newValMap)
-			continue
-		}
-
-		// For arrays, keep existing
-		// For scalar values, keep existing
-		// This is the "first" behavior
-	}
-
-	return result
-}
-
-// mergeKeepLast merges newObj into result, keeping last values for duplicates.
-func mergeKeepLast(result map[string]any, newObj any) map[string]any {
-	newMap, ok := newObj.(map[string]any)
-	if !ok {
-		return result
-	}
-
-	for key, newVal := range newMap {
-		existingVal, exists := result[key]
-		if !exists {
-			result[key] = newVal
-			continue
-		}
-
-		// Handle nested objects - recurse
-		existingMap, existingIsMap := existingVal.(map[string]any)
-		newValMap, newIsMap := newVal.(map[string]any)
-		if existingIsMap && newIsMap {
-			result[key] = mergeKeepLast(existingMap, newValMap)
-			continue
-		}
-
-		// For non-map values, keep the new one (last wins)
-		result[key] = newVal
-	}
-
-	return result
-}
-
 // charsetToLang converts qs.Charset to lang.Charset.
 func charsetToLang(c Charset) lang.Charset {
 	if c == CharsetISO88591 {
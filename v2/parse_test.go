@@ -10,11 +10,13 @@ package qs
 
 import (
 	"errors"
+	"math"
 	"reflect"
 	"regexp"
 	"strconv"
 	"strings"
 	"testing"
+	"time"
 )
 
 // Helper function to compare results with expected values
@@ -753,6 +755,42 @@ func TestParseComma(t *testing.T) {
 	})
 }
 
+// TestParseCommaRepeatedKeys pins repeated keys under Comma to JS qs:
+// values of a repeated key combine into one flat array, except under a
+// trailing "[]", where each occurrence stays its own array.
+func TestParseCommaRepeatedKeys(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		opts  []ParseOption
+		want  map[string]any
+	}{
+		{"nested key", "a[b]=1,2&a[b]=3,4", nil, map[string]any{"a": map[string]any{"b": []any{"1", "2", "3", "4"}}}},
+		{"deeper key", "a[b][c]=1,2&a[b][c]=3,4", nil, map[string]any{"a": map[string]any{"b": map[string]any{"c": []any{"1", "2", "3", "4"}}}}},
+		{"mixed with plain value", "a[b]=1&a[b]=3,4", nil, map[string]any{"a": map[string]any{"b": []any{"1", "3", "4"}}}},
+		{"brackets", "a[]=1,2&a[]=3,4", nil, map[string]any{"a": []any{[]any{"1", "2"}, []any{"3", "4"}}}},
+		{"first", "a[b]=1,2&a[b]=3,4", []ParseOption{WithParseDuplicates(DuplicateFirst)}, map[string]any{"a": map[string]any{"b": []any{"1", "2"}}}},
+		{"last", "a[b]=1,2&a[b]=3,4", []ParseOption{WithParseDuplicates(DuplicateLast)}, map[string]any{"a": map[string]any{"b": []any{"3", "4"}}}},
+		{"first with distinct keys", "a[0]=1&a[1]=2&a[0]=3", []ParseOption{WithParseDuplicates(DuplicateFirst)}, map[string]any{"a": []any{"1", "2"}}},
+	}
+
+	for _, tt := range tests {
+		for _, delimiter := range []string{"&", ";;"} {
+			t.Run(tt.name+" "+delimiter, func(t *testing.T) {
+				input := strings.ReplaceAll(tt.input, "&", delimiter)
+				opts := append([]ParseOption{WithParseComma(true), WithParseDelimiter(delimiter)}, tt.opts...)
+				got, err := Parse(input, opts...)
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				if !reflect.DeepEqual(got, tt.want) {
+					t.Errorf("got %v, want %v", got, tt.want)
+				}
+			})
+		}
+	}
+}
+
 func TestParseURLEncoding(t *testing.T) {
 	t.Run("decodes URL-encoded values", func(t *testing.T) {
 		result, err := Parse("a=%20b")
@@ -2923,3 +2961,1914 @@ func TestDetailedJSCompat2(t *testing.T) {
 		})
 	}
 }
+
+func TestParseValueTopLevelArray(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		opts     []ParseOption
+		expected any
+	}{
+		{
+			name:     "map without option",
+			input:    "[0]=a&[1]=b",
+			expected: map[string]any{"0": "a", "1": "b"},
+		},
+		{
+			name:     "bracketed indices",
+			input:    "[0]=a&[1]=b",
+			opts:     []ParseOption{WithParseTopLevelArray(true)},
+			expected: []any{"a", "b"},
+		},
+		{
+			name:     "bare indices",
+			input:    "1=b&0=a",
+			opts:     []ParseOption{WithParseTopLevelArray(true)},
+			expected: []any{"a", "b"},
+		},
+		{
+			name:     "nested values",
+			input:    "[0][name]=a&[1][tags][]=x&[1][tags][]=y",
+			opts:     []ParseOption{WithParseTopLevelArray(true)},
+			expected: []any{map[string]any{"name": "a"}, map[string]any{"tags": []any{"x", "y"}}},
+		},
+		{
+			name:     "gaps are compacted",
+			input:    "[0]=a&[5]=b",
+			opts:     []ParseOption{WithParseTopLevelArray(true)},
+			expected: []any{"a", "b"},
+		},
+		{
+			name:     "gaps preserved with AllowSparse",
+			input:    "[0]=a&[2]=b",
+			opts:     []ParseOption{WithParseTopLevelArray(true), WithParseAllowSparse(true)},
+			expected: []any{"a", nil, "b"},
+		},
+		{
+			name:     "mixed keys stay a map",
+			input:    "[0]=a&b=c",
+			opts:     []ParseOption{WithParseTopLevelArray(true)},
+			expected: map[string]any{"0": "a", "b": "c"},
+		},
+		{
+			name:     "non-canonical index stays a map",
+			input:    "[01]=a",
+			opts:     []ParseOption{WithParseTopLevelArray(true)},
+			expected: map[string]any{"01": "a"},
+		},
+		{
+			name:     "index over ArrayLimit stays a map",
+			input:    "[0]=a&[30]=b",
+			opts:     []ParseOption{WithParseTopLevelArray(true)},
+			expected: map[string]any{"0": "a", "30": "b"},
+		},
+		{
+			name:     "empty input stays a map",
+			input:    "",
+			opts:     []ParseOption{WithParseTopLevelArray(true)},
+			expected: map[string]any{},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := ParseValue(tt.input, tt.opts...)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if !reflect.DeepEqual(result, tt.expected) {
+				t.Errorf("ParseValue(%q)\n  got:  %#v\n  want: %#v", tt.input, result, tt.expected)
+			}
+		})
+	}
+
+	t.Run("Parse keeps only populated root indices", func(t *testing.T) {
+		result, err := Parse("[5]=b")
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		assertEqual(t, result, map[string]any{"5": "b"}, "Parse")
+	})
+
+	t.Run("Parse ignores option", func(t *testing.T) {
+		result, err := Parse("[0]=a", WithParseTopLevelArray(true))
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		assertEqual(t, result, map[string]any{"0": "a"}, "Parse")
+	})
+}
+
+func TestParseBraceExpansion(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		opts     []ParseOption
+		expected map[string]any
+	}{
+		{
+			name:     "disabled by default",
+			input:    "a={1,2,3}",
+			expected: map[string]any{"a": "{1,2,3}"},
+		},
+		{
+			name:     "expands brace-wrapped values",
+			input:    "a={1,2,3}",
+			opts:     []ParseOption{WithParseBraceExpansion(true)},
+			expected: map[string]any{"a": []any{"1", "2", "3"}},
+		},
+		{
+			name:     "percent-encoded braces",
+			input:    "a=%7Bx%2Cy,z%7D",
+			opts:     []ParseOption{WithParseBraceExpansion(true)},
+			expected: map[string]any{"a": []any{"x,y", "z"}},
+		},
+		{
+			name:     "plain commas stay literal",
+			input:    "a=1,2",
+			opts:     []ParseOption{WithParseBraceExpansion(true)},
+			expected: map[string]any{"a": "1,2"},
+		},
+		{
+			name:     "braces without comma stay literal",
+			input:    "a={x}",
+			opts:     []ParseOption{WithParseBraceExpansion(true)},
+			expected: map[string]any{"a": "{x}"},
+		},
+		{
+			name:     "unbalanced braces stay literal",
+			input:    "a={1,2",
+			opts:     []ParseOption{WithParseBraceExpansion(true)},
+			expected: map[string]any{"a": "{1,2"},
+		},
+		{
+			name:     "takes precedence over Comma",
+			input:    "a={1,2}&b=3,4",
+			opts:     []ParseOption{WithParseBraceExpansion(true), WithParseComma(true)},
+			expected: map[string]any{"a": []any{"1", "2"}, "b": []any{"3", "4"}},
+		},
+		{
+			name:     "nested key",
+			input:    "a[b]={1,2}",
+			opts:     []ParseOption{WithParseBraceExpansion(true)},
+			expected: map[string]any{"a": map[string]any{"b": []any{"1", "2"}}},
+		},
+		{
+			name:     "regexp delimiter",
+			input:    "a={1,2};b=c",
+			opts:     []ParseOption{WithParseBraceExpansion(true), WithParseDelimiterRegexp(regexp.MustCompile(`;`))},
+			expected: map[string]any{"a": []any{"1", "2"}, "b": "c"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := Parse(tt.input, tt.opts...)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if !reflect.DeepEqual(result, tt.expected) {
+				t.Errorf("Parse(%q)\n  got:  %#v\n  want: %#v", tt.input, result, tt.expected)
+			}
+		})
+	}
+}
+
+func TestParseContainerHook(t *testing.T) {
+	type event struct {
+		path string
+		kind ContainerKind
+	}
+
+	collect := func(events *[]event) ContainerHookFunc {
+		return func(path []string, kind ContainerKind) error {
+			*events = append(*events, event{strings.Join(path, "."), kind})
+			return nil
+		}
+	}
+
+	t.Run("nested maps and slices", func(t *testing.T) {
+		var events []event
+		result, err := Parse("b[]=1&b[]=2&a[x][y]=z", WithParseContainerHook(collect(&events)))
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		want := []event{
+			{"", ContainerMap},
+			{"a", ContainerMap},
+			{"a.x", ContainerMap},
+			{"b", ContainerSlice},
+		}
+		if !reflect.DeepEqual(events, want) {
+			t.Errorf("got %v, want %v", events, want)
+		}
+		if !reflect.DeepEqual(result, map[string]any{
+			"a": map[string]any{"x": map[string]any{"y": "z"}},
+			"b": []any{"1", "2"},
+		}) {
+			t.Errorf("hook changed result: %v", result)
+		}
+	})
+
+	t.Run("slice elements use index", func(t *testing.T) {
+		var events []event
+		_, err := Parse("a[0][b]=c&a[1][b]=d", WithParseContainerHook(collect(&events)))
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		want := []event{
+			{"", ContainerMap},
+			{"a", ContainerSlice},
+			{"a.0", ContainerMap},
+			{"a.1", ContainerMap},
+		}
+		if !reflect.DeepEqual(events, want) {
+			t.Errorf("got %v, want %v", events, want)
+		}
+	})
+
+	t.Run("ParseValue top-level array", func(t *testing.T) {
+		var events []event
+		_, err := ParseValue("[0]=a&[1]=b", WithParseTopLevelArray(true), WithParseContainerHook(collect(&events)))
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		want := []event{{"", ContainerMap}}
+		if !reflect.DeepEqual(events, want) {
+			t.Errorf("got %v, want %v", events, want)
+		}
+	})
+
+	t.Run("not called on error", func(t *testing.T) {
+		called := false
+		_, err := Parse("a[b]=c&d=e", WithParseParameterLimit(1), WithParseThrowOnLimitExceeded(true), WithParseContainerHook(func([]string, ContainerKind) error {
+			called = true
+			return nil
+		}))
+		if err == nil {
+			t.Fatal("expected error")
+		}
+		if called {
+			t.Error("hook called despite parse error")
+		}
+	})
+
+	t.Run("error stops the parse", func(t *testing.T) {
+		errTooManySlices := errors.New("too many slices")
+		count := 0
+		hook := WithParseContainerHook(func(path []string, kind ContainerKind) error {
+			if kind == ContainerSlice {
+				count++
+				if count > 1 {
+					return errTooManySlices
+				}
+			}
+			return nil
+		})
+		for _, parse := range []func(string, ...ParseOption) (map[string]any, error){Parse, ParseHeader} {
+			count = 0
+			result, err := parse("a[]=1&b[]=2&c=3", hook, WithParseDelimiter("&"))
+			if !errors.Is(err, errTooManySlices) || result != nil {
+				t.Errorf("got %v, %v, want %v", result, err, errTooManySlices)
+			}
+		}
+	})
+
+	t.Run("FlatResult reports nested containers", func(t *testing.T) {
+		var events []event
+		_, err := Parse("a[b]=c", WithParseFlatResult(true), WithParseContainerHook(collect(&events)))
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		want := []event{{"", ContainerMap}, {"a", ContainerMap}}
+		if !reflect.DeepEqual(events, want) {
+			t.Errorf("got %v, want %v", events, want)
+		}
+	})
+}
+
+func TestParseTrace(t *testing.T) {
+	type event struct {
+		name   string
+		detail any
+	}
+
+	collect := func(events *[]event) TraceFunc {
+		return func(name string, detail any) {
+			*events = append(*events, event{name, detail})
+		}
+	}
+
+	for _, tt := range []struct {
+		name       string
+		delimiter  string
+		pathsEarly bool
+	}{
+		{"lang parser", "&", true},
+		{"split parser", "&&", false},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			var events []event
+			result, err := Parse("a%5Bb%5D[0]=x"+tt.delimiter+"a[b][]=y",
+				WithParseTrace(collect(&events)), WithParseDelimiter(tt.delimiter))
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if want := map[string]any{"a": map[string]any{"b": []any{"x", "y"}}}; !reflect.DeepEqual(result, want) {
+				t.Errorf("result = %v, want %v", result, want)
+			}
+			first := []event{
+				{TraceSegment, "a%5Bb%5D[0]=x"},
+				{TraceKey, "a[b][0]"},
+			}
+			firstPath := event{TracePath, []string{"a", "[b]", "[0]"}}
+			firstContainers := []event{
+				{TraceContainer, TraceContainerDetail{"[0]", ContainerSlice}},
+				{TraceContainer, TraceContainerDetail{"[b]", ContainerMap}},
+				{TraceContainer, TraceContainerDetail{"a", ContainerMap}},
+			}
+			second := []event{
+				{TraceSegment, "a[b][]=y"},
+				{TraceKey, "a[b][]"},
+			}
+			secondPath := event{TracePath, []string{"a", "[b]", "[]"}}
+			secondContainers := []event{
+				{TraceContainer, TraceContainerDetail{"[]", ContainerSlice}},
+				{TraceContainer, TraceContainerDetail{"[b]", ContainerMap}},
+				{TraceContainer, TraceContainerDetail{"a", ContainerMap}},
+			}
+			var want []event
+			if tt.pathsEarly {
+				// lang.Parse splits keys into paths as it reads them
+				want = append(append(append(first, firstPath), second...), secondPath)
+				want = append(append(want, firstContainers...), secondContainers...)
+			} else {
+				want = append(append(first, second...), firstPath)
+				want = append(append(append(want, firstContainers...), secondPath), secondContainers...)
+			}
+			if !reflect.DeepEqual(events, want) {
+				t.Errorf("events =\n%v\nwant\n%v", events, want)
+			}
+		})
+	}
+
+	t.Run("index over array limit", func(t *testing.T) {
+		var events []event
+		_, err := Parse("a[100]=x", WithParseTrace(collect(&events)), WithParseArrayLimit(20))
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		want := event{TraceContainer, TraceContainerDetail{"[100]", ContainerMap}}
+		found := false
+		for _, e := range events {
+			found = found || e == want
+		}
+		if !found {
+			t.Errorf("events = %v, want %v", events, want)
+		}
+	})
+
+	t.Run("depth truncation", func(t *testing.T) {
+		var events []event
+		_, err := Parse("a[b][c][d]=x", WithParseTrace(collect(&events)), WithParseDepth(1))
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		var got []event
+		for _, e := range events {
+			if e.name == TraceDepth || e.name == TracePath {
+				got = append(got, e)
+			}
+		}
+		want := []event{
+			{TraceDepth, "[c][d]"},
+			{TracePath, []string{"a", "[b]", "[[c][d]]"}},
+		}
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("events = %v, want %v", got, want)
+		}
+	})
+}
+
+func TestParseDelimiters(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		opts  []ParseOption
+		want  map[string]any
+	}{
+		{"ampersand and semicolon", "a=1&b=2;c=3", []ParseOption{WithParseDelimiters([]string{"&", ";"})}, map[string]any{"a": "1", "b": "2", "c": "3"}},
+		{"multi-char delimiter", "a=1&&b=2|c=3", []ParseOption{WithParseDelimiters([]string{"&&", "|"})}, map[string]any{"a": "1", "b": "2", "c": "3"}},
+		{"first listed wins", "a=1&&b=2", []ParseOption{WithParseDelimiters([]string{"&&", "&"})}, map[string]any{"a": "1", "b": "2"}},
+		{"shorter listed first", "a=1&&b=2", []ParseOption{WithParseDelimiters([]string{"&", "&&"})}, map[string]any{"a": "1", "b": "2"}},
+		{"nested keys", "a[b]=1;a[c]=2", []ParseOption{WithParseDelimiters([]string{"&", ";"})}, map[string]any{"a": map[string]any{"b": "1", "c": "2"}}},
+		{"parameter limit", "a=1&b=2;c=3", []ParseOption{WithParseDelimiters([]string{"&", ";"}), WithParseParameterLimit(2)}, map[string]any{"a": "1", "b": "2"}},
+		{"later delimiter option wins", "a=1&b=2;c=3", []ParseOption{WithParseDelimiters([]string{"&", ";"}), WithParseDelimiter(";")}, map[string]any{"a": "1&b=2", "c": "3"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Parse(tt.input, tt.opts...)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got %v, want %v", got, tt.want)
+			}
+		})
+	}
+
+	t.Run("throw on limit exceeded", func(t *testing.T) {
+		_, err := Parse("a=1&b=2;c=3", WithParseDelimiters([]string{"&", ";"}), WithParseParameterLimit(2), WithParseThrowOnLimitExceeded(true))
+		if err != ErrParameterLimitExceeded {
+			t.Errorf("got %v, want %v", err, ErrParameterLimitExceeded)
+		}
+	})
+
+	t.Run("empty delimiter rejected", func(t *testing.T) {
+		_, err := Parse("a=1", WithParseDelimiters([]string{"&", ""}))
+		if err != ErrInvalidDelimiters {
+			t.Errorf("got %v, want %v", err, ErrInvalidDelimiters)
+		}
+	})
+}
+
+func TestParseMaxArrayDepth(t *testing.T) {
+	tests := []struct {
+		name    string
+		input   string
+		opts    []ParseOption
+		want    map[string]any
+		wantErr error
+	}{
+		{
+			name:    "nested indices exceed limit",
+			input:   "a[0][0][0]=b",
+			opts:    []ParseOption{WithParseMaxArrayDepth(2)},
+			wantErr: ErrArrayDepthExceeded,
+		},
+		{
+			name:    "nested brackets exceed limit",
+			input:   "a[][][]=b",
+			opts:    []ParseOption{WithParseMaxArrayDepth(2)},
+			wantErr: ErrArrayDepthExceeded,
+		},
+		{
+			name:  "within limit",
+			input: "a[0][0]=b",
+			opts:  []ParseOption{WithParseMaxArrayDepth(2)},
+			want:  map[string]any{"a": []any{[]any{"b"}}},
+		},
+		{
+			name:  "object nesting of same depth allowed",
+			input: "a[b][c][d]=e",
+			opts:  []ParseOption{WithParseMaxArrayDepth(2)},
+			want:  map[string]any{"a": map[string]any{"b": map[string]any{"c": map[string]any{"d": "e"}}}},
+		},
+		{
+			name:  "mixed nesting counts only arrays",
+			input: "a[0][b][0][c]=d",
+			opts:  []ParseOption{WithParseMaxArrayDepth(2)},
+			want:  map[string]any{"a": []any{map[string]any{"b": []any{map[string]any{"c": "d"}}}}},
+		},
+		{
+			name:  "indices over array limit are object keys",
+			input: "a[100][100][100]=b",
+			opts:  []ParseOption{WithParseMaxArrayDepth(1)},
+			want:  map[string]any{"a": map[string]any{"100": map[string]any{"100": map[string]any{"100": "b"}}}},
+		},
+		{
+			name:  "disabled by default",
+			input: "a[0][0][0]=b",
+			want:  map[string]any{"a": []any{[]any{[]any{"b"}}}},
+		},
+		{
+			name:  "ignored when arrays disabled",
+			input: "a[0][0]=b",
+			opts:  []ParseOption{WithParseMaxArrayDepth(1), WithParseArrays(false)},
+			want:  map[string]any{"a": map[string]any{"0": map[string]any{"0": "b"}}},
+		},
+		{
+			name:    "regexp delimiter path",
+			input:   "a[0][0]=b;c=d",
+			opts:    []ParseOption{WithParseMaxArrayDepth(1), WithParseDelimiterRegexp(regexp.MustCompile(`;`))},
+			wantErr: ErrArrayDepthExceeded,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Parse(tt.input, tt.opts...)
+			if tt.wantErr != nil {
+				if err != tt.wantErr {
+					t.Fatalf("got error %v, want %v", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseEncodedDelimiterInValue(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		opts  []ParseOption
+		want  map[string]any
+	}{
+		{"semicolon delimiter", "a=1%3B2;b=3", []ParseOption{WithParseDelimiter(";")}, map[string]any{"a": "1;2", "b": "3"}},
+		{"semicolon in key", "a%3Bx=1;b=2", []ParseOption{WithParseDelimiter(";")}, map[string]any{"a;x": "1", "b": "2"}},
+		{"regexp delimiter", "a=1%3B2;b=3,c=4", []ParseOption{WithParseDelimiterRegexp(regexp.MustCompile(`[;,]`))}, map[string]any{"a": "1;2", "b": "3", "c": "4"}},
+		{"multi-char delimiter", "a=1%3B%3B2;;b=3", []ParseOption{WithParseDelimiter(";;")}, map[string]any{"a": "1;;2", "b": "3"}},
+		{"multiple delimiters", "a=1%3B2&b=3%262;c=4", []ParseOption{WithParseDelimiters([]string{"&", ";"})}, map[string]any{"a": "1;2", "b": "3&2", "c": "4"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Parse(tt.input, tt.opts...)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseDelimiterEscape(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		opts  []ParseOption
+		want  map[string]any
+	}{
+		{"escaped semicolon", `a=1\;2;b=3`, []ParseOption{WithParseDelimiter(";"), WithParseDelimiterEscape('\\')}, map[string]any{"a": "1;2", "b": "3"}},
+		{"escaped default delimiter", `a=x\&y&b=z`, []ParseOption{WithParseDelimiterEscape('\\')}, map[string]any{"a": "x&y", "b": "z"}},
+		{"doubled escape", `a=1\\;b=2`, []ParseOption{WithParseDelimiter(";"), WithParseDelimiterEscape('\\')}, map[string]any{"a": `1\`, "b": "2"}},
+		{"escape before other char kept", `a=1\n;b=2`, []ParseOption{WithParseDelimiter(";"), WithParseDelimiterEscape('\\')}, map[string]any{"a": `1\n`, "b": "2"}},
+		{"escaped in key", `a\;b=1;c=2`, []ParseOption{WithParseDelimiter(";"), WithParseDelimiterEscape('\\')}, map[string]any{"a;b": "1", "c": "2"}},
+		{"multiple delimiters", `a=1\;2&b=3;c=4`, []ParseOption{WithParseDelimiters([]string{"&", ";"}), WithParseDelimiterEscape('\\')}, map[string]any{"a": "1;2", "b": "3", "c": "4"}},
+		{"regexp delimiter", `a=1\,2;b=3`, []ParseOption{WithParseDelimiterRegexp(regexp.MustCompile(`[;,]`)), WithParseDelimiterEscape('\\')}, map[string]any{"a": "1,2", "b": "3"}},
+		{"non-ascii escape rune", `a=1¦;2;b=3`, []ParseOption{WithParseDelimiter(";"), WithParseDelimiterEscape('¦')}, map[string]any{"a": "1;2", "b": "3"}},
+		{"disabled by default", `a=1\;2;b=3`, []ParseOption{WithParseDelimiter(";")}, map[string]any{"a": `1\`, "2": "", "b": "3"}},
+		{"parameter limit", `a=1\;2;b=3;c=4`, []ParseOption{WithParseDelimiter(";"), WithParseDelimiterEscape('\\'), WithParseParameterLimit(2)}, map[string]any{"a": "1;2", "b": "3"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Parse(tt.input, tt.opts...)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseSentinelScanLimit(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		opts  []ParseOption
+		want  map[string]any
+	}{
+		{
+			name:  "sentinel within limit",
+			input: "a=1&utf8=%26%2310003%3B&b=%F8",
+			opts:  []ParseOption{WithParseSentinelScanLimit(2)},
+			want:  map[string]any{"a": "1", "b": "ø"},
+		},
+		{
+			name:  "sentinel beyond limit is a regular param",
+			input: "a=1&b=2&utf8=%26%2310003%3B&c=%C3%B8",
+			opts:  []ParseOption{WithParseSentinelScanLimit(2)},
+			want:  map[string]any{"a": "1", "b": "2", "utf8": "&#10003;", "c": "ø"},
+		},
+		{
+			name:  "no limit by default",
+			input: "a=1&b=2&utf8=%26%2310003%3B&c=%F8",
+			want:  map[string]any{"a": "1", "b": "2", "c": "ø"},
+		},
+		{
+			name:  "regexp delimiter path",
+			input: "a=1;b=2;utf8=%26%2310003%3B;c=%C3%B8",
+			opts:  []ParseOption{WithParseSentinelScanLimit(2), WithParseDelimiterRegexp(regexp.MustCompile(`;`))},
+			want:  map[string]any{"a": "1", "b": "2", "utf8": "&#10003;", "c": "ø"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			opts := append([]ParseOption{WithParseCharsetSentinel(true)}, tt.opts...)
+			got, err := Parse(tt.input, opts...)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseStripQuotes(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		opts  []ParseOption
+		want  map[string]any
+	}{
+		{"double and single quotes", `a="b c"&d='e'`, nil, map[string]any{"a": "b c", "d": "e"}},
+		{"after percent-decoding", "a=%22b%20c%22&d=%27e%27", nil, map[string]any{"a": "b c", "d": "e"}},
+		{"one layer only", `a=""b""`, nil, map[string]any{"a": `"b"`}},
+		{"unmatched quotes kept", `a="b&c=d'&e="f'`, nil, map[string]any{"a": `"b`, "c": `d'`, "e": `"f'`}},
+		{"inner quotes kept", `a=it's&b=say "hi" now`, nil, map[string]any{"a": "it's", "b": `say "hi" now`}},
+		{"single quote char kept", `a="`, nil, map[string]any{"a": `"`}},
+		{"empty quoted value", `a=""`, nil, map[string]any{"a": ""}},
+		{"nested keys", `a[b]="c"&a[d][e]='f'`, nil, map[string]any{"a": map[string]any{"b": "c", "d": map[string]any{"e": "f"}}}},
+		{"array values", `a[]="x"&a[]='y'`, nil, map[string]any{"a": []any{"x", "y"}}},
+		{"comma values", `a="x","y"`, []ParseOption{WithParseComma(true)}, map[string]any{"a": []any{"x", "y"}}},
+		{"regexp delimiter", `a="b";c='d'`, []ParseOption{WithParseDelimiterRegexp(regexp.MustCompile(`;`))}, map[string]any{"a": "b", "c": "d"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			opts := append([]ParseOption{WithParseStripQuotes(true)}, tt.opts...)
+			got, err := Parse(tt.input, opts...)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got %v, want %v", got, tt.want)
+			}
+		})
+	}
+
+	t.Run("off by default", func(t *testing.T) {
+		got, err := Parse(`a="b"`)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if got["a"] != `"b"` {
+			t.Errorf("got %v, want %q", got["a"], `"b"`)
+		}
+	})
+}
+
+func TestParseDuration(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		opts  []ParseOption
+		want  map[string]any
+	}{
+		{"single duration", "timeout=5s", nil, map[string]any{"timeout": 5 * time.Second}},
+		{"compound duration", "t=1h30m", nil, map[string]any{"t": 90 * time.Minute}},
+		{"comma list", "timeouts=5s,1m,30s", []ParseOption{WithParseComma(true)}, map[string]any{"timeouts": []any{5 * time.Second, time.Minute, 30 * time.Second}}},
+		{"mixed valid and invalid", "t=5s,abc,10,1m", []ParseOption{WithParseComma(true)}, map[string]any{"t": []any{5 * time.Second, "abc", "10", time.Minute}}},
+		{"comma off keeps list string", "t=5s,1m", nil, map[string]any{"t": "5s,1m"}},
+		{"bare numbers stay strings", "a=0&b=10", nil, map[string]any{"a": "0", "b": "10"}},
+		{"repeated keys", "t=1s&t=2s", nil, map[string]any{"t": []any{time.Second, 2 * time.Second}}},
+		{"nested", "cfg[retry]=250ms", nil, map[string]any{"cfg": map[string]any{"retry": 250 * time.Millisecond}}},
+		{"regexp delimiter", "t=5s,x;u=2h", []ParseOption{WithParseComma(true), WithParseDelimiterRegexp(regexp.MustCompile(`;`))}, map[string]any{"t": []any{5 * time.Second, "x"}, "u": 2 * time.Hour}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			opts := append([]ParseOption{WithParseDuration(true)}, tt.opts...)
+			got, err := Parse(tt.input, opts...)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseNumbers(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		opts  []ParseOption
+		want  map[string]any
+	}{
+		{"integers", "page=2&n=-15&z=0", nil, map[string]any{"page": int64(2), "n": int64(-15), "z": int64(0)}},
+		{"floats", "a=0.5&b=-12.25", nil, map[string]any{"a": 0.5, "b": -12.25}},
+		{"ambiguous stay strings", "a=007&b=%2B1&c=-0&d=1.50&e=1e3&f=0x1f&g=.5&h=5.&i=1_000&j=NaN", nil,
+			map[string]any{"a": "007", "b": "+1", "c": "-0", "d": "1.50", "e": "1e3", "f": "0x1f", "g": ".5", "h": "5.", "i": "1_000", "j": "NaN"}},
+		{"int64 range", "a=9223372036854775807&b=9223372036854775808", nil,
+			map[string]any{"a": int64(math.MaxInt64), "b": "9223372036854775808"}},
+		{"overflowing float", "a=1e999&b=1.0e999", nil, map[string]any{"a": "1e999", "b": "1.0e999"}},
+		{"keys stay strings", "1=2&2.5=3", nil, map[string]any{"1": int64(2), "2.5": int64(3)}},
+		{"text and empty", "a=abc&b=&c=1+2", nil, map[string]any{"a": "abc", "b": "", "c": "1 2"}},
+		{"comma list", "ids=1,x,2.5", []ParseOption{WithParseComma(true)}, map[string]any{"ids": []any{int64(1), "x", 2.5}}},
+		{"repeated and nested", "a=1&a=2&o[n]=3", nil, map[string]any{"a": []any{int64(1), int64(2)}, "o": map[string]any{"n": int64(3)}}},
+		{"regexp delimiter", "a=1;b=x", []ParseOption{WithParseDelimiterRegexp(regexp.MustCompile(`;`))}, map[string]any{"a": int64(1), "b": "x"}},
+		{"strict null", "a&b=1", []ParseOption{WithParseStrictNullHandling(true)}, map[string]any{"a": nil, "b": int64(1)}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			opts := append([]ParseOption{WithParseNumbers(true)}, tt.opts...)
+			got, err := Parse(tt.input, opts...)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseBooleans(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		opts  []ParseOption
+		want  map[string]any
+	}{
+		{"literals", "a=true&b=false", nil, map[string]any{"a": true, "b": false}},
+		{"other spellings stay strings", "a=True&b=1&c=0&d=yes&e=FALSE&f=", nil,
+			map[string]any{"a": "True", "b": "1", "c": "0", "d": "yes", "e": "FALSE", "f": ""}},
+		{"comma list", "a=true,false,x", []ParseOption{WithParseComma(true)}, map[string]any{"a": []any{true, false, "x"}}},
+		{"bracket comma list", "a[]=true,false", []ParseOption{WithParseComma(true)}, map[string]any{"a": []any{[]any{true, false}}}},
+		{"nested and repeated", "f[x]=true&f[y]=false&r=true&r=false", nil,
+			map[string]any{"f": map[string]any{"x": true, "y": false}, "r": []any{true, false}}},
+		{"encoded", "a=%74rue", nil, map[string]any{"a": true}},
+		{"regexp delimiter", "a=true;b=false", []ParseOption{WithParseDelimiterRegexp(regexp.MustCompile(`;`))}, map[string]any{"a": true, "b": false}},
+		{"with numbers", "a=1&b=true", []ParseOption{WithParseNumbers(true)}, map[string]any{"a": int64(1), "b": true}},
+		{"keys stay strings", "true=false&page=2", []ParseOption{WithParseNumbers(true)}, map[string]any{"true": false, "page": int64(2)}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			opts := append([]ParseOption{WithParseBooleans(true)}, tt.opts...)
+			got, err := Parse(tt.input, opts...)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseKeySplitter(t *testing.T) {
+	slash := func(key string) []string { return strings.Split(key, "/") }
+
+	tests := []struct {
+		name    string
+		input   string
+		opts    []ParseOption
+		want    map[string]any
+		wantErr error
+	}{
+		{"slash paths", "a/b=1&a/c=2", nil, map[string]any{"a": map[string]any{"b": "1", "c": "2"}}, nil},
+		{"indices build arrays", "a/0=x&a/1=y", nil, map[string]any{"a": []any{"x", "y"}}, nil},
+		{"empty segment appends", "a/=x&a/=y", nil, map[string]any{"a": []any{"x", "y"}}, nil},
+		{"brackets are literal", "a[b]/c=1", nil, map[string]any{"a[b]": map[string]any{"c": "1"}}, nil},
+		{"decoded before splitting", "a%2Fb=1", nil, map[string]any{"a": map[string]any{"b": "1"}}, nil},
+		{"index over array limit", "a/5=x", []ParseOption{WithParseArrayLimit(2)}, map[string]any{"a": map[string]any{"5": "x"}}, nil},
+		{"depth limit keeps remainder", "a/b/c/d=1", []ParseOption{WithParseDepth(1)}, map[string]any{"a": map[string]any{"b": map[string]any{"[c][d]": "1"}}}, nil},
+		{"strict depth", "a/b/c=1", []ParseOption{WithParseDepth(1), WithParseStrictDepth(true)}, nil, ErrDepthLimitExceeded},
+		{"top-level index is a key", "0/a=1", nil, map[string]any{"0": map[string]any{"a": "1"}}, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			opts := append([]ParseOption{WithParseKeySplitter(slash)}, tt.opts...)
+			got, err := Parse(tt.input, opts...)
+			if tt.wantErr != nil {
+				if err != tt.wantErr {
+					t.Fatalf("got error %v, want %v", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got %v, want %v", got, tt.want)
+			}
+		})
+	}
+
+	t.Run("empty result skips param", func(t *testing.T) {
+		got, err := Parse("skip=1&keep=2", WithParseKeySplitter(func(key string) []string {
+			if key == "skip" {
+				return nil
+			}
+			return []string{key}
+		}))
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if want := map[string]any{"keep": "2"}; !reflect.DeepEqual(got, want) {
+			t.Errorf("got %v, want %v", got, want)
+		}
+	})
+}
+
+func TestParseRejectControlChars(t *testing.T) {
+	tests := []struct {
+		name    string
+		input   string
+		opts    []ParseOption
+		want    map[string]any
+		wantErr string
+	}{
+		{"lenient by default", "a=b\r\nc", nil, map[string]any{"a": "b\r\nc"}, ""},
+		{"rejects LF", "a=b\nc", []ParseOption{WithParseRejectControlChars(true)}, nil, "control character in input at offset 3: 0x0A"},
+		{"rejects NUL in key", "a\x00=b", []ParseOption{WithParseRejectControlChars(true)}, nil, "control character in input at offset 1: 0x00"},
+		{"encoded control chars allowed", "a=b%0Ac", []ParseOption{WithParseRejectControlChars(true)}, map[string]any{"a": "b\nc"}, ""},
+		{"allowed tab", "a=b\tc", []ParseOption{WithParseRejectControlChars(true), WithParseAllowedControlChars("\t")}, map[string]any{"a": "b\tc"}, ""},
+		{"allowed list is exact", "a=b\t\rc", []ParseOption{WithParseRejectControlChars(true), WithParseAllowedControlChars("\t")}, nil, "control character in input at offset 4: 0x0D"},
+		{"regexp delimiter path", "a=b;c=\x1f", []ParseOption{WithParseRejectControlChars(true), WithParseDelimiter(";")}, nil, "control character in input at offset 6: 0x1F"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Parse(tt.input, tt.opts...)
+			if tt.wantErr != "" {
+				if !errors.Is(err, ErrControlCharacter) || err.Error() != tt.wantErr {
+					t.Fatalf("got error %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseDecoders(t *testing.T) {
+	trim := func(str string, charset Charset, kind string) (string, error) {
+		return strings.TrimSpace(str), nil
+	}
+	lowerValues := func(str string, charset Charset, kind string) (string, error) {
+		if kind == "value" {
+			return strings.ToLower(str), nil
+		}
+		return str, nil
+	}
+	errBad := errors.New("bad value")
+	reject := func(str string, charset Charset, kind string) (string, error) {
+		if str == "bad" {
+			return "", errBad
+		}
+		return str, nil
+	}
+
+	tests := []struct {
+		name    string
+		input   string
+		opts    []ParseOption
+		want    map[string]any
+		wantErr error
+	}{
+		{"default then trim then lower", "+Key+=+Hello%20World+", []ParseOption{WithParseDecoders(DefaultDecoder, trim, lowerValues)}, map[string]any{"Key": "hello world"}, nil},
+		{"order matters", "a=%20B%20", []ParseOption{WithParseDecoders(trim, DefaultDecoder)}, map[string]any{"a": " B "}, nil},
+		{"without default decoder", "a=b%20c", []ParseOption{WithParseDecoders(trim)}, map[string]any{"a": "b%20c"}, nil},
+		{"comma parts", "a=X,Y", []ParseOption{WithParseComma(true), WithParseDecoders(DefaultDecoder, lowerValues)}, map[string]any{"a": []any{"x", "y"}}, nil},
+		{"split parser", "a=X;;b=Y", []ParseOption{WithParseDelimiter(";;"), WithParseDecoders(DefaultDecoder, lowerValues)}, map[string]any{"a": "x", "b": "y"}, nil},
+		{"empty pipeline uses default", "a=b%20c", []ParseOption{WithParseDecoders()}, map[string]any{"a": "b c"}, nil},
+		{"error propagates", "a=ok&b=bad", []ParseOption{WithParseDecoders(DefaultDecoder, reject, lowerValues)}, nil, errBad},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Parse(tt.input, tt.opts...)
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Fatalf("got error %v, want %v", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseArrayValueDelimiter(t *testing.T) {
+	want := map[string]any{"ids": []any{"1", "2", "3"}}
+
+	tests := []struct {
+		name      string
+		input     string
+		delimiter string
+	}{
+		{"form", "ids=1,2,3", ","},
+		{"spaceDelimited", "ids=1%202%203", "%20"},
+		{"pipeDelimited", "ids=1|2|3", "|"},
+		{"multi-char", "ids=1::2::3", "::"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Parse(tt.input, WithParseArrayValueDelimiter(tt.delimiter))
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if !reflect.DeepEqual(got, want) {
+				t.Errorf("got %v, want %v", got, want)
+			}
+		})
+	}
+
+	// Encoded separators stay part of the element
+	got, err := Parse("ids=1%7C2|3", WithParseArrayValueDelimiter("|"))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !reflect.DeepEqual(got, map[string]any{"ids": []any{"1|2", "3"}}) {
+		t.Errorf("got %v", got)
+	}
+}
+
+func TestParseSeparatorByKey(t *testing.T) {
+	separators := WithParseSeparatorByKey(map[string]string{"ids": ",", "tags": "|", "f[q]": "%20"})
+
+	tests := []struct {
+		name  string
+		input string
+		opts  []ParseOption
+		want  map[string]any
+	}{
+		{"listed keys", "ids=1,2&tags=a|b,c&f[q]=x%20y", nil, map[string]any{
+			"ids":  []any{"1", "2"},
+			"tags": []any{"a", "b,c"},
+			"f":    map[string]any{"q": []any{"x", "y"}},
+		}},
+		{"unlisted keys not split", "ids=1,2&other=a,b|c", nil, map[string]any{"ids": []any{"1", "2"}, "other": "a,b|c"}},
+		{"unlisted keys use comma default", "tags=a|b&other=a;b", []ParseOption{WithParseArrayValueDelimiter(";")}, map[string]any{"tags": []any{"a", "b"}, "other": []any{"a", "b"}}},
+		{"repeated keys combine", "tags=a|b&tags=c", nil, map[string]any{"tags": []any{"a", "b", "c"}}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Parse(tt.input, append([]ParseOption{separators}, tt.opts...)...)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got %v, want %v", got, tt.want)
+			}
+		})
+	}
+
+	if _, err := Parse("a=1", WithParseSeparatorByKey(map[string]string{"a": ""})); !errors.Is(err, ErrInvalidSeparatorByKey) {
+		t.Errorf("expected ErrInvalidSeparatorByKey, got %v", err)
+	}
+}
+
+func TestParseDepthOverflowMode(t *testing.T) {
+	splitter := WithParseKeySplitter(func(key string) []string { return strings.Split(key, "/") })
+
+	tests := []struct {
+		name  string
+		input string
+		opts  []ParseOption
+		want  map[string]any
+	}{
+		{"literal default", "a[b][c][d]=v", []ParseOption{WithParseDepth(1)}, map[string]any{"a": map[string]any{"b": map[string]any{"[c][d]": "v"}}}},
+		{"literal explicit", "a[b][c]=v", []ParseOption{WithParseDepth(1), WithParseDepthOverflowMode(DepthOverflowLiteral)}, map[string]any{"a": map[string]any{"b": map[string]any{"[c]": "v"}}}},
+		{"dot", "a[b][c][d]=v", []ParseOption{WithParseDepth(1), WithParseDepthOverflowMode(DepthOverflowDot)}, map[string]any{"a": map[string]any{"b": map[string]any{"c.d": "v"}}}},
+		{"dot from dot notation", "a.b.c.d=v", []ParseOption{WithParseDepth(1), WithParseAllowDots(true), WithParseDepthOverflowMode(DepthOverflowDot)}, map[string]any{"a": map[string]any{"b": map[string]any{"c.d": "v"}}}},
+		{"dot trailing text", "a[b][c]x=v", []ParseOption{WithParseDepth(1), WithParseDepthOverflowMode(DepthOverflowDot)}, map[string]any{"a": map[string]any{"b": map[string]any{"cx": "v"}}}},
+		{"drop", "a[b][c][d]=v&a[e]=w", []ParseOption{WithParseDepth(1), WithParseDepthOverflowMode(DepthOverflowDrop)}, map[string]any{"a": map[string]any{"b": "v", "e": "w"}}},
+		{"drop combines", "a[b][c]=1&a[b][d]=2", []ParseOption{WithParseDepth(1), WithParseDepthOverflowMode(DepthOverflowDrop)}, map[string]any{"a": map[string]any{"b": []any{"1", "2"}}}},
+		{"within depth unaffected", "a[b]=v", []ParseOption{WithParseDepth(1), WithParseDepthOverflowMode(DepthOverflowDrop)}, map[string]any{"a": map[string]any{"b": "v"}}},
+		{"split parser dot", "a[b][c][d]=v", []ParseOption{WithParseDepth(1), WithParseDelimiter(";;"), WithParseDepthOverflowMode(DepthOverflowDot)}, map[string]any{"a": map[string]any{"b": map[string]any{"c.d": "v"}}}},
+		{"split parser drop", "a[b][c]=v", []ParseOption{WithParseDepth(1), WithParseDelimiter(";;"), WithParseDepthOverflowMode(DepthOverflowDrop)}, map[string]any{"a": map[string]any{"b": "v"}}},
+		{"key splitter dot", "a/b/c/d=v", []ParseOption{WithParseDepth(1), splitter, WithParseDepthOverflowMode(DepthOverflowDot)}, map[string]any{"a": map[string]any{"b": map[string]any{"c.d": "v"}}}},
+		{"key splitter drop", "a/b/c=v", []ParseOption{WithParseDepth(1), splitter, WithParseDepthOverflowMode(DepthOverflowDrop)}, map[string]any{"a": map[string]any{"b": "v"}}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Parse(tt.input, tt.opts...)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got %v, want %v", got, tt.want)
+			}
+		})
+	}
+
+	if _, err := Parse("a=b", WithParseDepthOverflowMode("bogus")); !errors.Is(err, ErrInvalidDepthOverflow) {
+		t.Errorf("expected ErrInvalidDepthOverflow, got %v", err)
+	}
+	if _, err := Parse("a[b][c]=v", WithParseDepth(1), WithParseStrictDepth(true), WithParseDepthOverflowMode(DepthOverflowDrop)); !errors.Is(err, ErrDepthLimitExceeded) {
+		t.Errorf("expected ErrDepthLimitExceeded, got %v", err)
+	}
+}
+
+func TestParseRejectDuplicates(t *testing.T) {
+	split := WithParseDelimiter(";;")
+
+	rejected := []struct {
+		name  string
+		input string
+		opts  []ParseOption
+		key   string
+	}{
+		{"top-level", "a=1&b=2&a=3", nil, "a"},
+		{"nested path", "a[b]=1&a[b]=2", nil, "a[b]"},
+		{"decoded spelling", "a=1&%61=2", nil, "a"},
+		{"split parser", "a=1;;a=2", []ParseOption{split}, "a"},
+		{"split parser nested", "a[b]=1;;a%5Bb%5D=2", []ParseOption{split}, "a[b]"},
+	}
+	for _, tt := range rejected {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Parse(tt.input, append(tt.opts, WithParseRejectDuplicates(true))...)
+			if !errors.Is(err, ErrDuplicateKey) {
+				t.Fatalf("expected ErrDuplicateKey, got %v", err)
+			}
+			if !strings.Contains(err.Error(), strconv.Quote(tt.key)) {
+				t.Errorf("error %q should name key %q", err, tt.key)
+			}
+		})
+	}
+
+	accepted := []struct {
+		name  string
+		input string
+		opts  []ParseOption
+		want  map[string]any
+	}{
+		{"distinct nested paths", "a[b]=1&a[c]=2", nil, map[string]any{"a": map[string]any{"b": "1", "c": "2"}}},
+		{"array push", "a[]=1&a[]=2", nil, map[string]any{"a": []any{"1", "2"}}},
+		{"split parser array push", "a[]=1;;a[]=2", []ParseOption{split}, map[string]any{"a": []any{"1", "2"}}},
+		{"empty parts", "a=1&&b=2", nil, map[string]any{"a": "1", "b": "2"}},
+	}
+	for _, tt := range accepted {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Parse(tt.input, append(tt.opts, WithParseRejectDuplicates(true))...)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseLevelDelimiters(t *testing.T) {
+	levels := WithParseLevelDelimiters(".", "/")
+
+	tests := []struct {
+		name  string
+		input string
+		opts  []ParseOption
+		want  map[string]any
+	}{
+		{"per level", "a.b/c/d=v", []ParseOption{levels}, map[string]any{"a": map[string]any{"b": map[string]any{"c": map[string]any{"d": "v"}}}}},
+		{"three levels", "a.b/c:d=v", []ParseOption{WithParseLevelDelimiters(".", "/", ":")}, map[string]any{"a": map[string]any{"b": map[string]any{"c": map[string]any{"d": "v"}}}}},
+		{"wrong level delimiter stays literal", "a/b.c=v", []ParseOption{levels}, map[string]any{"a/b": map[string]any{"c": "v"}}},
+		{"indices", "a.b/0=x&a.b/1=y", []ParseOption{levels}, map[string]any{"a": map[string]any{"b": []any{"x", "y"}}}},
+		{"empty segment appends", "a.b/=x&a.b/=y", []ParseOption{levels}, map[string]any{"a": map[string]any{"b": []any{"x", "y"}}}},
+		{"brackets kept", "a.b[c]=v", []ParseOption{levels}, map[string]any{"a": map[string]any{"b": map[string]any{"c": "v"}}}},
+		{"encoded delimiters decoded first", "a.b%2Fc=v", []ParseOption{levels}, map[string]any{"a": map[string]any{"b": map[string]any{"c": "v"}}}},
+		{"depth applies", "a.b/c/d=v", []ParseOption{levels, WithParseDepth(1)}, map[string]any{"a": map[string]any{"b": map[string]any{"[c][d]": "v"}}}},
+		{"no delimiter", "a=v", []ParseOption{levels}, map[string]any{"a": "v"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Parse(tt.input, tt.opts...)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got %v, want %v", got, tt.want)
+			}
+		})
+	}
+
+	// Round-trips with the Stringify option
+	value := map[string]any{"a": map[string]any{"b": []any{"x", map[string]any{"c": "y"}}}}
+	str, err := Stringify(value, WithStringifyLevelDelimiters(".", "/"))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	got, err := Parse(str, levels)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !reflect.DeepEqual(got, value) {
+		t.Errorf("round trip of %q: got %v, want %v", str, got, value)
+	}
+
+	if _, err := Parse("a=b", WithParseLevelDelimiters("")); !errors.Is(err, ErrInvalidLevelDelimiters) {
+		t.Errorf("expected ErrInvalidLevelDelimiters, got %v", err)
+	}
+}
+
+func TestParseMaxDistinctKeys(t *testing.T) {
+	var distinct, repeated []string
+	for i := 0; i < 50; i++ {
+		distinct = append(distinct, "k"+strconv.Itoa(i)+"=v")
+		repeated = append(repeated, "k"+strconv.Itoa(i%3)+"=v")
+	}
+
+	for name, delimiter := range map[string]string{"lang parser": "&", "split parser": ";;"} {
+		t.Run(name, func(t *testing.T) {
+			opts := []ParseOption{WithParseDelimiter(delimiter), WithParseMaxDistinctKeys(10)}
+
+			if _, err := Parse(strings.Join(distinct, delimiter), opts...); !errors.Is(err, ErrTooManyKeys) {
+				t.Errorf("distinct keys: expected ErrTooManyKeys, got %v", err)
+			}
+
+			got, err := Parse(strings.Join(repeated, delimiter), opts...)
+			if err != nil {
+				t.Fatalf("repeated keys: unexpected error: %v", err)
+			}
+			if len(got) != 3 {
+				t.Errorf("repeated keys: got %d keys, want 3", len(got))
+			}
+
+			// Nested keys count once per top-level key
+			if _, err := Parse(strings.Join([]string{"a[x]=1", "a[y]=2", "a[z]=3"}, delimiter), WithParseDelimiter(delimiter), WithParseMaxDistinctKeys(1)); err != nil {
+				t.Errorf("nested keys: unexpected error: %v", err)
+			}
+		})
+	}
+
+	if _, err := Parse(strings.Join(distinct, "&")); err != nil {
+		t.Errorf("disabled by default: unexpected error: %v", err)
+	}
+}
+
+func TestParseMaxArrayIndex(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		opts  []ParseOption
+		want  any
+	}{
+		{"beyond cap becomes object", "a[1000000000]=b", []ParseOption{WithParseArrayLimit(math.MaxInt32)}, map[string]any{"a": map[string]any{"1000000000": "b"}}},
+		{"within cap stays array", "a[100]=b", []ParseOption{WithParseArrayLimit(math.MaxInt32)}, map[string]any{"a": []any{"b"}}},
+		{"caps per-key limit", "a[2000]=b", []ParseOption{WithParseArrayLimitFor(map[string]int{"a": 5000})}, map[string]any{"a": map[string]any{"2000": "b"}}},
+		{"lower array limit still applies", "a[50]=b", nil, map[string]any{"a": map[string]any{"50": "b"}}},
+		{"split parser", "a[1000000000]=b;;c[1]=d", []ParseOption{WithParseArrayLimit(math.MaxInt32), WithParseDelimiter(";;")}, map[string]any{
+			"a": map[string]any{"1000000000": "b"},
+			"c": []any{"d"},
+		}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Parse(tt.input, append(tt.opts, WithParseMaxArrayIndex(1000))...)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got %v, want %v", got, tt.want)
+			}
+		})
+	}
+
+	got, err := ParseValue("[5000]=a", WithParseTopLevelArray(true), WithParseArrayLimit(math.MaxInt32), WithParseMaxArrayIndex(1000))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if want := map[string]any{"5000": "a"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+}
+
+func TestParseKeyRegexp(t *testing.T) {
+	re := regexp.MustCompile(`^utm_`)
+	tests := []struct {
+		name  string
+		input string
+		opts  []ParseOption
+		want  map[string]any
+	}{
+		{"top-level", "utm_source=mail&page=2&utm_medium=x&utm_medium=y", nil,
+			map[string]any{"utm_source": "mail", "utm_medium": []any{"x", "y"}}},
+		{"nested", "utm_tags[]=a&filter[utm_x]=1&utm_meta[k]=v", nil,
+			map[string]any{"utm_tags": []any{"a"}, "utm_meta": map[string]any{"k": "v"}}},
+		{"dots", "utm_a.b=1&x.utm_c=2", []ParseOption{WithParseAllowDots(true)},
+			map[string]any{"utm_a": map[string]any{"b": "1"}}},
+		{"decoded", "utm%5Fsource=mail&other=1", nil, map[string]any{"utm_source": "mail"}},
+		{"none match", "a=1&b=2", nil, map[string]any{}},
+	}
+
+	for _, tt := range tests {
+		for _, delimiter := range []string{"&", ";;"} {
+			t.Run(tt.name+" "+delimiter, func(t *testing.T) {
+				input := strings.ReplaceAll(tt.input, "&", delimiter)
+				opts := append([]ParseOption{WithParseKeyRegexp(re), WithParseDelimiter(delimiter)}, tt.opts...)
+				got, err := Parse(input, opts...)
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				if !reflect.DeepEqual(got, tt.want) {
+					t.Errorf("got %v, want %v", got, tt.want)
+				}
+			})
+		}
+	}
+
+	t.Run("reject duplicates", func(t *testing.T) {
+		_, err := Parse("page=1&page=2", WithParseKeyRegexp(re), WithParseRejectDuplicates(true))
+		if !errors.Is(err, ErrDuplicateKey) {
+			t.Errorf("got %v, want ErrDuplicateKey", err)
+		}
+	})
+}
+
+func TestParseNestedEmptyBrackets(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		opts  []ParseOption
+		want  map[NestedEmptyBracketsMode]any
+	}{
+		{"repeated", "a[][]=b&a[][]=c", nil, map[NestedEmptyBracketsMode]any{
+			NestedEmptyBracketsFlatten:     []any{"b", "c"},
+			NestedEmptyBracketsAppendInner: []any{[]any{"b", "c"}},
+			NestedEmptyBracketsNewInner:    []any{[]any{"b"}, []any{"c"}},
+		}},
+		{"single", "a[][]=b", nil, map[NestedEmptyBracketsMode]any{
+			NestedEmptyBracketsFlatten:     []any{"b"},
+			NestedEmptyBracketsAppendInner: []any{[]any{"b"}},
+			NestedEmptyBracketsNewInner:    []any{[]any{"b"}},
+		}},
+		{"three brackets", "a[][][]=b&a[][][]=c", nil, map[NestedEmptyBracketsMode]any{
+			NestedEmptyBracketsFlatten:     []any{"b", "c"},
+			NestedEmptyBracketsAppendInner: []any{[]any{[]any{"b", "c"}}},
+			NestedEmptyBracketsNewInner:    []any{[]any{[]any{"b"}}, []any{[]any{"c"}}},
+		}},
+		{"comma", "a[][]=b,c&a[][]=d", []ParseOption{WithParseComma(true)}, map[NestedEmptyBracketsMode]any{
+			NestedEmptyBracketsFlatten:     []any{[]any{"b", "c"}, "d"},
+			NestedEmptyBracketsAppendInner: []any{[]any{"b", "c", "d"}},
+			NestedEmptyBracketsNewInner:    []any{[]any{"b", "c"}, []any{"d"}},
+		}},
+		{"single bracket unaffected", "a[]=b&a[]=c", nil, map[NestedEmptyBracketsMode]any{
+			NestedEmptyBracketsFlatten:     []any{"b", "c"},
+			NestedEmptyBracketsAppendInner: []any{"b", "c"},
+			NestedEmptyBracketsNewInner:    []any{"b", "c"},
+		}},
+	}
+
+	for _, tt := range tests {
+		for mode, want := range tt.want {
+			for _, delimiter := range []string{"&", ";;"} {
+				t.Run(tt.name+" "+string(mode)+" "+delimiter, func(t *testing.T) {
+					input := strings.ReplaceAll(tt.input, "&", delimiter)
+					opts := append([]ParseOption{WithParseNestedEmptyBrackets(mode), WithParseDelimiter(delimiter)}, tt.opts...)
+					got, err := Parse(input, opts...)
+					if err != nil {
+						t.Fatalf("unexpected error: %v", err)
+					}
+					if !reflect.DeepEqual(got["a"], want) {
+						t.Errorf("a = %v, want %v", got["a"], want)
+					}
+				})
+			}
+		}
+	}
+
+	t.Run("nested key", func(t *testing.T) {
+		got, err := Parse("a[x][][]=b&a[x][][]=c", WithParseNestedEmptyBrackets(NestedEmptyBracketsNewInner))
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		want := map[string]any{"a": map[string]any{"x": []any{[]any{"b"}, []any{"c"}}}}
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("got %v, want %v", got, want)
+		}
+	})
+
+	t.Run("invalid mode", func(t *testing.T) {
+		if _, err := Parse("a[][]=b", WithParseNestedEmptyBrackets("wrap")); !errors.Is(err, ErrInvalidNestedBrackets) {
+			t.Errorf("got %v, want %v", err, ErrInvalidNestedBrackets)
+		}
+	})
+}
+
+func TestParseLenientSentinel(t *testing.T) {
+	tests := []struct {
+		name    string
+		input   string
+		lenient bool
+		want    map[string]any
+	}{
+		{"trailing plus", "utf8=%E2%9C%93+&a=%C3%B8", true, map[string]any{"a": "ø"}},
+		{"encoded spaces and lower case", "utf8=%20%e2%9c%93%09&a=%C3%B8", true, map[string]any{"a": "ø"}},
+		{"iso with raw space", "utf8= %26%2310003%3B&a=%F8", true, map[string]any{"a": "ø"}},
+		{"unknown value kept", "utf8=+unknown&a=%C3%B8", true, map[string]any{"utf8": " unknown", "a": "ø"}},
+		{"strict keeps padded value", "utf8=%26%2310003%3B+&a=%C3%B8", false, map[string]any{"utf8": "&#10003; ", "a": "ø"}},
+		{"lower case without lenient", "utf8=%26%2310003%3b&a=%F8", false, map[string]any{"a": "ø"}},
+	}
+
+	for _, tt := range tests {
+		for _, delimiter := range []string{"&", ";;"} {
+			t.Run(tt.name+" "+delimiter, func(t *testing.T) {
+				input := strings.ReplaceAll(tt.input, "&", delimiter)
+				got, err := Parse(input,
+					WithParseCharsetSentinel(true), WithParseLenientSentinel(tt.lenient), WithParseDelimiter(delimiter))
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				if !reflect.DeepEqual(got, tt.want) {
+					t.Errorf("got %v, want %v", got, tt.want)
+				}
+			})
+		}
+	}
+}
+
+func TestParseCharsetSentinels(t *testing.T) {
+	const shiftJIS Charset = "shift_jis"
+	sentinels := WithParseCharsetSentinels(map[Charset]string{shiftJIS: "%81%E3"})
+
+	// Records the charset values are decoded with
+	var charsets []Charset
+	decoder := WithParseDecoder(func(str string, charset Charset, kind string) (string, error) {
+		if kind == "value" {
+			charsets = append(charsets, charset)
+		}
+		return DefaultDecoder(str, charset, kind)
+	})
+
+	tests := []struct {
+		name        string
+		input       string
+		opts        []ParseOption
+		want        map[string]any
+		wantCharset Charset
+	}{
+		{"registered", "utf8=%81%E3&a=b", nil, map[string]any{"a": "b"}, shiftJIS},
+		{"lower case", "utf8=%81%e3&a=b", nil, map[string]any{"a": "b"}, shiftJIS},
+		{"lenient", "utf8=%81%E3+&a=b", []ParseOption{WithParseLenientSentinel(true)}, map[string]any{"a": "b"}, shiftJIS},
+		{"built-in still detected", "utf8=%26%2310003%3B&a=b", nil, map[string]any{"a": "b"}, CharsetISO88591},
+		{"unknown value kept", "utf8=x&a=b", nil, map[string]any{"utf8": "x", "a": "b"}, CharsetUTF8},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			charsets = nil
+			opts := append([]ParseOption{WithParseCharsetSentinel(true), sentinels, decoder}, tt.opts...)
+			got, err := Parse(tt.input, opts...)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got %v, want %v", got, tt.want)
+			}
+			if charsets[len(charsets)-1] != tt.wantCharset {
+				t.Errorf("decoded with %v, want %v", charsets, tt.wantCharset)
+			}
+		})
+	}
+
+	t.Run("flat", func(t *testing.T) {
+		got, err := ParseFlatString("utf8=%81%E3&a=b", WithParseCharsetSentinel(true), sentinels)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if want := map[string]string{"a": "b"}; !reflect.DeepEqual(got, want) {
+			t.Errorf("got %v, want %v", got, want)
+		}
+	})
+
+	t.Run("invalid", func(t *testing.T) {
+		for _, m := range []map[Charset]string{{"": "%81"}, {shiftJIS: ""}} {
+			if _, err := Parse("a=b", WithParseCharsetSentinels(m)); !errors.Is(err, ErrInvalidCharsetSentinels) {
+				t.Errorf("%v: got error %v, want ErrInvalidCharsetSentinels", m, err)
+			}
+		}
+	})
+}
+
+func TestParseFlatString(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		opts  []ParseOption
+		want  map[string]string
+	}{
+		{"simple", "a=1&b=x+y&c", nil, map[string]string{"a": "1", "b": "x y", "c": ""}},
+		{"empty", "", nil, map[string]string{}},
+		{"query prefix", "?a=1", []ParseOption{WithParseIgnoreQueryPrefix(true)}, map[string]string{"a": "1"}},
+		{"comma ignored", "a=1,2", []ParseOption{WithParseComma(true)}, map[string]string{"a": "1,2"}},
+		{"dots without AllowDots", "a.b=1", nil, map[string]string{"a.b": "1"}},
+		{"delimiter", "a=1;b=2", []ParseOption{WithParseDelimiter(";")}, map[string]string{"a": "1", "b": "2"}},
+		{"charset sentinel", "utf8=%26%2310003%3B&a=%E9", []ParseOption{WithParseCharsetSentinel(true)}, map[string]string{"a": "é"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseFlatString(tt.input, tt.opts...)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got %v, want %v", got, tt.want)
+			}
+		})
+	}
+
+	errTests := []struct {
+		name  string
+		input string
+		opts  []ParseOption
+		want  error
+	}{
+		{"brackets", "a=1&b[c]=2", nil, ErrNestedKey},
+		{"encoded brackets", "b%5Bc%5D=2", nil, ErrNestedKey},
+		{"dots with AllowDots", "a.b=1", []ParseOption{WithParseAllowDots(true)}, ErrNestedKey},
+		{"duplicate", "a=1&a=2", nil, ErrDuplicateKey},
+		{"parameter limit", "a=1&b=2", []ParseOption{WithParseParameterLimit(1), WithParseThrowOnLimitExceeded(true)}, ErrParameterLimitExceeded},
+	}
+	for _, tt := range errTests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := ParseFlatString(tt.input, tt.opts...)
+			if !errors.Is(err, tt.want) {
+				t.Errorf("expected %v, got %v", tt.want, err)
+			}
+		})
+	}
+}
+
+func TestParseArrayLimitFor(t *testing.T) {
+	limits := WithParseArrayLimitFor(map[string]int{"items": 1000, "ids": 2})
+
+	tests := []struct {
+		name  string
+		input string
+		opts  []ParseOption
+		want  map[string]any
+	}{
+		{"high limit stays array", "items[500]=x&other[500]=y", nil, map[string]any{
+			"items": []any{"x"},
+			"other": map[string]any{"500": "y"},
+		}},
+		{"low limit converts to object", "ids[3]=a&other[3]=b", nil, map[string]any{
+			"ids":   map[string]any{"3": "a"},
+			"other": []any{"b"},
+		}},
+		{"nested arrays", "items[0][500]=x", nil, map[string]any{"items": []any{[]any{"x"}}}},
+		{"split parser", "items[500]=x;;ids[3]=a", []ParseOption{WithParseDelimiter(";;")}, map[string]any{
+			"items": []any{"x"},
+			"ids":   map[string]any{"3": "a"},
+		}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Parse(tt.input, append([]ParseOption{limits}, tt.opts...)...)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got %v, want %v", got, tt.want)
+			}
+		})
+	}
+
+	// ThrowOnLimitExceeded counts repeated values against the key's limit
+	_, err := Parse("ids[]=1&ids[]=2&ids[]=3", limits, WithParseThrowOnLimitExceeded(true))
+	if !errors.Is(err, ErrArrayLimitExceeded) {
+		t.Errorf("expected ErrArrayLimitExceeded, got %v", err)
+	}
+	if _, err := Parse("items[]=1&items[]=2&items[]=3", limits, WithParseThrowOnLimitExceeded(true), WithParseArrayLimit(1)); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
+
+func TestParseFixedArraySize(t *testing.T) {
+	sizes := WithParseFixedArraySize(map[string]int{"coords": 3})
+
+	for name, delimiter := range map[string]string{"lang parser": "&", "split parser": ";;"} {
+		t.Run(name, func(t *testing.T) {
+			join := func(parts ...string) string { return strings.Join(parts, delimiter) }
+			opts := []ParseOption{WithParseDelimiter(delimiter), sizes}
+
+			got, err := Parse(join("coords[0]=1", "coords[2]=3", "coords[1]=2", "other[5]=x"), opts...)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			coords, ok := got["coords"].([]any)
+			if !ok || !reflect.DeepEqual(coords, []any{"1", "2", "3"}) {
+				t.Errorf("coords = %v, want [1 2 3]", got["coords"])
+			}
+			if cap(coords) < 3 {
+				t.Errorf("cap(coords) = %d, want at least 3", cap(coords))
+			}
+
+			for input, wantIndex := range map[string]string{
+				join("coords[0]=1", "coords[3]=4"):                           "index 3",
+				join("coords[]=1", "coords[]=2", "coords[]=3", "coords[]=4"): "index 3",
+				join("coords=1", "coords=2", "coords=3", "coords=4"):         "index 3",
+			} {
+				_, err := Parse(input, opts...)
+				if !errors.Is(err, ErrArrayIndexOutOfRange) {
+					t.Errorf("%q: expected ErrArrayIndexOutOfRange, got %v", input, err)
+					continue
+				}
+				if !strings.Contains(err.Error(), `"coords"`) || !strings.Contains(err.Error(), wantIndex) {
+					t.Errorf("%q: error %q should name key and %s", input, err, wantIndex)
+				}
+			}
+		})
+	}
+
+	if _, err := Parse("a=b", WithParseFixedArraySize(map[string]int{"a": -1})); !errors.Is(err, ErrInvalidFixedArraySize) {
+		t.Errorf("expected ErrInvalidFixedArraySize, got %v", err)
+	}
+}
+
+func TestParseGroupBracketObjects(t *testing.T) {
+	rows := "person[][name]=A&person[][age]=30&person[][name]=B&person[][age]=40"
+
+	// Default matches JS qs, which merges sub-keys into one element
+	got, err := Parse(rows)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	want := map[string]any{"person": []any{map[string]any{"name": []any{"A", "B"}, "age": []any{"30", "40"}}}}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("default: got %v, want %v", got, want)
+	}
+
+	tests := []struct {
+		name  string
+		input string
+		want  map[string]any
+	}{
+		{"interleaved siblings", rows, map[string]any{"person": []any{
+			map[string]any{"name": "A", "age": "30"},
+			map[string]any{"name": "B", "age": "40"},
+		}}},
+		{"missing sibling", "p[][name]=A&p[][name]=B&p[][age]=40", map[string]any{"p": []any{
+			map[string]any{"name": "A"},
+			map[string]any{"name": "B", "age": "40"},
+		}}},
+		{"separate arrays", "a[][x]=1&b[][x]=2&a[][x]=3", map[string]any{
+			"a": []any{map[string]any{"x": "1"}, map[string]any{"x": "3"}},
+			"b": []any{map[string]any{"x": "2"}},
+		}},
+		{"nested sub-keys", "p[][n][f]=A&p[][n][l]=B&p[][n][f]=C", map[string]any{"p": []any{
+			map[string]any{"n": map[string]any{"f": "A", "l": "B"}},
+			map[string]any{"n": map[string]any{"f": "C"}},
+		}}},
+		{"plain pushes unaffected", "a[]=1&a[]=2", map[string]any{"a": []any{"1", "2"}}},
+		{"encoded brackets", "p%5B%5D%5Bn%5D=A&p%5B%5D%5Bn%5D=B", map[string]any{"p": []any{
+			map[string]any{"n": "A"},
+			map[string]any{"n": "B"},
+		}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Parse(tt.input, WithParseGroupBracketObjects(true))
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got %v, want %v", got, tt.want)
+			}
+		})
+	}
+
+	// Repeated rows are not duplicate keys once grouped
+	if _, err := Parse(rows, WithParseGroupBracketObjects(true), WithParseRejectDuplicates(true)); err != nil {
+		t.Errorf("with RejectDuplicates: unexpected error: %v", err)
+	}
+}
+
+func TestParseInvalidUTF8(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		mode  InvalidUTF8Mode
+		want  map[string]any
+	}{
+		{"preserve by default", "a=%E2%82", "", map[string]any{"a": "\xe2\x82"}},
+		{"replace truncated", "a=x%E2%82y", InvalidUTF8Replace, map[string]any{"a": "x�y"}},
+		{"replace lone continuation", "a=%80%80", InvalidUTF8Replace, map[string]any{"a": "�"}},
+		{"replace in key", "%F0%9F%98=1", InvalidUTF8Replace, map[string]any{"�": "1"}},
+		{"valid unchanged", "a=%E2%82%AC", InvalidUTF8Error, map[string]any{"a": "€"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Parse(tt.input, WithParseInvalidUTF8(tt.mode))
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+
+	for _, input := range []string{"a=%E2%82", "%C3=1", "a[%E2]=1"} {
+		if _, err := Parse(input, WithParseInvalidUTF8(InvalidUTF8Error)); !errors.Is(err, ErrInvalidUTF8) {
+			t.Errorf("%q: expected ErrInvalidUTF8, got %v", input, err)
+		}
+		if _, err := Parse(input, WithParseInvalidUTF8(InvalidUTF8Error), WithParseDelimiter(";;")); !errors.Is(err, ErrInvalidUTF8) {
+			t.Errorf("%q split parser: expected ErrInvalidUTF8, got %v", input, err)
+		}
+	}
+
+	// ISO-8859-1 bytes are always valid
+	got, err := Parse("a=%E2", WithParseCharset(CharsetISO88591), WithParseInvalidUTF8(InvalidUTF8Error))
+	if err != nil || got["a"] != "â" {
+		t.Errorf("ISO-8859-1: got %v, %v", got, err)
+	}
+
+	if _, err := Parse("a=b", WithParseInvalidUTF8("bogus")); !errors.Is(err, ErrInvalidUTF8Mode) {
+		t.Errorf("expected ErrInvalidUTF8Mode, got %v", err)
+	}
+}
+
+func TestParseAlwaysArrayKeys(t *testing.T) {
+	keys := WithParseAlwaysArrayKeys([]string{"tag", "id", "f", "n"})
+
+	for name, delimiter := range map[string]string{"lang parser": "&", "split parser": ";;"} {
+		t.Run(name, func(t *testing.T) {
+			join := func(parts ...string) string { return strings.Join(parts, delimiter) }
+			opts := []ParseOption{keys, WithParseDelimiter(delimiter)}
+
+			tests := []struct {
+				input string
+				want  map[string]any
+			}{
+				{join("tag=a", "other=b"), map[string]any{"tag": []any{"a"}, "other": "b"}},
+				{join("tag=a", "tag=b"), map[string]any{"tag": []any{"a", "b"}}},
+				{join("id[]=1"), map[string]any{"id": []any{"1"}}},
+				{join("tag="), map[string]any{"tag": []any{""}}},
+				{join("f[x]=1"), map[string]any{"f": map[string]any{"x": "1"}}},
+				{join("other=b"), map[string]any{"other": "b"}},
+			}
+			for _, tt := range tests {
+				got, err := Parse(tt.input, opts...)
+				if err != nil {
+					t.Fatalf("%q: unexpected error: %v", tt.input, err)
+				}
+				if !reflect.DeepEqual(got, tt.want) {
+					t.Errorf("%q: got %v, want %v", tt.input, got, tt.want)
+				}
+			}
+		})
+	}
+
+	got, err := Parse("n&tag=a", keys, WithParseStrictNullHandling(true))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if want := map[string]any{"n": []any{nil}, "tag": []any{"a"}}; !reflect.DeepEqual(got, want) {
+		t.Errorf("strict null: got %v, want %v", got, want)
+	}
+}
+
+func TestParseFlatResult(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		opts  []ParseOption
+		want  map[string]any
+	}{
+		{"nested maps", "a[b][c]=d&a[e]=f&g=h", nil, map[string]any{"a.b.c": "d", "a.e": "f", "g": "h"}},
+		{"arrays", "e[]=f&e[]=g&x[0][y]=1", nil, map[string]any{"e.0": "f", "e.1": "g", "x.0.y": "1"}},
+		{"repeated keys", "a=1&a=2", nil, map[string]any{"a.0": "1", "a.1": "2"}},
+		{"dot notation input", "a.b=c", []ParseOption{WithParseAllowDots(true)}, map[string]any{"a.b": "c"}},
+		{"empty array kept", "a[]=", []ParseOption{WithParseAllowEmptyArrays(true)}, map[string]any{"a": []any{}}},
+		{"split parser", "a[b]=1;;c[]=2", []ParseOption{WithParseDelimiter(";;")}, map[string]any{"a.b": "1", "c.0": "2"}},
+		{"dots and brackets in keys escaped", "a.b=1&a[b]=2&c[d.e]=3&f%25=4&g]=5", nil, map[string]any{"a%2Eb": "1", "a.b": "2", "c.d%2Ee": "3", "f%25": "4", "g%5D": "5"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Parse(tt.input, append(tt.opts, WithParseFlatResult(true))...)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseDoubleDecodeKeys(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		opts  []ParseOption
+		want  map[string]any
+	}{
+		{"named key", "next=%253Fa%253D1%2526b%253D2&q=%2541", nil,
+			map[string]any{"next": "?a=1&b=2", "q": "%41"}},
+		{"nested key", "r[url]=%2541&s[url]=%2541", nil,
+			map[string]any{"r": map[string]any{"url": "A"}, "s": map[string]any{"url": "%41"}}},
+		{"comma values", "next=%2541,%2542", []ParseOption{WithParseComma(true)},
+			map[string]any{"next": []any{"A", "B"}}},
+		{"single pass only", "next=%25252541", nil, map[string]any{"next": "%2541"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			opts := append([]ParseOption{WithParseDoubleDecodeKeys("next", "r[url]")}, tt.opts...)
+			got, err := Parse(tt.input, opts...)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got %v, want %v", got, tt.want)
+			}
+		})
+	}
+
+	t.Run("flat", func(t *testing.T) {
+		got, err := ParseFlatString("next=%253F&q=%253F", WithParseDoubleDecodeKeys("next"))
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if want := map[string]string{"next": "?", "q": "%3F"}; !reflect.DeepEqual(got, want) {
+			t.Errorf("got %v, want %v", got, want)
+		}
+	})
+}
+
+func TestParseBytes(t *testing.T) {
+	buf := []byte("a[b]=c&d=e")
+	got, err := ParseBytes(buf)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	// The result must not change when the buffer is reused
+	copy(buf, "xxxxxxxxxx")
+	if want := map[string]any{"a": map[string]any{"b": "c"}, "d": "e"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+
+	if _, err := ParseBytes([]byte("a=1&b=2"), WithParseParameterLimit(1), WithParseThrowOnLimitExceeded(true)); !errors.Is(err, ErrParameterLimitExceeded) {
+		t.Errorf("got error %v, want ErrParameterLimitExceeded", err)
+	}
+}
+
+func TestParseDecodePasses(t *testing.T) {
+	tests := []struct {
+		name   string
+		input  string
+		passes int
+		opts   []ParseOption
+		want   map[string]any
+	}{
+		{"single pass by default", "a=%2520", 1, nil, map[string]any{"a": "%20"}},
+		{"double encoded", "a=%2520b&c=%25C3%25BC", 2, nil, map[string]any{"a": " b", "c": "ü"}},
+		{"keys", "a%255Bb%255D=1", 2, nil, map[string]any{"a": map[string]any{"b": "1"}}},
+		{"keys with split-only option", "a%255Bb%255D=1", 2, []ParseOption{WithParseDoubleDecodeKeys("zz")}, map[string]any{"a": map[string]any{"b": "1"}}},
+		{"bounded", "a=%252520", 2, nil, map[string]any{"a": "%20"}},
+		{"stops when stable", "a=%252520&b=x%2Fy", 5, nil, map[string]any{"a": " ", "b": "x/y"}},
+		{"plus kept after first pass", "a=1+%252B+2", 2, nil, map[string]any{"a": "1 + 2"}},
+		{"encoded plus", "a=%2B", 2, nil, map[string]any{"a": "+"}},
+		{"split path", "a=%2520;b=%2541", 2, []ParseOption{WithParseDelimiter(";")}, map[string]any{"a": " ", "b": "A"}},
+		{"regexp delimiter", "a=%2520;;b=1", 2, []ParseOption{WithParseDelimiterRegexp(regexp.MustCompile(`;+`))}, map[string]any{"a": " ", "b": "1"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			opts := append([]ParseOption{WithParseDecodePasses(tt.passes)}, tt.opts...)
+			got, err := Parse(tt.input, opts...)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got %v, want %v", got, tt.want)
+			}
+		})
+	}
+
+	t.Run("invalid utf-8 error", func(t *testing.T) {
+		_, err := Parse("a=%25E2%2582", WithParseDecodePasses(2), WithParseInvalidUTF8(InvalidUTF8Error))
+		if !errors.Is(err, ErrInvalidUTF8) {
+			t.Errorf("got error %v, want ErrInvalidUTF8", err)
+		}
+	})
+}
+
+func TestParseMaxInputLength(t *testing.T) {
+	long := "a=" + strings.Repeat("x", 100)
+	tests := []struct {
+		name    string
+		input   string
+		max     int
+		opts    []ParseOption
+		wantErr bool
+	}{
+		{"unlimited by default", long, 0, nil, false},
+		{"within limit", "a=1&b=2", 7, nil, false},
+		{"single long value", long, 50, nil, true},
+		{"query prefix counts", "?a=1", 3, []ParseOption{WithParseIgnoreQueryPrefix(true)}, true},
+		{"split path", "a=1;b=2", 5, []ParseOption{WithParseDelimiter(";")}, true},
+		{"negative means unlimited", long, -1, nil, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			opts := append([]ParseOption{WithParseMaxInputLength(tt.max)}, tt.opts...)
+			_, err := Parse(tt.input, opts...)
+			if tt.wantErr != errors.Is(err, ErrInputTooLong) {
+				t.Fatalf("got error %v, want ErrInputTooLong: %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+		})
+	}
+
+	t.Run("other entry points", func(t *testing.T) {
+		opt := WithParseMaxInputLength(50)
+		if _, err := ParseFlatString(long, opt); !errors.Is(err, ErrInputTooLong) {
+			t.Errorf("ParseFlatString got error %v, want ErrInputTooLong", err)
+		}
+		var dest struct {
+			A string `query:"a"`
+		}
+		if err := Unmarshal(long, &dest, opt); !errors.Is(err, ErrInputTooLong) {
+			t.Errorf("Unmarshal got error %v, want ErrInputTooLong", err)
+		}
+		r := &endlessParams{}
+		if _, err := ParseReader(r, opt, WithParseParameterLimit(0)); !errors.Is(err, ErrInputTooLong) {
+			t.Errorf("ParseReader got error %v, want ErrInputTooLong", err)
+		}
+		if r.read > readChunkSize {
+			t.Errorf("read %d bytes, want at most %d", r.read, readChunkSize)
+		}
+	})
+}
+
+func TestParseValueSubParse(t *testing.T) {
+	tests := []struct {
+		name   string
+		input  string
+		format map[string]ValueFormat
+		opts   []ParseOption
+		want   map[string]any
+	}{
+		{"default separators", "meta=k1:v1,k2:v2&a=b", map[string]ValueFormat{"meta": {}}, nil,
+			map[string]any{"meta": map[string]any{"k1": "v1", "k2": "v2"}, "a": "b"}},
+		{"custom separators", "meta=k1=v1;k2=v2", map[string]ValueFormat{"meta": {ElementSeparator: ";", PairSeparator: "="}}, nil,
+			map[string]any{"meta": map[string]any{"k1": "v1", "k2": "v2"}}},
+		{"decodes parts", "meta=a%3Ab:c%2Cd,e:f+g", map[string]ValueFormat{"meta": {}}, nil,
+			map[string]any{"meta": map[string]any{"a:b": "c,d", "e": "f g"}}},
+		{"missing pair separator", "meta=k1,,k2:v2", map[string]ValueFormat{"meta": {}}, nil,
+			map[string]any{"meta": map[string]any{"k1": "", "k2": "v2"}}},
+		{"repeated packed key", "meta=k:1,k:2", map[string]ValueFormat{"meta": {}}, nil,
+			map[string]any{"meta": map[string]any{"k": "2"}}},
+		{"empty value", "meta=", map[string]ValueFormat{"meta": {}}, nil,
+			map[string]any{"meta": ""}},
+		{"nested key", "filter[meta]=k:v", map[string]ValueFormat{"filter[meta]": {}}, nil,
+			map[string]any{"filter": map[string]any{"meta": map[string]any{"k": "v"}}}},
+		{"other keys unchanged", "a=k:v,x:y", map[string]ValueFormat{"meta": {}}, []ParseOption{WithParseComma(true)},
+			map[string]any{"a": []any{"k:v", "x:y"}}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			opts := append([]ParseOption{WithParseValueSubParse(tt.format)}, tt.opts...)
+			got, err := Parse(tt.input, opts...)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got %v, want %v", got, tt.want)
+			}
+		})
+	}
+
+	t.Run("same separators", func(t *testing.T) {
+		_, err := Parse("meta=a", WithParseValueSubParse(map[string]ValueFormat{"meta": {PairSeparator: ","}}))
+		if !errors.Is(err, ErrInvalidValueSubParse) {
+			t.Errorf("got error %v, want ErrInvalidValueSubParse", err)
+		}
+	})
+}
@@ -0,0 +1,163 @@
+// Copyright 2025 Zaytra
+// SPDX-License-Identifier: Apache-2.0
+
+package qs
+
+import (
+	"bytes"
+	"io"
+)
+
+// readChunkSize is the number of bytes ParseReader reads at a time.
+const readChunkSize = 4096
+
+// ParseReader parses a query string read from r, such as an
+// application/x-www-form-urlencoded request body, like Parse.
+//
+// Instead of reading r to the end first, it scans the input for
+// delimiters and stops reading once ParameterLimit parameters were read,
+// so memory use is bounded by the limit rather than by the input. As in
+// Parse, empty parts between delimiters do not count toward the limit.
+// With ThrowOnLimitExceeded, a parameter ended by a delimiter after the
+// last one within the limit returns ErrParameterLimitExceeded without
+// reading further.
+//
+// DelimiterRegexp is matched against the input read so far, reading more
+// while a match reaches its end. With DelimiterEscape, or a ParameterLimit
+// of zero or less, r is read to the end. With MaxInputLength, at most one
+// byte more than the limit is read before ErrInputTooLong is returned.
+//
+// Example:
+//
+//	result, err := qs.ParseReader(r.Body, qs.WithParseParameterLimit(100))
+func ParseReader(r io.Reader, opts ...ParseOption) (map[string]any, error) {
+	options := applyParseOptions(opts...)
+
+	// Normalize options
+	normalizedOpts, err := normalizeParseOptions(&options)
+	if err != nil {
+		return nil, err
+	}
+
+	str, err := readParams(r, &normalizedOpts)
+	if err != nil {
+		return nil, err
+	}
+	return Parse(str, opts...)
+}
+
+// Parser parses a query string read from an io.Reader, like ParseReader.
+type Parser struct {
+	r    io.Reader
+	opts []ParseOption
+}
+
+// NewParser returns a Parser reading from r with the given options.
+//
+// Example:
+//
+//	p := qs.NewParser(r.Body, qs.WithParseParameterLimit(100))
+//	result, err := p.Parse()
+func NewParser(r io.Reader, opts ...ParseOption) *Parser {
+	return &Parser{r: r, opts: opts}
+}
+
+// Parse reads the query string from the Parser's reader and parses it.
+// As with ParseReader, reading stops once ParameterLimit parameters were
+// read.
+func (p *Parser) Parse() (map[string]any, error) {
+	return ParseReader(p.r, p.opts...)
+}
+
+// readParams reads r until it ends or ParameterLimit non-empty parameters
+// were read, and returns the input up to the end of the last parameter.
+// With ThrowOnLimitExceeded it reads on until one more parameter ends, so
+// that Parse still sees the input it would reject.
+func readParams(r io.Reader, opts *ParseOptions) (string, error) {
+	if opts.MaxInputLength > 0 {
+		r = io.LimitReader(r, int64(opts.MaxInputLength)+1)
+	}
+
+	limit := opts.ParameterLimit
+	if opts.DelimiterEscape != 0 || limit <= 0 {
+		data, err := io.ReadAll(r)
+		return string(data), err
+	}
+
+	var buf []byte
+	chunk := make([]byte, readChunkSize)
+	params, start, scanned := 0, 0, 0
+	for {
+		n, err := r.Read(chunk)
+		buf = append(buf, chunk[:n]...)
+		eof := err == io.EOF
+		if err != nil && !eof {
+			return "", err
+		}
+
+		// Count the parameters ended by delimiters in the new input
+		for {
+			loc, next := nextDelimiter(buf[start:], scanned-start, opts, eof)
+			if loc == nil {
+				scanned = start + next
+				break
+			}
+			if loc[0] > 0 {
+				params++
+			}
+			if opts.ThrowOnLimitExceeded && params > limit {
+				return "", ErrParameterLimitExceeded
+			}
+			if !opts.ThrowOnLimitExceeded && params == limit {
+				return string(buf[:start+loc[0]]), nil
+			}
+			start += loc[1]
+			scanned = start
+		}
+
+		if eof {
+			return string(buf), nil
+		}
+	}
+}
+
+// nextDelimiter returns the start and end of the first delimiter in b at
+// or after from. Otherwise it returns nil and the from to pass once b has
+// grown: the offset up to which b holds no delimiter or, for
+// DelimiterRegexp, the size of b when it was last searched. Unless eof is
+// set, a delimiter is only returned if more input could not change it.
+func nextDelimiter(b []byte, from int, opts *ParseOptions, eof bool) ([]int, int) {
+	if re := opts.DelimiterRegexp; re != nil && len(opts.Delimiters) == 0 {
+		// Matches may start before where the last one ended, so b is
+		// searched again from its start once it has doubled in size
+		if !eof && len(b) < 2*from {
+			return nil, from
+		}
+		loc := re.FindIndex(b)
+		if loc == nil || loc[0] == loc[1] || (!eof && loc[1] == len(b)) {
+			return nil, max(len(b), 1)
+		}
+		return loc, 0
+	}
+
+	delimiters := opts.Delimiters
+	if len(delimiters) == 0 {
+		delimiters = []string{opts.Delimiter}
+	}
+	maxLen := 0
+	for _, d := range delimiters {
+		maxLen = max(maxLen, len(d))
+	}
+	for i := from; i < len(b); i++ {
+		if !eof && len(b)-i < maxLen {
+			return nil, i
+		}
+		// The first matching delimiter wins, as in splitByDelimiters
+		for _, d := range delimiters {
+			if d != "" && bytes.HasPrefix(b[i:], []byte(d)) {
+				return []int{i, i + len(d)}, 0
+			}
+		}
+	}
+	return nil, len(b)
+}
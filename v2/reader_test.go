@@ -0,0 +1,143 @@
+// Copyright 2025 Zaytra
+// SPDX-License-Identifier: Apache-2.0
+
+package qs
+
+import (
+	"errors"
+	"io"
+	"reflect"
+	"regexp"
+	"strings"
+	"testing"
+	"testing/iotest"
+)
+
+// endlessParams returns an unlimited stream of "a=1&" parameters.
+type endlessParams struct {
+	read int
+}
+
+func (r *endlessParams) Read(p []byte) (int, error) {
+	for i := range p {
+		p[i] = "a=1&"[(r.read+i)%4]
+	}
+	r.read += len(p)
+	return len(p), nil
+}
+
+func TestParseReader(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		opts  []ParseOption
+	}{
+		{"simple", "a=1&b[c]=2&b[d]=3&e[]=4&e[]=5", nil},
+		{"limit", "a=1&b=2&c=3&d=4", []ParseOption{WithParseParameterLimit(2)}},
+		{"empty", "", nil},
+		{"query prefix", "?a=1&b=2", []ParseOption{WithParseIgnoreQueryPrefix(true)}},
+		{"multi-char delimiter", "a=1;;b=2;;c=3", []ParseOption{WithParseDelimiter(";;"), WithParseParameterLimit(2)}},
+		{"delimiters", "a=1;b=2&c=3", []ParseOption{WithParseDelimiters([]string{";", "&"})}},
+		{"regexp", "a=1; b=2;c=3", []ParseOption{WithParseDelimiterRegexp(regexp.MustCompile(`;\s*`)), WithParseParameterLimit(2)}},
+		{"escape", `a=1\&2&b=3`, []ParseOption{WithParseDelimiterEscape('\\'), WithParseParameterLimit(1)}},
+		{"charset sentinel", "utf8=%E2%9C%93&a=%C3%BC", []ParseOption{WithParseCharsetSentinel(true)}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			want, err := Parse(tt.input, tt.opts...)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			// One byte at a time to split delimiters across reads
+			got, err := ParseReader(iotest.OneByteReader(strings.NewReader(tt.input)), tt.opts...)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if !reflect.DeepEqual(got, want) {
+				t.Errorf("got %v, want %v", got, want)
+			}
+		})
+	}
+
+	t.Run("stops at limit", func(t *testing.T) {
+		r := &endlessParams{}
+		got, err := ParseReader(r, WithParseParameterLimit(3), WithParseDuplicates(DuplicateLast))
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if want := map[string]any{"a": "1"}; !reflect.DeepEqual(got, want) {
+			t.Errorf("got %v, want %v", got, want)
+		}
+		if r.read > readChunkSize {
+			t.Errorf("read %d bytes, want at most %d", r.read, readChunkSize)
+		}
+	})
+
+	t.Run("throw on limit", func(t *testing.T) {
+		r := &endlessParams{}
+		_, err := ParseReader(r, WithParseParameterLimit(3), WithParseThrowOnLimitExceeded(true))
+		if !errors.Is(err, ErrParameterLimitExceeded) {
+			t.Fatalf("got error %v, want ErrParameterLimitExceeded", err)
+		}
+
+		got, err := ParseReader(strings.NewReader("a=1&b=2"), WithParseParameterLimit(2), WithParseThrowOnLimitExceeded(true))
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if want := map[string]any{"a": "1", "b": "2"}; !reflect.DeepEqual(got, want) {
+			t.Errorf("got %v, want %v", got, want)
+		}
+	})
+
+	t.Run("empty parts do not count", func(t *testing.T) {
+		tests := []struct {
+			input string
+			opts  []ParseOption
+		}{
+			{"a=1&&&b=2", []ParseOption{WithParseParameterLimit(2)}},
+			{"&&&a=1", []ParseOption{WithParseParameterLimit(1)}},
+			{"a=1&&&b=2&c=3", []ParseOption{WithParseParameterLimit(2)}},
+			{"a=1&b=2&", []ParseOption{WithParseParameterLimit(2), WithParseThrowOnLimitExceeded(true)}},
+			{"a=1&&b=2&&", []ParseOption{WithParseParameterLimit(2), WithParseThrowOnLimitExceeded(true)}},
+		}
+		for _, tt := range tests {
+			want, err := Parse(tt.input, tt.opts...)
+			if err != nil {
+				t.Fatalf("Parse(%q): %v", tt.input, err)
+			}
+			got, err := ParseReader(strings.NewReader(tt.input), tt.opts...)
+			if err != nil {
+				t.Fatalf("ParseReader(%q): %v", tt.input, err)
+			}
+			if !reflect.DeepEqual(got, want) {
+				t.Errorf("ParseReader(%q) = %v, want %v", tt.input, got, want)
+			}
+		}
+	})
+
+	t.Run("read error", func(t *testing.T) {
+		errRead := errors.New("read failed")
+		_, err := ParseReader(io.MultiReader(strings.NewReader("a=1&"), iotest.ErrReader(errRead)))
+		if !errors.Is(err, errRead) {
+			t.Errorf("got error %v, want %v", err, errRead)
+		}
+	})
+}
+
+func TestParser(t *testing.T) {
+	r := &endlessParams{}
+	p := NewParser(r, WithParseParameterLimit(2), WithParseDuplicates(DuplicateFirst))
+	got, err := p.Parse()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if want := map[string]any{"a": "1"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+
+	_, err = NewParser(&endlessParams{}, WithParseParameterLimit(2), WithParseThrowOnLimitExceeded(true)).Parse()
+	if !errors.Is(err, ErrParameterLimitExceeded) {
+		t.Errorf("got error %v, want ErrParameterLimitExceeded", err)
+	}
+}
@@ -0,0 +1,161 @@
+// Copyright 2025 Zaytra
+// SPDX-License-Identifier: Apache-2.0
+
+package qs
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+)
+
+// SchemaType is the expected type of a key declared in a Schema.
+type SchemaType string
+
+const (
+	// SchemaString expects a scalar string value.
+	SchemaString SchemaType = "string"
+	// SchemaInt expects a value that parses as a base-10 integer.
+	SchemaInt SchemaType = "int"
+	// SchemaBool expects a value accepted by strconv.ParseBool.
+	SchemaBool SchemaType = "bool"
+	// SchemaArray expects a list; a single scalar is wrapped in a slice.
+	SchemaArray SchemaType = "array"
+)
+
+// Schema validation errors.
+var (
+	ErrSchemaMissingKey  = errors.New("required key is missing")
+	ErrSchemaInvalidType = errors.New("value has invalid type")
+)
+
+// Schema declares the top-level keys an endpoint expects, their types and
+// which of them are required. Schema.Parse validates a query against it and
+// returns typed values. Keys not declared in the schema are passed through
+// unchanged.
+//
+// Example:
+//
+//	s := qs.NewSchema().
+//		AddString("q").
+//		AddInt("page").
+//		AddArray("tags").
+//		Require("q")
+//	result, err := s.Parse("q=go&page=2&tags=a")
+//	// result = map[string]any{"q": "go", "page": 2, "tags": []any{"a"}}
+type Schema struct {
+	fields   map[string]SchemaType
+	order    []string
+	required []string
+	opts     []ParseOption
+}
+
+// NewSchema creates an empty schema. The options are used by Schema.Parse.
+func NewSchema(opts ...ParseOption) *Schema {
+	return &Schema{
+		fields: make(map[string]SchemaType),
+		opts:   opts,
+	}
+}
+
+// Add declares key with the given type, replacing any earlier declaration.
+func (s *Schema) Add(key string, typ SchemaType) *Schema {
+	if _, exists := s.fields[key]; !exists {
+		s.order = append(s.order, key)
+	}
+	s.fields[key] = typ
+	return s
+}
+
+// AddString declares a string key.
+func (s *Schema) AddString(key string) *Schema {
+	return s.Add(key, SchemaString)
+}
+
+// AddInt declares an integer key.
+func (s *Schema) AddInt(key string) *Schema {
+	return s.Add(key, SchemaInt)
+}
+
+// AddBool declares a boolean key.
+func (s *Schema) AddBool(key string) *Schema {
+	return s.Add(key, SchemaBool)
+}
+
+// AddArray declares an array key.
+func (s *Schema) AddArray(key string) *Schema {
+	return s.Add(key, SchemaArray)
+}
+
+// Require marks keys as required. Required keys that were not declared
+// with a type only need to be present.
+func (s *Schema) Require(keys ...string) *Schema {
+	s.required = append(s.required, keys...)
+	return s
+}
+
+// Parse parses query and validates the result against the schema.
+// Declared keys are converted to their typed values: string, int, bool
+// or []any. Errors wrap ErrSchemaMissingKey or ErrSchemaInvalidType.
+func (s *Schema) Parse(query string) (map[string]any, error) {
+	result, err := Parse(query, s.opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, key := range s.required {
+		if _, ok := result[key]; !ok {
+			return nil, fmt.Errorf("%w: %q", ErrSchemaMissingKey, key)
+		}
+	}
+
+	for _, key := range s.order {
+		value, ok := result[key]
+		if !ok {
+			continue
+		}
+		typed, err := convertSchemaValue(value, s.fields[key])
+		if err != nil {
+			return nil, fmt.Errorf("%w: %q: %v", ErrSchemaInvalidType, key, err)
+		}
+		result[key] = typed
+	}
+
+	return result, nil
+}
+
+// convertSchemaValue converts a parsed value to the given schema type.
+func convertSchemaValue(value any, typ SchemaType) (any, error) {
+	if typ == SchemaArray {
+		switch v := value.(type) {
+		case []any:
+			return v, nil
+		case map[string]any:
+			return nil, errors.New("expected array, got object")
+		default:
+			return []any{v}, nil
+		}
+	}
+
+	str, ok := value.(string)
+	if !ok {
+		return nil, fmt.Errorf("expected %s, got %T", typ, value)
+	}
+
+	switch typ {
+	case SchemaInt:
+		n, err := strconv.Atoi(str)
+		if err != nil {
+			return nil, fmt.Errorf("cannot convert %q to int", str)
+		}
+		return n, nil
+	case SchemaBool:
+		b, err := strconv.ParseBool(str)
+		if err != nil {
+			return nil, fmt.Errorf("cannot convert %q to bool", str)
+		}
+		return b, nil
+	default:
+		return str, nil
+	}
+}
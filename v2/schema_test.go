@@ -0,0 +1,93 @@
+// Copyright 2025 Zaytra
+// SPDX-License-Identifier: Apache-2.0
+
+package qs
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestSchemaParse(t *testing.T) {
+	schema := NewSchema().
+		AddString("q").
+		AddInt("page").
+		AddBool("draft").
+		AddArray("tags").
+		Require("q")
+
+	tests := []struct {
+		name    string
+		query   string
+		want    map[string]any
+		wantErr error
+	}{
+		{
+			name:  "typed values",
+			query: "q=go&page=2&draft=true&tags[]=a&tags[]=b",
+			want:  map[string]any{"q": "go", "page": 2, "draft": true, "tags": []any{"a", "b"}},
+		},
+		{
+			name:  "optional keys absent",
+			query: "q=go",
+			want:  map[string]any{"q": "go"},
+		},
+		{
+			name:  "scalar wrapped as array",
+			query: "q=go&tags=a",
+			want:  map[string]any{"q": "go", "tags": []any{"a"}},
+		},
+		{
+			name:  "unknown keys passed through",
+			query: "q=go&x[y]=z",
+			want:  map[string]any{"q": "go", "x": map[string]any{"y": "z"}},
+		},
+		{name: "missing required", query: "page=1", wantErr: ErrSchemaMissingKey},
+		{name: "invalid int", query: "q=go&page=two", wantErr: ErrSchemaInvalidType},
+		{name: "invalid bool", query: "q=go&draft=maybe", wantErr: ErrSchemaInvalidType},
+		{name: "array for string", query: "q=a&q=b", wantErr: ErrSchemaInvalidType},
+		{name: "object for array", query: "q=go&tags[x]=1", wantErr: ErrSchemaInvalidType},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := schema.Parse(tt.query)
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Fatalf("got error %v, want %v", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSchemaOptions(t *testing.T) {
+	schema := NewSchema(WithParseAllowDots(true)).AddInt("a").Require("b")
+
+	got, err := schema.Parse("a=1&b.c=d")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	want := map[string]any{"a": 1, "b": map[string]any{"c": "d"}}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+
+	// Redeclaring a key replaces its type
+	schema.AddString("a")
+	got, err = schema.Parse("a=1&b=x")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got["a"] != "1" {
+		t.Errorf("got %v, want string \"1\"", got["a"])
+	}
+}
@@ -0,0 +1,89 @@
+// Copyright 2025 Zaytra
+// SPDX-License-Identifier: Apache-2.0
+
+package qs
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// SortKeysAsc returns a SortFunc ordering keys in ascending byte order.
+//
+// Example:
+//
+//	str, _ := qs.Stringify(data, qs.WithStringifySort(qs.SortKeysAsc()))
+func SortKeysAsc() SortFunc {
+	return func(a, b string) bool { return a < b }
+}
+
+// SortKeysDesc returns a SortFunc ordering keys in descending byte order.
+func SortKeysDesc() SortFunc {
+	return func(a, b string) bool { return a > b }
+}
+
+// SortNumericKeys returns a SortFunc ordering integer keys by value, so
+// "2" comes before "10", followed by all other keys in ascending byte
+// order.
+func SortNumericKeys() SortFunc {
+	return func(a, b string) bool {
+		na, errA := strconv.ParseInt(a, 10, 64)
+		nb, errB := strconv.ParseInt(b, 10, 64)
+		switch {
+		case errA == nil && errB == nil:
+			return na < nb
+		case errA == nil || errB == nil:
+			return errA == nil
+		}
+		return a < b
+	}
+}
+
+// SortByValueLength returns a SortFunc for WithStringifySortPairs that
+// puts pairs with shorter serialized values first. Pairs whose values
+// have the same length keep their order.
+//
+// Example:
+//
+//	str, _ := qs.Stringify(data, qs.WithStringifySortPairs(qs.SortByValueLength()))
+func SortByValueLength() SortFunc {
+	return func(a, b string) bool {
+		return len(pairValue(a)) < len(pairValue(b))
+	}
+}
+
+// pairValue returns the value part of a serialized key=value pair.
+func pairValue(pair string) string {
+	_, value, _ := strings.Cut(pair, "=")
+	return value
+}
+
+// pairKey returns the key part of a serialized key=value pair.
+func pairKey(pair string) string {
+	key, _, _ := strings.Cut(pair, "=")
+	return key
+}
+
+// sortPairsByKey sorts serialized pairs with less, keeping the pairs of
+// each key together and in their order, as IndexedRepeat requires. Keys
+// are ordered by their first pair.
+func sortPairsByKey(pairs []string, less SortFunc) {
+	var keys []string
+	groups := make(map[string][]string)
+	for _, pair := range pairs {
+		key := pairKey(pair)
+		if _, ok := groups[key]; !ok {
+			keys = append(keys, key)
+		}
+		groups[key] = append(groups[key], pair)
+	}
+	sort.SliceStable(keys, func(i, j int) bool {
+		return less(groups[keys[i]][0], groups[keys[j]][0])
+	})
+
+	pairs = pairs[:0]
+	for _, key := range keys {
+		pairs = append(pairs, groups[key]...)
+	}
+}
@@ -0,0 +1,56 @@
+// Copyright 2025 Zaytra
+// SPDX-License-Identifier: Apache-2.0
+
+package qs
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestSortFuncs(t *testing.T) {
+	tests := []struct {
+		name string
+		less SortFunc
+		in   []string
+		want []string
+	}{
+		{"keys asc", SortKeysAsc(), []string{"b", "a", "B", "c"}, []string{"B", "a", "b", "c"}},
+		{"keys desc", SortKeysDesc(), []string{"b", "a", "c"}, []string{"c", "b", "a"}},
+		{"numeric keys", SortNumericKeys(), []string{"10", "b", "2", "-1", "a", "1"}, []string{"-1", "1", "2", "10", "a", "b"}},
+		{"value length", SortByValueLength(), []string{"a=xyz", "b=x", "c", "d=xy", "e=y"}, []string{"c", "b=x", "e=y", "d=xy", "a=xyz"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := append([]string(nil), tt.in...)
+			sortStrings(got, tt.less)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestStringifySortPairs(t *testing.T) {
+	data := map[string]any{"long": "abcdef", "mid": "abc", "short": "a", "list": []any{"xy", "x"}}
+
+	got, err := Stringify(data,
+		WithStringifyEncode(false),
+		WithStringifySort(SortKeysAsc()),
+		WithStringifySortPairs(SortByValueLength()),
+	)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if want := "list[1]=x&short=a&list[0]=xy&mid=abc&long=abcdef"; got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+
+	got, err = Stringify(map[string]any{"10": "a", "9": "b", "x": "c"}, WithStringifySort(SortNumericKeys()))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if want := "9=b&10=a&x=c"; got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
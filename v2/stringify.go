@@ -4,11 +4,19 @@
 package qs
 
 import (
+	"bytes"
+	"encoding/json"
 	"errors"
+	"fmt"
+	"io"
+	"math"
 	"reflect"
+	"regexp"
+	"sort"
 	"strconv"
 	"strings"
 	"time"
+	"unicode/utf8"
 )
 
 // ArrayFormat specifies how arrays should be serialized in the query string.
@@ -23,6 +31,9 @@ const (
 	ArrayFormatRepeat ArrayFormat = "repeat"
 	// ArrayFormatComma serializes arrays as comma-separated values: a=b,c
 	ArrayFormatComma ArrayFormat = "comma"
+	// ArrayFormatJSONPointer serializes every key as a JSON Pointer path: /a/b/0=c.
+	// "~" and "/" in keys are escaped as "~0" and "~1" (RFC 6901).
+	ArrayFormatJSONPointer ArrayFormat = "jsonpointer"
 )
 
 // EncoderFunc is a custom encoder function signature.
@@ -35,9 +46,77 @@ const (
 // Returns the encoded string.
 type EncoderFunc func(str string, charset Charset, kind string, format Format) string
 
+// DefaultEncoder percent-encodes str like Stringify does when no Encoder
+// is set; see Encode.
+func DefaultEncoder(str string, charset Charset, kind string, format Format) string {
+	return Encode(str, charset, format)
+}
+
 // SerializeDateFunc is a function that serializes a time.Time to a string.
 type SerializeDateFunc func(t time.Time) string
 
+// QuoteMode specifies when Stringify wraps values in quotes.
+type QuoteMode string
+
+const (
+	// QuoteNever leaves values unquoted (default).
+	QuoteNever QuoteMode = "never"
+	// QuoteWhenNeeded quotes values containing the delimiter or '='.
+	QuoteWhenNeeded QuoteMode = "needed"
+	// QuoteAlways quotes every value.
+	QuoteAlways QuoteMode = "always"
+)
+
+// BoolFormat specifies how Stringify renders bool values.
+type BoolFormat string
+
+const (
+	// BoolTrueFalse renders true/false (default).
+	BoolTrueFalse BoolFormat = "truefalse"
+	// BoolOneZero renders 1/0.
+	BoolOneZero BoolFormat = "onezero"
+	// BoolYesNo renders yes/no.
+	BoolYesNo BoolFormat = "yesno"
+	// BoolOnOff renders on/off.
+	BoolOnOff BoolFormat = "onoff"
+	// BoolFlag renders true as a bare key (a) and omits false.
+	BoolFlag BoolFormat = "flag"
+)
+
+// NilSliceHandling specifies how Stringify renders a nil []any.
+type NilSliceHandling string
+
+const (
+	// NilSliceAsEmpty renders a nil slice like an empty one, so it is
+	// omitted or written as a[] depending on AllowEmptyArrays (default).
+	NilSliceAsEmpty NilSliceHandling = "empty"
+	// NilSliceSkip always omits a nil slice, even with AllowEmptyArrays.
+	NilSliceSkip NilSliceHandling = "skip"
+	// NilSliceAsNull renders a nil slice like a nil value: a= or, with
+	// StrictNullHandling, a bare a. It is omitted with SkipNulls.
+	NilSliceAsNull NilSliceHandling = "null"
+)
+
+// boolFormatValues maps value-style BoolFormats to their true and false strings.
+var boolFormatValues = map[BoolFormat][2]string{
+	BoolTrueFalse: {"true", "false"},
+	BoolOneZero:   {"1", "0"},
+	BoolYesNo:     {"yes", "no"},
+	BoolOnOff:     {"on", "off"},
+}
+
+// Array marks a value that Stringify always serializes as an array,
+// using the configured ArrayFormat. Unlike a plain slice, an empty Array
+// is written as "a[]", as with AllowEmptyArrays, and a single-element
+// Array in ArrayFormatComma as "a[]=b", as with CommaRoundTrip, so that
+// both parse back as arrays. A nil Array follows NilSlices.
+type Array []any
+
+// Object marks a value that Stringify always serializes as an object,
+// even when its keys look like array indices. Like an empty map, an empty
+// Object writes nothing.
+type Object map[string]any
+
 // FilterFunc is a function that filters/transforms values during stringification.
 // It receives the key (or prefix) and the value, and returns the transformed value.
 // Return nil to skip this key.
@@ -66,10 +145,42 @@ type StringifyOptions struct {
 	// Default: false
 	AllowEmptyArrays bool
 
+	// NilSlices sets how a nil []any (or Array) is rendered, as distinct
+	// from an empty one, which AllowEmptyArrays governs.
+	// Default: NilSliceAsEmpty
+	NilSlices NilSliceHandling
+
 	// ArrayFormat specifies how arrays are serialized.
 	// Default: ArrayFormatIndices
 	ArrayFormat ArrayFormat
 
+	// NestedArrayFormat, when set, serializes arrays found inside the
+	// elements of another array, while ArrayFormat governs the outer one:
+	// with ArrayFormatBrackets and a nested ArrayFormatIndices,
+	// {"a": [{"b": ["x"]}]} becomes a[][b][0]=x. It has no effect with
+	// ArrayFormatJSONPointer or LevelDelimiters, and cannot itself be
+	// ArrayFormatJSONPointer.
+	// Default: "" (same as ArrayFormat)
+	NestedArrayFormat ArrayFormat
+
+	// CompactIndices writes arrays in ArrayFormatIndices with empty
+	// brackets, a[]=b&a[]=c, when that parses back to the same array: the
+	// array has no gaps and holds only primitive values. Other arrays keep
+	// explicit indices, e.g. a[1]=b for a sparse array, so output stays
+	// round-trip safe. It has no effect when array keys are filtered or
+	// sorted, or with LevelDelimiters.
+	// Default: false
+	CompactIndices bool
+
+	// PreserveSparseIndices keeps the original indices of arrays with
+	// gaps, a[1]=b&a[4]=c for an array holding nil (unset) slots. When
+	// false, gaps are closed and elements are numbered in sequence,
+	// a[0]=b&a[1]=c, for backends that reject gaps. Null values skipped
+	// by SkipNulls count as gaps. A []string Filter selects indices of
+	// the closed array.
+	// Default: true
+	PreserveSparseIndices bool
+
 	// Charset specifies the character encoding to use.
 	// Default: CharsetUTF8
 	Charset Charset
@@ -83,6 +194,12 @@ type StringifyOptions struct {
 	// Default: false
 	CommaRoundTrip bool
 
+	// CommaDelimiter is the separator used to join array elements with
+	// ArrayFormatComma, e.g. "|" gives a=b|c. It is written as-is; with
+	// Encode (and not EncodeValuesOnly) it is encoded with the joined value.
+	// Default: ","
+	CommaDelimiter string
+
 	// Delimiter is the string used to join key-value pairs.
 	// Default: "&"
 	Delimiter string
@@ -91,6 +208,13 @@ type StringifyOptions struct {
 	// Default: true
 	Encode bool
 
+	// StrictUnencoded makes Stringify return an error wrapping
+	// ErrUnsafeValue, naming the key, when Encode is false and a value
+	// contains the Delimiter or "=", which would not parse back as
+	// written. It has no effect when Encode is true.
+	// Default: false
+	StrictUnencoded bool
+
 	// EncodeDotInKeys encodes . as %2E in keys when using dot notation.
 	// Default: false
 	EncodeDotInKeys bool
@@ -110,6 +234,18 @@ type StringifyOptions struct {
 	// Default: nil
 	Filter any // FilterFunc or []string
 
+	// FilterRegexp includes only top-level keys matching the pattern, e.g.
+	// regexp.MustCompile(`^utm_`). It applies together with Filter.
+	// Default: nil
+	FilterRegexp *regexp.Regexp
+
+	// FilterRegexpPath matches FilterRegexp against the full key of each
+	// value instead, as built before encoding, e.g. "a[b][0]" or "a.b.0"
+	// with AllowDots. Values written under one key, such as comma-joined
+	// arrays, are matched by that key.
+	// Default: false
+	FilterRegexpPath bool
+
 	// Format specifies the RFC encoding format (RFC1738 or RFC3986).
 	// Default: FormatRFC3986
 	Format Format
@@ -131,16 +267,172 @@ type StringifyOptions struct {
 	// Default: nil (no sorting)
 	Sort SortFunc
 
+	// KeyPriority lists top-level keys to write first, in the given order.
+	// Listed keys missing from the object are skipped, and the remaining
+	// keys follow in Sort order (or unspecified order if Sort is nil).
+	// Nested keys are not affected.
+	// Default: nil
+	KeyPriority []string
+
+	// SortPairs orders the serialized key=value pairs, as written after
+	// encoding, before they are joined, e.g. SortByValueLength(). It runs
+	// after Sort and KeyPriority, and equal pairs keep their order.
+	// Default: nil (no sorting)
+	SortPairs SortFunc
+
 	// SortArrayIndices when true, sorts array indices as strings along with object keys.
 	// This is needed for compatibility with JS qs library which sorts all keys including
 	// array indices when sort option is provided (e.g., "0", "1", "10", "2" order).
 	// Default: false (arrays preserve natural numeric order)
 	SortArrayIndices bool
 
+	// PreserveNumericKeys writes maps whose keys are all array indices
+	// (e.g., {"2": "b", "5": "c"}, as Parse produces for indices beyond
+	// ArrayLimit) with their keys in ascending numeric order: a[2]=b&a[5]=c.
+	// The keys are kept as-is regardless of ArrayFormat, and the numeric
+	// order takes precedence over Sort for such maps.
+	// Default: false
+	PreserveNumericKeys bool
+
 	// StrictNullHandling serializes null values without = sign.
 	// e.g., {a: null} → "a" instead of "a="
 	// Default: false
 	StrictNullHandling bool
+
+	// EscapePercentOnly escapes bare % signs in keys and values as %25 when
+	// Encode is false. A % already followed by two hex digits is left alone,
+	// so pre-encoded keys and values pass through unchanged. The flip side
+	// is that a literal "%41" is indistinguishable from an escaped "A" and
+	// parses back as "A"; use Encode for input that may hold such text.
+	// Has no effect when Encode is true.
+	// Default: false
+	EscapePercentOnly bool
+
+	// SafeChar decides which runes are left unencoded, replacing the
+	// format's default unreserved set. Ignored when Encoder is set.
+	// Default: nil (use the format's unreserved characters)
+	SafeChar SafeCharFunc
+
+	// QuoteValues wraps values in QuoteChar when Encode is false, producing
+	// CSV-like output for non-URL consumers that understand quoted values.
+	// Has no effect when Encode is true.
+	// Default: QuoteNever
+	QuoteValues QuoteMode
+
+	// QuoteChar is the quote rune used by QuoteValues.
+	// Default: '"'
+	QuoteChar rune
+
+	// RawKeyChars lists characters left unencoded in keys only (e.g., "/:"
+	// for path-like keys). Values are still fully encoded.
+	// Ignored when Encoder is set.
+	// Default: "" (none)
+	RawKeyChars string
+
+	// BoolFormat sets how bool values are rendered.
+	// Default: BoolTrueFalse
+	BoolFormat BoolFormat
+
+	// BoolKeys overrides BoolFormat for specific keys. Keys are matched
+	// against the full unencoded key path, e.g. "active" or "filter[active]"
+	// ("filter.active" with AllowDots).
+	// Default: nil
+	BoolKeys map[string]BoolFormat
+
+	// LevelDelimiters replaces bracket and dot notation in keys with a
+	// separator per nesting level: the Nth delimiter joins a segment at
+	// level N to its parent, and the last one is used for deeper levels.
+	// e.g., [".", "/"] gives a.b/c/d=v. Array elements are segments too:
+	// indices write the index, brackets an empty segment, and repeat none.
+	// Ignored with ArrayFormatJSONPointer.
+	// Default: nil
+	LevelDelimiters []string
+
+	// MaxValueLength makes Stringify return an error wrapping
+	// ErrValueTooLong, naming the key, when a value is longer than this
+	// many bytes as written, i.e., after encoding. Zero means no limit.
+	// Default: 0
+	MaxValueLength int
+
+	// ChecksumName and ChecksumFunc append a ChecksumName=<checksum> pair
+	// as the last parameter. The checksum is ChecksumFunc applied to the
+	// bytes of the output written before it, after any "?" prefix and
+	// including a charset sentinel, exactly as written: the pairs in
+	// output order joined by Delimiter. Set Sort for a reproducible key
+	// order; map iteration order is random otherwise. The pair is encoded
+	// like other keys and values when Encode is true.
+	// Default: "", nil (no checksum)
+	ChecksumName string
+	ChecksumFunc func(content []byte) string
+
+	// BoolKeyAsBareValue writes the first key holding true in a nested map,
+	// in Sort order or else in byte order, as a bare value of the map,
+	// after the map's other entries:
+	// {"a": {"b": "c", "d": true}} gives "a[b]=c&a=d" rather than
+	// "a[b]=c&a[d]=true". This reverses how Parse adds a bare value to an
+	// existing object as a key holding true, so such results round-trip.
+	// Only one key per map is written this way, since repeated bare values
+	// parse as an array, and maps holding only true values are written as
+	// usual, since their bare value would parse as a string. It has no
+	// effect with a Filter, and SortPairs can move the bare value before
+	// the pairs it depends on.
+	// Default: false
+	BoolKeyAsBareValue bool
+
+	// TagName is the struct tag Marshal and StructToQueryString read field
+	// names and omitempty from. Fields without it fall back to their qs
+	// tag, then their json tag, then the lowercase field name.
+	// Default: "query"
+	TagName string
+
+	// ASCIIOnly guarantees the output is ASCII by percent-encoding any
+	// non-ASCII bytes left in keys and values, as happens with Encode
+	// disabled, SafeChar, RawKeyChars, or a custom Encoder. Runes are
+	// encoded as the Encode function would for Charset. The Delimiter
+	// must be ASCII, or Stringify returns ErrNonASCIIDelimiter.
+	// Default: false
+	ASCIIOnly bool
+
+	// IndexedRepeat guarantees that ArrayFormatRepeat writes array elements
+	// in index order, making the order of repeated keys significant:
+	// SortArrayIndices is ignored, and SortPairs orders keys by their first
+	// pair, keeping the pairs of each key together and in order. Parse then
+	// rebuilds each array in order, and with DuplicateFirst or
+	// DuplicateLast keeps its first or last element. It has no effect with
+	// other array formats, whose indices are written out.
+	// Default: false
+	IndexedRepeat bool
+
+	// PairSpacing writes a space on each side of the '=' between keys and
+	// values, "a = b" rather than "a=b", for config-file-like output. It
+	// applies only with Encode disabled, since Parse would otherwise read
+	// the spaces as part of keys and values.
+	// Default: false
+	PairSpacing bool
+
+	// IntegerFormatter formats integer values, for APIs expecting hex or
+	// fixed-width IDs. It applies to values of any int or uint type, as
+	// scalars, array elements and nested values, but not to map keys or
+	// array indices. Unsigned values above math.MaxInt64 are written in
+	// decimal. Without it integers are always written in plain decimal.
+	// Default: nil
+	IntegerFormatter func(n int64) string
+
+	// DuplicateReducer combines the values written under one key, such as
+	// the elements of an array or a slice returned by Filter, before they
+	// are emitted. It receives the key as built before encoding, e.g.
+	// "a[b]", and returns the values to write, so it can merge, reorder
+	// or drop them; an empty result is handled like an empty array. It
+	// applies to arrays at any depth and runs after Filter.
+	// Default: nil
+	DuplicateReducer func(key string, values []any) []any
+
+	// ArrayElementSort orders arrays whose elements are all maps, such as
+	// a set of objects, for canonical output, e.g. by comparing an "id"
+	// field. The sort is stable, and arrays holding other values keep
+	// their order. It applies at any depth, after DuplicateReducer.
+	// Default: nil
+	ArrayElementSort func(a, b map[string]any) bool
 }
 
 // Default values for StringifyOptions
@@ -157,8 +449,16 @@ var (
 	ErrInvalidStringifyCharset          = errors.New("charset must be utf-8 or iso-8859-1")
 	ErrInvalidFormat                    = errors.New("unknown format option provided")
 	ErrInvalidCommaRoundTrip            = errors.New("commaRoundTrip must be a boolean, or absent")
-	ErrInvalidArrayFormat               = errors.New("arrayFormat must be indices, brackets, repeat, or comma")
+	ErrInvalidArrayFormat               = errors.New("arrayFormat must be indices, brackets, repeat, comma, or jsonpointer")
 	ErrCyclicReference                  = errors.New("cyclic object value")
+	ErrEmptyRootKey                     = errors.New("root key must not be empty")
+	ErrInvalidQuoteMode                 = errors.New("quoteValues must be never, needed, or always")
+	ErrInvalidBoolFormat                = errors.New("boolFormat must be truefalse, onezero, yesno, onoff, or flag")
+	ErrValueTooLong                     = errors.New("value too long")
+	ErrUnsafeValue                      = errors.New("unencoded value contains a delimiter")
+	ErrInvalidNilSlices                 = errors.New("nilSlices must be empty, skip, or null")
+	ErrInvalidChecksum                  = errors.New("checksum requires both a name and a function")
+	ErrNonASCIIDelimiter                = errors.New("asciiOnly requires an ASCII delimiter")
 )
 
 // defaultSerializeDate is the default date serialization function.
@@ -169,25 +469,55 @@ func defaultSerializeDate(t time.Time) string {
 // DefaultStringifyOptions returns StringifyOptions with default values.
 func DefaultStringifyOptions() StringifyOptions {
 	return StringifyOptions{
-		AddQueryPrefix:     false,
-		AllowDots:          false,
-		AllowEmptyArrays:   false,
-		ArrayFormat:        ArrayFormatIndices,
-		Charset:            CharsetUTF8,
-		CharsetSentinel:    false,
-		CommaRoundTrip:     false,
-		Delimiter:          DefaultStringifyDelimiter,
-		Encode:             true,
-		EncodeDotInKeys:    false,
-		Encoder:            nil,
-		EncodeValuesOnly:   false,
-		Filter:             nil,
-		Format:             DefaultFormat,
-		Formatter:          nil,
-		SerializeDate:      defaultSerializeDate,
-		SkipNulls:          false,
-		Sort:               nil,
-		StrictNullHandling: false,
+		AddQueryPrefix:        false,
+		AllowDots:             false,
+		AllowEmptyArrays:      false,
+		NilSlices:             NilSliceAsEmpty,
+		ArrayFormat:           ArrayFormatIndices,
+		NestedArrayFormat:     "",
+		CompactIndices:        false,
+		PreserveSparseIndices: true,
+		Charset:               CharsetUTF8,
+		CharsetSentinel:       false,
+		CommaRoundTrip:        false,
+		CommaDelimiter:        ",",
+		Delimiter:             DefaultStringifyDelimiter,
+		Encode:                true,
+		StrictUnencoded:       false,
+		EncodeDotInKeys:       false,
+		Encoder:               nil,
+		EncodeValuesOnly:      false,
+		Filter:                nil,
+		FilterRegexp:          nil,
+		FilterRegexpPath:      false,
+		Format:                DefaultFormat,
+		Formatter:             nil,
+		SerializeDate:         defaultSerializeDate,
+		SkipNulls:             false,
+		Sort:                  nil,
+		KeyPriority:           nil,
+		SortPairs:             nil,
+		PreserveNumericKeys:   false,
+		StrictNullHandling:    false,
+		EscapePercentOnly:     false,
+		SafeChar:              nil,
+		QuoteValues:           QuoteNever,
+		QuoteChar:             '"',
+		RawKeyChars:           "",
+		BoolFormat:            BoolTrueFalse,
+		BoolKeys:              nil,
+		LevelDelimiters:       nil,
+		MaxValueLength:        0,
+		ChecksumName:          "",
+		ChecksumFunc:          nil,
+		BoolKeyAsBareValue:    false,
+		TagName:               defaultTagName,
+		ASCIIOnly:             false,
+		IndexedRepeat:         false,
+		PairSpacing:           false,
+		IntegerFormatter:      nil,
+		DuplicateReducer:      nil,
+		ArrayElementSort:      nil,
 	}
 }
 
@@ -219,13 +549,66 @@ func normalizeStringifyOptions(opts *StringifyOptions) (StringifyOptions, error)
 		result.Formatter = GetFormatter(result.Format)
 	}
 
+	// Validate level delimiters
+	for _, d := range result.LevelDelimiters {
+		if d == "" {
+			return result, ErrInvalidLevelDelimiters
+		}
+	}
+
+	// Validate checksum
+	if (result.ChecksumName == "") != (result.ChecksumFunc == nil) {
+		return result, ErrInvalidChecksum
+	}
+
+	// Validate nil slice handling
+	if result.NilSlices == "" {
+		result.NilSlices = NilSliceAsEmpty
+	} else if result.NilSlices != NilSliceAsEmpty &&
+		result.NilSlices != NilSliceSkip &&
+		result.NilSlices != NilSliceAsNull {
+		return result, ErrInvalidNilSlices
+	}
+
+	// Validate bool formats
+	if result.BoolFormat == "" {
+		result.BoolFormat = BoolTrueFalse
+	} else if !isValidBoolFormat(result.BoolFormat) {
+		return result, ErrInvalidBoolFormat
+	}
+	for _, f := range result.BoolKeys {
+		if !isValidBoolFormat(f) {
+			return result, ErrInvalidBoolFormat
+		}
+	}
+
+	// Validate quote mode
+	if result.QuoteValues == "" {
+		result.QuoteValues = QuoteNever
+	} else if result.QuoteValues != QuoteNever &&
+		result.QuoteValues != QuoteWhenNeeded &&
+		result.QuoteValues != QuoteAlways {
+		return result, ErrInvalidQuoteMode
+	}
+	if result.QuoteChar == 0 {
+		result.QuoteChar = '"'
+	}
+
 	// Validate array format
 	if result.ArrayFormat == "" {
 		result.ArrayFormat = ArrayFormatIndices
 	} else if result.ArrayFormat != ArrayFormatIndices &&
 		result.ArrayFormat != ArrayFormatBrackets &&
 		result.ArrayFormat != ArrayFormatRepeat &&
-		result.ArrayFormat != ArrayFormatComma {
+		result.ArrayFormat != ArrayFormatComma &&
+		result.ArrayFormat != ArrayFormatJSONPointer {
+		return result, ErrInvalidArrayFormat
+	}
+	if result.NestedArrayFormat != "" &&
+		result.NestedArrayFormat != ArrayFormatIndices &&
+		result.NestedArrayFormat != ArrayFormatBrackets &&
+		result.NestedArrayFormat != ArrayFormatRepeat &&
+		result.NestedArrayFormat != ArrayFormatComma {
 		return result, ErrInvalidArrayFormat
 	}
 
@@ -243,6 +626,12 @@ func normalizeStringifyOptions(opts *StringifyOptions) (StringifyOptions, error)
 	if result.Delimiter == "" {
 		result.Delimiter = DefaultStringifyDelimiter
 	}
+	if result.ASCIIOnly && !isASCII(result.Delimiter) {
+		return result, ErrNonASCIIDelimiter
+	}
+	if result.CommaDelimiter == "" {
+		result.CommaDelimiter = ","
+	}
 
 	// Set default date serializer
 	if result.SerializeDate == nil {
@@ -285,6 +674,13 @@ func WithStringifyAllowEmptyArrays(v bool) StringifyOption {
 	}
 }
 
+// WithStringifyNilSlices sets how nil slices are rendered.
+func WithStringifyNilSlices(v NilSliceHandling) StringifyOption {
+	return func(o *StringifyOptions) {
+		o.NilSlices = v
+	}
+}
+
 // WithStringifyArrayFormat sets how arrays are serialized.
 func WithStringifyArrayFormat(v ArrayFormat) StringifyOption {
 	return func(o *StringifyOptions) {
@@ -292,6 +688,27 @@ func WithStringifyArrayFormat(v ArrayFormat) StringifyOption {
 	}
 }
 
+// WithStringifyNestedArrayFormat sets how arrays nested in arrays are serialized.
+func WithStringifyNestedArrayFormat(v ArrayFormat) StringifyOption {
+	return func(o *StringifyOptions) {
+		o.NestedArrayFormat = v
+	}
+}
+
+// WithStringifyCompactIndices writes dense arrays of primitives as a[] in indices format.
+func WithStringifyCompactIndices(v bool) StringifyOption {
+	return func(o *StringifyOptions) {
+		o.CompactIndices = v
+	}
+}
+
+// WithStringifyPreserveSparseIndices keeps the original indices of arrays with gaps.
+func WithStringifyPreserveSparseIndices(v bool) StringifyOption {
+	return func(o *StringifyOptions) {
+		o.PreserveSparseIndices = v
+	}
+}
+
 // WithStringifyCharset sets the character encoding to use.
 func WithStringifyCharset(v Charset) StringifyOption {
 	return func(o *StringifyOptions) {
@@ -313,6 +730,13 @@ func WithStringifyCommaRoundTrip(v bool) StringifyOption {
 	}
 }
 
+// WithStringifyCommaDelimiter sets the separator for comma-format arrays.
+func WithStringifyCommaDelimiter(v string) StringifyOption {
+	return func(o *StringifyOptions) {
+		o.CommaDelimiter = v
+	}
+}
+
 // WithStringifyDelimiter sets the string used to join key-value pairs.
 func WithStringifyDelimiter(v string) StringifyOption {
 	return func(o *StringifyOptions) {
@@ -327,6 +751,13 @@ func WithStringifyEncode(v bool) StringifyOption {
 	}
 }
 
+// WithStringifyStrictUnencoded rejects unencoded values containing the delimiter or "=".
+func WithStringifyStrictUnencoded(v bool) StringifyOption {
+	return func(o *StringifyOptions) {
+		o.StrictUnencoded = v
+	}
+}
+
 // WithStringifyEncodeDotInKeys encodes . as %2E in keys.
 func WithStringifyEncodeDotInKeys(v bool) StringifyOption {
 	return func(o *StringifyOptions) {
@@ -342,6 +773,29 @@ func WithStringifyEncoder(v EncoderFunc) StringifyOption {
 	}
 }
 
+// WithStringifyEncoders sets a pipeline of encoders applied in order to
+// each key and value: the first receives the raw string, each later one the
+// previous output, and the last produces the final encoded form. Every
+// stage receives the same kind ("key" or "value"). Include DefaultEncoder
+// to keep percent-encoding, e.g.:
+//
+//	qs.WithStringifyEncoders(replaceTabs, qs.DefaultEncoder)
+func WithStringifyEncoders(v ...EncoderFunc) StringifyOption {
+	encoders := append([]EncoderFunc(nil), v...)
+	return func(o *StringifyOptions) {
+		if len(encoders) == 0 {
+			o.Encoder = nil
+			return
+		}
+		o.Encoder = func(str string, charset Charset, kind string, format Format) string {
+			for _, e := range encoders {
+				str = e(str, charset, kind, format)
+			}
+			return str
+		}
+	}
+}
+
 // WithStringifyEncodeValuesOnly only encodes values, not keys.
 func WithStringifyEncodeValuesOnly(v bool) StringifyOption {
 	return func(o *StringifyOptions) {
@@ -356,6 +810,20 @@ func WithStringifyFilter(v any) StringifyOption {
 	}
 }
 
+// WithStringifyFilterRegexp includes only top-level keys matching re.
+func WithStringifyFilterRegexp(re *regexp.Regexp) StringifyOption {
+	return func(o *StringifyOptions) {
+		o.FilterRegexp = re
+	}
+}
+
+// WithStringifyFilterRegexpPath matches FilterRegexp against full keys.
+func WithStringifyFilterRegexpPath(v bool) StringifyOption {
+	return func(o *StringifyOptions) {
+		o.FilterRegexpPath = v
+	}
+}
+
 // WithStringifyFormat sets the RFC encoding format.
 func WithStringifyFormat(v Format) StringifyOption {
 	return func(o *StringifyOptions) {
@@ -392,6 +860,20 @@ func WithStringifySort(v SortFunc) StringifyOption {
 	}
 }
 
+// WithStringifyKeyPriority sets top-level keys to write first, in order.
+func WithStringifyKeyPriority(keys []string) StringifyOption {
+	return func(o *StringifyOptions) {
+		o.KeyPriority = keys
+	}
+}
+
+// WithStringifySortPairs sets a function for sorting serialized key=value pairs.
+func WithStringifySortPairs(v SortFunc) StringifyOption {
+	return func(o *StringifyOptions) {
+		o.SortPairs = v
+	}
+}
+
 // WithStringifySortArrayIndices enables sorting array indices as strings.
 // When true, array indices are sorted lexicographically like object keys,
 // producing output like "a[0], a[1], a[10], a[2]" instead of "a[0], a[1], a[2], a[10]".
@@ -402,6 +884,13 @@ func WithStringifySortArrayIndices(v bool) StringifyOption {
 	}
 }
 
+// WithStringifyPreserveNumericKeys writes index-keyed maps in numeric key order.
+func WithStringifyPreserveNumericKeys(v bool) StringifyOption {
+	return func(o *StringifyOptions) {
+		o.PreserveNumericKeys = v
+	}
+}
+
 // WithStringifyStrictNullHandling serializes null values without = sign.
 func WithStringifyStrictNullHandling(v bool) StringifyOption {
 	return func(o *StringifyOptions) {
@@ -409,6 +898,133 @@ func WithStringifyStrictNullHandling(v bool) StringifyOption {
 	}
 }
 
+// WithStringifyEscapePercentOnly escapes bare % signs in keys and values when encoding is disabled.
+func WithStringifyEscapePercentOnly(v bool) StringifyOption {
+	return func(o *StringifyOptions) {
+		o.EscapePercentOnly = v
+	}
+}
+
+// WithStringifySafeCharFunc sets a predicate for runes that are left unencoded.
+func WithStringifySafeCharFunc(v SafeCharFunc) StringifyOption {
+	return func(o *StringifyOptions) {
+		o.SafeChar = v
+	}
+}
+
+// WithStringifyQuoteValues sets when values are wrapped in quotes with encoding disabled.
+func WithStringifyQuoteValues(v QuoteMode) StringifyOption {
+	return func(o *StringifyOptions) {
+		o.QuoteValues = v
+	}
+}
+
+// WithStringifyQuoteChar sets the quote rune used by QuoteValues.
+func WithStringifyQuoteChar(v rune) StringifyOption {
+	return func(o *StringifyOptions) {
+		o.QuoteChar = v
+	}
+}
+
+// WithStringifyRawKeyChars sets characters that are left unencoded in keys.
+func WithStringifyRawKeyChars(v string) StringifyOption {
+	return func(o *StringifyOptions) {
+		o.RawKeyChars = v
+	}
+}
+
+// WithStringifyBoolFormat sets how bool values are rendered.
+func WithStringifyBoolFormat(v BoolFormat) StringifyOption {
+	return func(o *StringifyOptions) {
+		o.BoolFormat = v
+	}
+}
+
+// WithStringifyBoolKeys sets per-key bool formats, overriding BoolFormat.
+func WithStringifyBoolKeys(v map[string]BoolFormat) StringifyOption {
+	return func(o *StringifyOptions) {
+		o.BoolKeys = v
+	}
+}
+
+// WithStringifyLevelDelimiters sets a key separator per nesting level.
+func WithStringifyLevelDelimiters(v ...string) StringifyOption {
+	return func(o *StringifyOptions) {
+		o.LevelDelimiters = v
+	}
+}
+
+// WithStringifyMaxValueLength limits the length of each serialized value.
+func WithStringifyMaxValueLength(v int) StringifyOption {
+	return func(o *StringifyOptions) {
+		o.MaxValueLength = v
+	}
+}
+
+// WithStringifyChecksum appends a name=<checksum> pair computed by fn over the output.
+func WithStringifyChecksum(name string, fn func(content []byte) string) StringifyOption {
+	return func(o *StringifyOptions) {
+		o.ChecksumName = name
+		o.ChecksumFunc = fn
+	}
+}
+
+// WithStringifyBoolKeyAsBareValue writes true values in nested maps as bare values of the map.
+func WithStringifyBoolKeyAsBareValue(v bool) StringifyOption {
+	return func(o *StringifyOptions) {
+		o.BoolKeyAsBareValue = v
+	}
+}
+
+// WithStringifyTagName sets the struct tag used to name fields when marshaling.
+func WithStringifyTagName(v string) StringifyOption {
+	return func(o *StringifyOptions) {
+		o.TagName = v
+	}
+}
+
+// WithStringifyASCIIOnly percent-encodes any non-ASCII bytes left in the output.
+func WithStringifyASCIIOnly(v bool) StringifyOption {
+	return func(o *StringifyOptions) {
+		o.ASCIIOnly = v
+	}
+}
+
+// WithStringifyIndexedRepeat keeps repeated keys in index order with ArrayFormatRepeat.
+func WithStringifyIndexedRepeat(v bool) StringifyOption {
+	return func(o *StringifyOptions) {
+		o.IndexedRepeat = v
+	}
+}
+
+// WithStringifyPairSpacing writes "key = value" pairs when Encode is disabled.
+func WithStringifyPairSpacing(v bool) StringifyOption {
+	return func(o *StringifyOptions) {
+		o.PairSpacing = v
+	}
+}
+
+// WithStringifyIntegerFormatter sets a custom function for formatting integer values.
+func WithStringifyIntegerFormatter(fn func(n int64) string) StringifyOption {
+	return func(o *StringifyOptions) {
+		o.IntegerFormatter = fn
+	}
+}
+
+// WithStringifyDuplicateReducer sets a function combining the values written under one key.
+func WithStringifyDuplicateReducer(fn func(key string, values []any) []any) StringifyOption {
+	return func(o *StringifyOptions) {
+		o.DuplicateReducer = fn
+	}
+}
+
+// WithStringifyArrayElementSort sets a comparison function for ordering arrays of maps.
+func WithStringifyArrayElementSort(less func(a, b map[string]any) bool) StringifyOption {
+	return func(o *StringifyOptions) {
+		o.ArrayElementSort = less
+	}
+}
+
 // applyStringifyOptions applies functional options to a StringifyOptions struct.
 func applyStringifyOptions(opts ...StringifyOption) StringifyOptions {
 	o := DefaultStringifyOptions()
@@ -418,12 +1034,81 @@ func applyStringifyOptions(opts ...StringifyOption) StringifyOptions {
 	return o
 }
 
+// arraySettings holds how arrays are written at one level of nesting:
+// ArrayFormat for the outermost arrays, and NestedArrayFormat below them
+// when set.
+type arraySettings struct {
+	generateArrayPrefix func(prefix string, key string) string
+	commaRoundTrip      bool
+	compactIndices      bool
+}
+
+// stringifyState holds the settings stringify reads at every level,
+// derived from the normalized options once per walkPairs call.
+type stringifyState struct {
+	commaDelimiter        string
+	nested                *arraySettings
+	preserveSparseIndices bool
+	allowEmptyArrays      bool
+	nilSlices             NilSliceHandling
+	strictNullHandling    bool
+	skipNulls             bool
+	encodeDotInKeys       bool
+	filter                any
+	pathFilter            *regexp.Regexp
+	duplicateReducer      func(string, []any) []any
+	sort                  SortFunc
+	keyOrder              func(map[string]any) []string
+	elementSort           func(a, b map[string]any) bool
+	sortArrayIndices      bool
+	preserveNumericKeys   bool
+	allowDots             bool
+	jsonPointer           bool
+	levels                *keyLevels
+	boolFormatFor         func(key string) BoolFormat
+	boolKeyAsBareValue    bool
+	pairSeparator         string
+	maxValueLength        int
+	unsafeDelimiter       string
+	serializeDate         SerializeDateFunc
+	integerFormatter      func(int64) string
+	format                Format
+	formatter             FormatterFunc
+	encodeValuesOnly      bool
+	charset               Charset
+}
+
 // arrayPrefixGenerators holds functions that generate the key prefix for array items.
 var arrayPrefixGenerators = map[ArrayFormat]func(prefix string, key string) string{
 	ArrayFormatBrackets: func(prefix, key string) string { return prefix + "[]" },
 	ArrayFormatIndices:  func(prefix, key string) string { return prefix + "[" + key + "]" },
 	ArrayFormatRepeat:   func(prefix, key string) string { return prefix },
 	ArrayFormatComma:    nil, // Special case, handled separately
+	ArrayFormatJSONPointer: func(prefix, key string) string {
+		return prefix + "/" + escapeJSONPointer(key)
+	},
+}
+
+// keyLevels joins key segments with LevelDelimiters.
+type keyLevels struct {
+	delimiters []string
+	// arraySegment returns the segment written for an array element, or
+	// false if the array format writes none (repeat).
+	arraySegment func(key string) (string, bool)
+}
+
+// delimiter returns the separator that joins a segment at the given
+// zero-based boundary to its parent.
+func (l *keyLevels) delimiter(level int) string {
+	return l.delimiters[min(level, len(l.delimiters)-1)]
+}
+
+// jsonPointerEscaper escapes a JSON Pointer reference token (RFC 6901).
+var jsonPointerEscaper = strings.NewReplacer("~", "~0", "/", "~1")
+
+// escapeJSONPointer escapes "~" and "/" in a JSON Pointer path segment.
+func escapeJSONPointer(key string) string {
+	return jsonPointerEscaper.Replace(key)
 }
 
 // isNonNullishPrimitive checks if a value is a non-nil primitive type (string, number, bool).
@@ -509,25 +1194,15 @@ func (sc *sideChannel) child() *sideChannel {
 }
 
 // stringify is the internal recursive function that stringifies values.
-func stringify(
+// It writes object under prefix using arrays for the array settings of
+// this level, encoder to encode keys and values (nil to write them as is)
+// and level to pick the LevelDelimiter of the next key segment.
+func (st *stringifyState) stringify(
 	object any,
 	prefix string,
-	generateArrayPrefix func(string, string) string,
-	commaRoundTrip bool,
-	allowEmptyArrays bool,
-	strictNullHandling bool,
-	skipNulls bool,
-	encodeDotInKeys bool,
+	arrays arraySettings,
 	encoder func(string, Charset, string, Format) string,
-	filter any,
-	sort SortFunc,
-	sortArrayIndices bool,
-	allowDots bool,
-	serializeDate SerializeDateFunc,
-	format Format,
-	formatter FormatterFunc,
-	encodeValuesOnly bool,
-	charset Charset,
+	level int,
 	sideChannel *sideChannel,
 	step int,
 ) ([]string, error) {
@@ -549,7 +1224,7 @@ func stringify(
 	// For backwards compatibility and JS parity, we skip only if:
 	// 1. Filter returns nil AND original value was NOT nil (filter explicitly removed it)
 	// 2. Filter returns SkipValue marker
-	if filterFunc, ok := filter.(FilterFunc); ok {
+	if filterFunc, ok := st.filter.(FilterFunc); ok {
 		origObj := obj
 		obj = filterFunc(prefix, obj)
 		// Skip only if filter explicitly returns nil for non-nil input (JS undefined behavior)
@@ -557,7 +1232,7 @@ func stringify(
 		if obj == nil && origObj != nil {
 			return []string{}, nil
 		}
-	} else if fn, ok := filter.(func(string, any) any); ok {
+	} else if fn, ok := st.filter.(func(string, any) any); ok {
 		origObj := obj
 		obj = fn(prefix, obj)
 		if obj == nil && origObj != nil {
@@ -565,45 +1240,126 @@ func stringify(
 		}
 	}
 
+	// Unwrap explicit Array and Object markers, remembering an Array so
+	// that it is written as an array where a plain slice may not be
+	_, explicitArray := obj.(Array)
+	obj = unwrapContainer(obj)
+
+	// Combine the values written under prefix, see DuplicateReducer
+	if s, ok := obj.([]any); ok && s != nil && st.duplicateReducer != nil {
+		if s = st.duplicateReducer(prefix, s); s == nil {
+			s = []any{}
+		}
+		obj = s
+	}
+
+	if s, ok := obj.([]any); ok && st.elementSort != nil {
+		obj = sortMapElements(s, st.elementSort)
+	}
+
+	// Number the elements of sparse arrays in sequence, see PreserveSparseIndices
+	if s, ok := obj.([]any); ok && !st.preserveSparseIndices && arrays.generateArrayPrefix != nil {
+		obj = closeGaps(s, st.skipNulls)
+	}
+
+	// Handle nil slices
+	if s, ok := obj.([]any); ok && s == nil {
+		switch st.nilSlices {
+		case NilSliceSkip:
+			return []string{}, nil
+		case NilSliceAsNull:
+			if st.skipNulls {
+				return []string{}, nil
+			}
+			obj = nil
+		}
+	}
+
 	// Handle time.Time
 	if t, ok := obj.(time.Time); ok {
-		obj = serializeDate(t)
+		obj = st.serializeDate(t)
+	}
+
+	// Fall back to fmt.Stringer for other types (net.IP, *big.Rat, ...)
+	if s, ok := stringerValue(obj); ok {
+		obj = s
+	}
+
+	if s, ok := formatInteger(obj, st.integerFormatter); ok {
+		obj = s
 	}
 
 	// Handle comma format with arrays - serialize dates in array first
-	if generateArrayPrefix == nil && isSlice(obj) {
+	if arrays.generateArrayPrefix == nil && isSlice(obj) {
 		obj = MaybeMap(obj, func(v any) any {
 			if t, ok := v.(time.Time); ok {
-				return serializeDate(t)
+				return st.serializeDate(t)
+			}
+			if s, ok := formatInteger(v, st.integerFormatter); ok {
+				return s
 			}
 			return v
 		})
 	}
 
+	// Values are written under prefix unless they are non-empty containers
+	if st.pathFilter != nil && !st.pathFilter.MatchString(prefix) && !hasChildren(obj, arrays.generateArrayPrefix == nil) {
+		return []string{}, nil
+	}
+
 	// Handle nil/null
 	if obj == nil || IsExplicitNull(obj) {
-		if strictNullHandling {
-			if encoder != nil && !encodeValuesOnly {
-				return []string{formatter(encoder(prefix, charset, "key", format))}, nil
+		if st.strictNullHandling {
+			if encoder != nil && !st.encodeValuesOnly {
+				return []string{st.formatter(encoder(prefix, st.charset, "key", st.format))}, nil
 			}
-			return []string{formatter(prefix)}, nil
+			return []string{st.formatter(prefix)}, nil
 		}
 		obj = ""
 	}
 
+	// Apply bool formats
+	if b, ok := obj.(bool); ok && st.boolFormatFor != nil {
+		f := st.boolFormatFor(prefix)
+		if f == BoolFlag {
+			if !b {
+				return []string{}, nil
+			}
+			if encoder != nil && !st.encodeValuesOnly {
+				return []string{st.formatter(encoder(prefix, st.charset, "key", st.format))}, nil
+			}
+			return []string{st.formatter(prefix)}, nil
+		}
+		if b {
+			obj = boolFormatValues[f][0]
+		} else {
+			obj = boolFormatValues[f][1]
+		}
+	}
+
 	// Handle primitives
 	if isNonNullishPrimitive(obj) {
 		if encoder != nil {
 			var keyValue string
-			if encodeValuesOnly {
+			if st.encodeValuesOnly {
 				keyValue = prefix
 			} else {
-				keyValue = encoder(prefix, charset, "key", format)
+				keyValue = encoder(prefix, st.charset, "key", st.format)
 			}
-			valStr := toString(obj)
-			return []string{formatter(keyValue) + "=" + formatter(encoder(valStr, charset, "value", format))}, nil
+			valStr := st.formatter(encoder(toString(obj), st.charset, "value", st.format))
+			if st.maxValueLength > 0 && len(valStr) > st.maxValueLength {
+				return nil, valueTooLongError(prefix, len(valStr), st.maxValueLength)
+			}
+			return []string{st.formatter(keyValue) + st.pairSeparator + valStr}, nil
+		}
+		valStr := st.formatter(toString(obj))
+		if st.maxValueLength > 0 && len(valStr) > st.maxValueLength {
+			return nil, valueTooLongError(prefix, len(valStr), st.maxValueLength)
+		}
+		if err := checkUnencodedValue(prefix, valStr, st.unsafeDelimiter); err != nil {
+			return nil, err
 		}
-		return []string{formatter(prefix) + "=" + formatter(toString(obj))}, nil
+		return []string{st.formatter(prefix) + st.pairSeparator + valStr}, nil
 	}
 
 	var values []string
@@ -611,21 +1367,21 @@ func stringify(
 	// Handle objects and arrays
 	var objKeys []any
 
-	if generateArrayPrefix == nil && isSlice(obj) {
+	if arrays.generateArrayPrefix == nil && isSlice(obj) {
 		// Comma format - join elements
 		slice := toSlice(obj)
-		if encodeValuesOnly && encoder != nil {
+		if st.encodeValuesOnly && encoder != nil {
 			// Encode each element
 			encodedSlice := make([]string, len(slice))
 			for i, v := range slice {
 				if s, ok := v.(string); ok {
-					encodedSlice[i] = encoder(s, charset, "value", format)
+					encodedSlice[i] = encoder(s, st.charset, "value", st.format)
 				} else {
 					encodedSlice[i] = toString(v)
 				}
 			}
 			if len(encodedSlice) > 0 {
-				joined := strings.Join(encodedSlice, ",")
+				joined := strings.Join(encodedSlice, st.commaDelimiter)
 				// JS: obj.join(',') || null - if result is empty string, use null
 				// Use ExplicitNullValue (not nil) so it's not skipped as sparse array
 				if joined == "" {
@@ -640,7 +1396,7 @@ func stringify(
 				for i, v := range slice {
 					strSlice[i] = toString(v)
 				}
-				joined := strings.Join(strSlice, ",")
+				joined := strings.Join(strSlice, st.commaDelimiter)
 				// JS: obj.join(',') || null - if result is empty string, use null
 				// Use ExplicitNullValue (not nil) so it's not skipped as sparse array
 				if joined == "" {
@@ -650,7 +1406,7 @@ func stringify(
 				}
 			}
 		}
-	} else if filterSlice, ok := filter.([]string); ok {
+	} else if filterSlice, ok := st.filter.([]string); ok {
 		// Filter is array of keys - applies to both arrays and objects
 		// For arrays: filter acts as array of indices (strings that can convert to int)
 		// For objects: filter acts as array of keys
@@ -662,26 +1418,25 @@ func stringify(
 		// No filter array - get keys from object
 		switch v := obj.(type) {
 		case map[string]any:
-			keys := make([]string, 0, len(v))
-			for k := range v {
-				keys = append(keys, k)
-			}
-			if sort != nil {
-				sortStrings(keys, sort)
+			keys := orderedKeys(v, st.keyOrder)
+			if st.preserveNumericKeys && sortNumericKeys(keys) {
+				// Keys already in numeric order
+			} else if st.sort != nil {
+				sortStrings(keys, st.sort)
 			}
 			objKeys = make([]any, len(keys))
 			for i, k := range keys {
 				objKeys[i] = k
 			}
 		case []any:
-			if sortArrayIndices && sort != nil {
+			if st.sortArrayIndices && st.sort != nil {
 				// Convert indices to strings and sort them lexicographically
 				// This matches JS qs behavior where sort applies to all keys including array indices
 				keys := make([]string, len(v))
 				for i := range v {
 					keys[i] = strconv.Itoa(i)
 				}
-				sortStrings(keys, sort)
+				sortStrings(keys, st.sort)
 				objKeys = make([]any, len(keys))
 				for i, k := range keys {
 					objKeys[i] = k // Keep as string for sorted order
@@ -696,23 +1451,35 @@ func stringify(
 		}
 	}
 
-	// Encode prefix dots if needed
+	// Encode dots in the root key. Nested prefixes already hold encoded
+	// keys, and their dots are separators from allowDots.
 	encodedPrefix := prefix
-	if encodeDotInKeys {
+	if st.encodeDotInKeys && step == 0 {
 		encodedPrefix = strings.ReplaceAll(prefix, ".", "%2E")
 	}
 
 	// Handle commaRoundTrip for single element arrays
 	adjustedPrefix := encodedPrefix
+	commaRoundTrip := arrays.commaRoundTrip || (explicitArray && arrays.generateArrayPrefix == nil)
 	if commaRoundTrip && isSlice(obj) && len(toSlice(obj)) == 1 {
 		adjustedPrefix = encodedPrefix + "[]"
 	}
 
 	// Handle empty arrays
-	if allowEmptyArrays && isSlice(obj) && len(toSlice(obj)) == 0 {
+	if (st.allowEmptyArrays || explicitArray) && isSlice(obj) && len(toSlice(obj)) == 0 {
 		return []string{adjustedPrefix + "[]"}, nil
 	}
 
+	// Dense arrays of primitives need no explicit indices
+	compact := arrays.compactIndices && st.filter == nil && !(st.sortArrayIndices && st.sort != nil) &&
+		isSlice(obj) && isCompactArray(toSlice(obj), st.skipNulls, st.boolFormatFor != nil)
+
+	// A key holding true is written last as a bare value, see BoolKeyAsBareValue
+	bareKey, hasBareKey := "", false
+	if m, ok := obj.(map[string]any); ok && st.boolKeyAsBareValue && st.filter == nil {
+		bareKey, hasBareKey = bareValueKey(m, st.sort)
+	}
+
 	// Iterate over keys
 	for _, key := range objKeys {
 		var value any
@@ -754,12 +1521,17 @@ func stringify(
 			continue
 		}
 		// Skip nulls if skipNulls is requested
-		if skipNulls && (value == nil || IsExplicitNull(value)) {
+		if st.skipNulls && (value == nil || IsExplicitNull(value)) {
+			continue
+		}
+
+		if hasBareKey && keyStr == bareKey {
 			continue
 		}
 
 		// Generate key prefix
 		var keyPrefix string
+		childLevel := level
 
 		// Check if this is a comma format special case (keyStr="" for joined value)
 		if keyMap, ok := key.(map[string]any); ok && keyStr == "" {
@@ -772,18 +1544,32 @@ func stringify(
 		// If keyPrefix wasn't set by comma format handling, generate it normally
 		if keyPrefix == "" && key != nil {
 			encodedKey := keyStr
-			if allowDots && encodeDotInKeys {
+			if st.allowDots && st.encodeDotInKeys {
 				encodedKey = strings.ReplaceAll(keyStr, ".", "%2E")
 			}
 
 			if isSlice(obj) {
-				if generateArrayPrefix != nil {
-					keyPrefix = generateArrayPrefix(adjustedPrefix, encodedKey)
+				if st.levels != nil && arrays.generateArrayPrefix != nil {
+					if seg, ok := st.levels.arraySegment(encodedKey); ok {
+						keyPrefix = adjustedPrefix + st.levels.delimiter(level) + seg
+						childLevel++
+					} else {
+						keyPrefix = adjustedPrefix
+					}
+				} else if compact {
+					keyPrefix = adjustedPrefix + "[]"
+				} else if arrays.generateArrayPrefix != nil {
+					keyPrefix = arrays.generateArrayPrefix(adjustedPrefix, encodedKey)
 				} else {
 					keyPrefix = adjustedPrefix
 				}
 			} else {
-				if allowDots {
+				if st.levels != nil {
+					keyPrefix = adjustedPrefix + st.levels.delimiter(level) + encodedKey
+					childLevel++
+				} else if st.jsonPointer {
+					keyPrefix = adjustedPrefix + "/" + escapeJSONPointer(keyStr)
+				} else if st.allowDots {
 					keyPrefix = adjustedPrefix + "." + encodedKey
 				} else {
 					keyPrefix = adjustedPrefix + "[" + encodedKey + "]"
@@ -796,35 +1582,20 @@ func stringify(
 
 		// Determine encoder for recursive call
 		var childEncoder func(string, Charset, string, Format) string
-		if generateArrayPrefix == nil && encodeValuesOnly && isSlice(obj) {
+		if arrays.generateArrayPrefix == nil && st.encodeValuesOnly && isSlice(obj) {
 			childEncoder = nil
 		} else {
 			childEncoder = encoder
 		}
 
+		// Elements of an array switch to the nested array format
+		childArrays := arrays
+		if st.nested != nil && isSlice(obj) {
+			childArrays = *st.nested
+		}
+
 		// Recurse
-		childValues, err := stringify(
-			value,
-			keyPrefix,
-			generateArrayPrefix,
-			commaRoundTrip,
-			allowEmptyArrays,
-			strictNullHandling,
-			skipNulls,
-			encodeDotInKeys,
-			childEncoder,
-			filter,
-			sort,
-			sortArrayIndices,
-			allowDots,
-			serializeDate,
-			format,
-			formatter,
-			encodeValuesOnly,
-			charset,
-			childSc,
-			step+1,
-		)
+		childValues, err := st.stringify(value, keyPrefix, childArrays, childEncoder, childLevel, childSc, step+1)
 		if err != nil {
 			return nil, err
 		}
@@ -832,6 +1603,26 @@ func stringify(
 		values = append(values, childValues...)
 	}
 
+	if hasBareKey {
+		keyValue, valStr := prefix, bareKey
+		if encoder != nil {
+			if !st.encodeValuesOnly {
+				keyValue = encoder(prefix, st.charset, "key", st.format)
+			}
+			valStr = encoder(bareKey, st.charset, "value", st.format)
+		}
+		valStr = st.formatter(valStr)
+		if st.maxValueLength > 0 && len(valStr) > st.maxValueLength {
+			return nil, valueTooLongError(prefix, len(valStr), st.maxValueLength)
+		}
+		if encoder == nil {
+			if err := checkUnencodedValue(prefix, valStr, st.unsafeDelimiter); err != nil {
+				return nil, err
+			}
+		}
+		values = append(values, st.formatter(keyValue)+st.pairSeparator+valStr)
+	}
+
 	return values, nil
 }
 
@@ -848,6 +1639,13 @@ func stringify(
 //	str, err := qs.Stringify(map[string]any{"a": []any{"b", "c"}})
 //	// str = "a%5B0%5D=b&a%5B1%5D=c"  (a[0]=b&a[1]=c URL encoded)
 func Stringify(obj any, opts ...StringifyOption) (string, error) {
+	return stringifyInOrder(obj, nil, opts...)
+}
+
+// stringifyInOrder is Stringify writing the keys of each map in the order
+// keyOrder returns for it, unless Sort is set. Maps for which keyOrder
+// returns nil, or all maps if keyOrder is nil, have no set order.
+func stringifyInOrder(obj any, keyOrder func(map[string]any) []string, opts ...StringifyOption) (string, error) {
 	options := applyStringifyOptions(opts...)
 
 	// Normalize options
@@ -855,7 +1653,206 @@ func Stringify(obj any, opts ...StringifyOption) (string, error) {
 	if err != nil {
 		return "", err
 	}
+	return stringifyNormalized(obj, keyOrder, &normalizedOpts)
+}
+
+// stringifyNormalized is stringifyInOrder using already normalized options.
+func stringifyNormalized(obj any, keyOrder func(map[string]any) []string, opts *StringifyOptions) (string, error) {
+	normalizedOpts := *opts
+
+	var keys []string
+	err := walkPairs(obj, keyOrder, &normalizedOpts, func(pairs []string) error {
+		keys = append(keys, pairs...)
+		return nil
+	})
+	if err != nil {
+		return "", err
+	}
+
+	if normalizedOpts.SortPairs != nil {
+		if usesIndexedRepeat(&normalizedOpts) {
+			sortPairsByKey(keys, normalizedOpts.SortPairs)
+		} else {
+			sortStrings(keys, normalizedOpts.SortPairs)
+		}
+	}
+	if normalizedOpts.ASCIIOnly {
+		for i, pair := range keys {
+			keys[i] = escapeNonASCII(pair, normalizedOpts.Charset)
+		}
+	}
+
+	joined := strings.Join(keys, normalizedOpts.Delimiter)
+	prefix := queryPrefix(&normalizedOpts)
+
+	// Append the checksum over everything after "?". Without pairs the
+	// sentinel is omitted as usual, so the content is empty.
+	if normalizedOpts.ChecksumFunc != nil {
+		sentinel := strings.TrimPrefix(prefix, "?")
+		content := ""
+		if len(joined) > 0 {
+			content = sentinel + joined
+		}
+		name, sum := normalizedOpts.ChecksumName, normalizedOpts.ChecksumFunc([]byte(content))
+		if normalizedOpts.Encode {
+			name = Encode(name, normalizedOpts.Charset, normalizedOpts.Format)
+			sum = Encode(sum, normalizedOpts.Charset, normalizedOpts.Format)
+		}
+		if normalizedOpts.ASCIIOnly {
+			name = escapeNonASCII(name, normalizedOpts.Charset)
+			sum = escapeNonASCII(sum, normalizedOpts.Charset)
+		}
+		pairSeparator := pairSeparatorFor(&normalizedOpts)
+		if len(joined) > 0 {
+			joined += normalizedOpts.Delimiter + name + pairSeparator + sum
+		} else {
+			prefix = strings.TrimSuffix(prefix, sentinel)
+			joined = name + pairSeparator + sum
+		}
+	}
+
+	if len(joined) > 0 {
+		return prefix + joined, nil
+	}
+	return "", nil
+}
+
+// StringifyTo is Stringify writing the result to w. Instead of building
+// the whole string, it writes the pairs of each top-level key as soon as
+// that key is serialized, so memory use is bounded by the largest
+// top-level value; Sort still applies at every level. With SortPairs or
+// ChecksumName and ChecksumFunc, which need all pairs, the result is
+// built in memory first.
+//
+// If an error such as ErrCyclicReference occurs after some pairs were
+// written, w holds that partial output. Errors from w are returned as is.
+//
+// Example:
+//
+//	err := qs.StringifyTo(w, map[string]any{"a": "b", "c": "d"})
+//	// w receives "a=b&c=d"
+func StringifyTo(w io.Writer, obj map[string]any, opts ...StringifyOption) error {
+	options := applyStringifyOptions(opts...)
 
+	// Normalize options
+	normalizedOpts, err := normalizeStringifyOptions(&options)
+	if err != nil {
+		return err
+	}
+	return writeTo(w, obj, &normalizedOpts)
+}
+
+// writeTo writes the Stringify output for obj to w, see StringifyTo.
+func writeTo(w io.Writer, obj any, opts *StringifyOptions) error {
+	return writePairs(obj, opts, func(s string) error {
+		_, err := io.WriteString(w, s)
+		return err
+	})
+}
+
+// StringifyBytes is Stringify appending the result to dst and returning
+// the extended buffer, so a buffer can be reused across calls. Like
+// StringifyTo it does not build the whole string first, except with
+// SortPairs or a checksum.
+//
+// Example:
+//
+//	buf, err = qs.StringifyBytes(buf[:0], map[string]any{"a": "b"})
+//	// buf = []byte("a=b")
+func StringifyBytes(dst []byte, obj any, opts ...StringifyOption) ([]byte, error) {
+	options := applyStringifyOptions(opts...)
+
+	// Normalize options
+	normalizedOpts, err := normalizeStringifyOptions(&options)
+	if err != nil {
+		return dst, err
+	}
+	return appendPairs(dst, obj, &normalizedOpts)
+}
+
+// appendPairs appends the Stringify output for obj to dst, see
+// StringifyBytes.
+func appendPairs(dst []byte, obj any, opts *StringifyOptions) ([]byte, error) {
+	err := writePairs(obj, opts, func(s string) error {
+		dst = append(dst, s...)
+		return nil
+	})
+	return dst, err
+}
+
+// writePairs passes the Stringify output for obj to write in pieces, as
+// each top-level key is serialized, or as a whole with SortPairs or a
+// checksum.
+func writePairs(obj any, normalizedOpts *StringifyOptions, write func(s string) error) error {
+	if normalizedOpts.SortPairs != nil || normalizedOpts.ChecksumFunc != nil {
+		str, err := stringifyNormalized(obj, nil, normalizedOpts)
+		if err != nil || str == "" {
+			return err
+		}
+		return write(str)
+	}
+
+	// The prefix and sentinel are only written once there is a pair
+	sep := queryPrefix(normalizedOpts)
+	return walkPairs(obj, nil, normalizedOpts, func(pairs []string) error {
+		for _, pair := range pairs {
+			if normalizedOpts.ASCIIOnly {
+				pair = escapeNonASCII(pair, normalizedOpts.Charset)
+			}
+			if sep != "" {
+				if err := write(sep); err != nil {
+					return err
+				}
+			}
+			if err := write(pair); err != nil {
+				return err
+			}
+			sep = normalizedOpts.Delimiter
+		}
+		return nil
+	})
+}
+
+// queryPrefix returns what is written before the first pair: "?" with
+// AddQueryPrefix, followed by the charset sentinel pair and a delimiter.
+func queryPrefix(opts *StringifyOptions) string {
+	prefix := ""
+
+	if opts.AddQueryPrefix {
+		prefix = "?"
+	}
+
+	// Add charset sentinel
+	if opts.CharsetSentinel {
+		if opts.Charset == CharsetISO88591 {
+			// encodeURIComponent('&#10003;'), the "numeric entity" representation of a checkmark
+			prefix += "utf8=%26%2310003%3B&"
+		} else {
+			// encodeURIComponent('✓')
+			prefix += "utf8=%E2%9C%93&"
+		}
+	}
+	return prefix
+}
+
+// usesIndexedRepeat reports whether IndexedRepeat applies to the array
+// formats in opts.
+func usesIndexedRepeat(opts *StringifyOptions) bool {
+	return opts.IndexedRepeat &&
+		(opts.ArrayFormat == ArrayFormatRepeat || opts.NestedArrayFormat == ArrayFormatRepeat)
+}
+
+// pairSeparatorFor returns the separator written between keys and values.
+func pairSeparatorFor(opts *StringifyOptions) string {
+	if opts.PairSpacing && !opts.Encode {
+		return " = "
+	}
+	return "="
+}
+
+// walkPairs serializes obj with normalized options, calling emit with the
+// pairs of each top-level key in order. Errors from emit stop the walk.
+func walkPairs(obj any, keyOrder func(map[string]any) []string, normalizedOpts *StringifyOptions, emit func(pairs []string) error) error {
 	var filter any = normalizedOpts.Filter
 	var objKeys []string
 
@@ -870,29 +1867,76 @@ func Stringify(obj any, opts ...StringifyOption) (string, error) {
 
 	// Handle non-object input
 	if obj == nil {
-		return "", nil
+		return nil
 	}
-	objMap, isMap := obj.(map[string]any)
+	objMap, isMap := unwrapContainer(obj).(map[string]any)
 	if !isMap {
-		return "", nil
+		return nil
 	}
 
 	// Get array prefix generator
 	generateArrayPrefix := arrayPrefixGenerators[normalizedOpts.ArrayFormat]
 	commaRoundTrip := generateArrayPrefix == nil && normalizedOpts.CommaRoundTrip
+	jsonPointer := normalizedOpts.ArrayFormat == ArrayFormatJSONPointer
+
+	compactIndices := normalizedOpts.CompactIndices && normalizedOpts.ArrayFormat == ArrayFormatIndices
+	indexedRepeat := usesIndexedRepeat(normalizedOpts)
+	pairSeparator := pairSeparatorFor(normalizedOpts)
+
+	// Only checked when values are written unencoded, see StrictUnencoded
+	var unsafeDelimiter string
+	if normalizedOpts.StrictUnencoded && !normalizedOpts.Encode {
+		unsafeDelimiter = normalizedOpts.Delimiter
+	}
+
+	var nested *arraySettings
+	if normalizedOpts.NestedArrayFormat != "" && !jsonPointer && len(normalizedOpts.LevelDelimiters) == 0 {
+		nestedPrefix := arrayPrefixGenerators[normalizedOpts.NestedArrayFormat]
+		nested = &arraySettings{
+			generateArrayPrefix: nestedPrefix,
+			commaRoundTrip:      nestedPrefix == nil && normalizedOpts.CommaRoundTrip,
+			compactIndices:      normalizedOpts.CompactIndices && normalizedOpts.NestedArrayFormat == ArrayFormatIndices,
+		}
+	}
+
+	// Join key segments with level delimiters instead of brackets or dots
+	var levels *keyLevels
+	if len(normalizedOpts.LevelDelimiters) > 0 && !jsonPointer {
+		levels = &keyLevels{delimiters: normalizedOpts.LevelDelimiters}
+		switch normalizedOpts.ArrayFormat {
+		case ArrayFormatBrackets:
+			levels.arraySegment = func(string) (string, bool) { return "", true }
+		case ArrayFormatRepeat:
+			levels.arraySegment = func(string) (string, bool) { return "", false }
+		default:
+			levels.arraySegment = func(key string) (string, bool) { return key, true }
+		}
+	}
+
+	// Resolve bool formats per key; nil keeps the default true/false
+	var boolFormatFor func(key string) BoolFormat
+	if normalizedOpts.BoolFormat != BoolTrueFalse || len(normalizedOpts.BoolKeys) > 0 {
+		boolFormat, boolKeys := normalizedOpts.BoolFormat, normalizedOpts.BoolKeys
+		boolFormatFor = func(key string) BoolFormat {
+			if f, ok := boolKeys[key]; ok {
+				return f
+			}
+			return boolFormat
+		}
+	}
 
 	// Get keys if not filtered
 	if objKeys == nil {
-		objKeys = make([]string, 0, len(objMap))
-		for k := range objMap {
-			objKeys = append(objKeys, k)
-		}
+		objKeys = orderedKeys(objMap, keyOrder)
 	}
 
 	// Sort keys if requested
 	if normalizedOpts.Sort != nil {
 		sortStrings(objKeys, normalizedOpts.Sort)
 	}
+	if len(normalizedOpts.KeyPriority) > 0 {
+		prioritizeKeys(objKeys, normalizedOpts.KeyPriority)
+	}
 
 	// Set up encoder
 	var encoder func(string, Charset, string, Format) string
@@ -900,16 +1944,96 @@ func Stringify(obj any, opts ...StringifyOption) (string, error) {
 		if normalizedOpts.Encoder != nil {
 			encoder = normalizedOpts.Encoder
 		} else {
+			safeChar := normalizedOpts.SafeChar
+			keySafeChar := safeChar
+			if rawKeyChars := normalizedOpts.RawKeyChars; rawKeyChars != "" {
+				format := normalizedOpts.Format
+				keySafeChar = func(r rune) bool {
+					if strings.ContainsRune(rawKeyChars, r) {
+						return true
+					}
+					if safeChar != nil {
+						return safeChar(r)
+					}
+					return r < utf8.RuneSelf && isUnreservedChar(byte(r), format)
+				}
+			}
 			encoder = func(str string, charset Charset, kind string, format Format) string {
-				return Encode(str, charset, format)
+				if kind == "key" {
+					return EncodeSafe(str, charset, format, keySafeChar)
+				}
+				return EncodeSafe(str, charset, format, safeChar)
 			}
 		}
+	} else if normalizedOpts.EscapePercentOnly || normalizedOpts.QuoteValues != QuoteNever {
+		escapePercent := normalizedOpts.EscapePercentOnly
+		quoteMode := normalizedOpts.QuoteValues
+		quote := string(normalizedOpts.QuoteChar)
+		delimiter := normalizedOpts.Delimiter
+		encoder = func(str string, charset Charset, kind string, format Format) string {
+			if kind != "value" {
+				if escapePercent && kind == "key" {
+					return escapeBarePercent(str)
+				}
+				return str
+			}
+			if escapePercent {
+				str = escapeBarePercent(str)
+			}
+			if quoteMode == QuoteAlways ||
+				(quoteMode == QuoteWhenNeeded && (strings.Contains(str, delimiter) || strings.Contains(str, "="))) {
+				str = quote + str + quote
+			}
+			return str
+		}
 	}
 
 	// Initialize side channel for cycle detection
 	sideChannel := newSideChannel()
 
-	var keys []string
+	var pathFilter *regexp.Regexp
+	if normalizedOpts.FilterRegexpPath {
+		pathFilter = normalizedOpts.FilterRegexp
+	}
+
+	arrays := arraySettings{
+		generateArrayPrefix: generateArrayPrefix,
+		commaRoundTrip:      commaRoundTrip,
+		compactIndices:      compactIndices,
+	}
+	st := &stringifyState{
+		commaDelimiter:        normalizedOpts.CommaDelimiter,
+		nested:                nested,
+		preserveSparseIndices: normalizedOpts.PreserveSparseIndices,
+		allowEmptyArrays:      normalizedOpts.AllowEmptyArrays,
+		nilSlices:             normalizedOpts.NilSlices,
+		strictNullHandling:    normalizedOpts.StrictNullHandling,
+		skipNulls:             normalizedOpts.SkipNulls,
+		encodeDotInKeys:       normalizedOpts.EncodeDotInKeys,
+		filter:                filter,
+		pathFilter:            pathFilter,
+		duplicateReducer:      normalizedOpts.DuplicateReducer,
+		sort:                  normalizedOpts.Sort,
+		keyOrder:              keyOrder,
+		elementSort:           normalizedOpts.ArrayElementSort,
+		sortArrayIndices:      normalizedOpts.SortArrayIndices && !indexedRepeat,
+		preserveNumericKeys:   normalizedOpts.PreserveNumericKeys,
+		allowDots:             normalizedOpts.AllowDots,
+		jsonPointer:           jsonPointer,
+		levels:                levels,
+		boolFormatFor:         boolFormatFor,
+		boolKeyAsBareValue:    normalizedOpts.BoolKeyAsBareValue,
+		pairSeparator:         pairSeparator,
+		maxValueLength:        normalizedOpts.MaxValueLength,
+		unsafeDelimiter:       unsafeDelimiter,
+		serializeDate:         normalizedOpts.SerializeDate,
+		integerFormatter:      normalizedOpts.IntegerFormatter,
+		format:                normalizedOpts.Format,
+		formatter:             normalizedOpts.Formatter,
+		encodeValuesOnly:      normalizedOpts.EncodeValuesOnly,
+		charset:               normalizedOpts.Charset,
+	}
+
 	for _, key := range objKeys {
 		value, exists := objMap[key]
 
@@ -918,65 +2042,194 @@ func Stringify(obj any, opts ...StringifyOption) (string, error) {
 			continue
 		}
 
+		// Skip keys not matching FilterRegexp
+		if re := normalizedOpts.FilterRegexp; re != nil && pathFilter == nil && !re.MatchString(key) {
+			continue
+		}
+
 		// Skip nulls if requested
 		if normalizedOpts.SkipNulls && (value == nil || IsExplicitNull(value)) {
 			continue
 		}
 
-		keyValues, err := stringify(
-			value,
-			key,
-			generateArrayPrefix,
-			commaRoundTrip,
-			normalizedOpts.AllowEmptyArrays,
-			normalizedOpts.StrictNullHandling,
-			normalizedOpts.SkipNulls,
-			normalizedOpts.EncodeDotInKeys,
-			encoder,
-			filter,
-			normalizedOpts.Sort,
-			normalizedOpts.SortArrayIndices,
-			normalizedOpts.AllowDots,
-			normalizedOpts.SerializeDate,
-			normalizedOpts.Format,
-			normalizedOpts.Formatter,
-			normalizedOpts.EncodeValuesOnly,
-			normalizedOpts.Charset,
-			sideChannel,
-			0,
-		)
+		rootKey := key
+		if jsonPointer {
+			rootKey = "/" + escapeJSONPointer(key)
+		}
+
+		keyValues, err := st.stringify(value, rootKey, arrays, encoder, 0, sideChannel, 0)
 		if err != nil {
-			return "", err
+			return err
+		}
+		if len(keyValues) > 0 {
+			if err := emit(keyValues); err != nil {
+				return err
+			}
 		}
+	}
+	return nil
+}
 
-		keys = append(keys, keyValues...)
+// StringifySlice converts a top-level slice into a URL query string.
+// Query strings need key names, so the slice is serialized under key,
+// which must not be empty. All Stringify options apply.
+//
+// Example:
+//
+//	str, err := qs.StringifySlice("ids", []any{"1", "2"}, qs.WithStringifyEncode(false))
+//	// str = "ids[0]=1&ids[1]=2"
+func StringifySlice(key string, s []any, opts ...StringifyOption) (string, error) {
+	if key == "" {
+		return "", ErrEmptyRootKey
 	}
+	return Stringify(map[string]any{key: s}, opts...)
+}
 
-	joined := strings.Join(keys, normalizedOpts.Delimiter)
-	prefix := ""
+// StringifyJSON encodes m as a JSON object and percent-encodes the result
+// as a single value without a key, for APIs that take the whole payload
+// as JSON in one parameter, as in "?data=<json>". Map keys are written
+// in sorted order, ExplicitNullValue as null, and a nil map as {}.
+//
+// Example:
+//
+//	str, err := qs.StringifyJSON(map[string]any{"a": map[string]any{"b": 1}})
+//	// str = "%7B%22a%22%3A%7B%22b%22%3A1%7D%7D"  ({"a":{"b":1}} URL encoded)
+func StringifyJSON(m map[string]any) (string, error) {
+	if m == nil {
+		m = map[string]any{}
+	}
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	enc.SetEscapeHTML(false)
+	if err := enc.Encode(m); err != nil {
+		return "", err
+	}
+	data := strings.TrimSuffix(buf.String(), "\n")
+	return Encode(data, CharsetUTF8, FormatRFC3986), nil
+}
 
-	if normalizedOpts.AddQueryPrefix {
-		prefix = "?"
+// StringifyJSONUnder is StringifyJSON writing the JSON value under key,
+// which must not be empty.
+//
+// Example:
+//
+//	str, err := qs.StringifyJSONUnder("data", map[string]any{"a": "b"})
+//	// str = "data=%7B%22a%22%3A%22b%22%7D"
+func StringifyJSONUnder(key string, m map[string]any) (string, error) {
+	if key == "" {
+		return "", ErrEmptyRootKey
 	}
+	value, err := StringifyJSON(m)
+	if err != nil {
+		return "", err
+	}
+	return Encode(key, CharsetUTF8, FormatRFC3986) + "=" + value, nil
+}
 
-	// Add charset sentinel
-	if normalizedOpts.CharsetSentinel {
-		if normalizedOpts.Charset == CharsetISO88591 {
-			// encodeURIComponent('&#10003;'), the "numeric entity" representation of a checkmark
-			prefix += "utf8=%26%2310003%3B&"
-		} else {
-			// encodeURIComponent('✓')
-			prefix += "utf8=%E2%9C%93&"
+// Helper functions
+
+// prioritizeKeys moves the keys listed in priority to the front of keys,
+// in priority order, keeping the relative order of the rest.
+func prioritizeKeys(keys []string, priority []string) {
+	rank := make(map[string]int, len(priority))
+	for i, k := range priority {
+		if _, ok := rank[k]; !ok {
+			rank[k] = i
 		}
 	}
+	sortStrings(keys, func(a, b string) bool {
+		ra, okA := rank[a]
+		rb, okB := rank[b]
+		return okA && (!okB || ra < rb)
+	})
+}
 
-	if len(joined) > 0 {
-		return prefix + joined, nil
+// sortNumericKeys sorts keys in ascending numeric order if every key is a
+// canonical non-negative integer, and reports whether it did.
+func sortNumericKeys(keys []string) bool {
+	if len(keys) == 0 {
+		return false
 	}
-	return "", nil
+	nums := make(map[string]int, len(keys))
+	for _, k := range keys {
+		n, err := strconv.Atoi(k)
+		if err != nil || n < 0 || strconv.Itoa(n) != k {
+			return false
+		}
+		nums[k] = n
+	}
+	sortStrings(keys, func(a, b string) bool { return nums[a] < nums[b] })
+	return true
 }
 
-// Helper functions
+// valueTooLongError returns the error for a value of n bytes at key
+// exceeding limit.
+func valueTooLongError(key string, n, limit int) error {
+	return fmt.Errorf("%w: %q is %d bytes (limit %d)", ErrValueTooLong, key, n, limit)
+}
+
+// checkUnencodedValue returns an error wrapping ErrUnsafeValue if the
+// value at key contains delimiter or "=". An empty delimiter disables the
+// check.
+func checkUnencodedValue(key, value, delimiter string) error {
+	if delimiter == "" {
+		return nil
+	}
+	for _, sep := range []string{delimiter, "="} {
+		if strings.Contains(value, sep) {
+			return fmt.Errorf("%w: %q contains %q", ErrUnsafeValue, key, sep)
+		}
+	}
+	return nil
+}
+
+// closeGaps returns arr without its nil slots, and without null values
+// if skipNulls is set. It returns arr itself if it has no gaps.
+func closeGaps(arr []any, skipNulls bool) []any {
+	isGap := func(v any) bool {
+		return v == nil || (skipNulls && IsExplicitNull(v))
+	}
+	i := 0
+	for i < len(arr) && !isGap(arr[i]) {
+		i++
+	}
+	if i == len(arr) {
+		return arr
+	}
+	closed := append(make([]any, 0, len(arr)-1), arr[:i]...)
+	for _, v := range arr[i+1:] {
+		if !isGap(v) {
+			closed = append(closed, v)
+		}
+	}
+	return closed
+}
+
+// isCompactArray reports whether every element of arr is written as a
+// primitive, so that empty brackets parse back to the same array. Nulls
+// leave a gap with skipNulls, and bools may be omitted by BoolFlag.
+func isCompactArray(arr []any, skipNulls bool, boolFormats bool) bool {
+	for _, v := range arr {
+		switch v.(type) {
+		case nil:
+			return false
+		case bool:
+			if boolFormats {
+				return false
+			}
+		case time.Time:
+		default:
+			if IsExplicitNull(v) {
+				if skipNulls {
+					return false
+				}
+			} else if !isNonNullishPrimitive(v) {
+				return false
+			}
+		}
+	}
+	return true
+}
 
 // isSlice checks if a value is a slice.
 func isSlice(v any) bool {
@@ -1033,10 +2286,150 @@ func toString(v any) string {
 		}
 		return "false"
 	default:
+		if s, ok := stringerValue(v); ok {
+			return s
+		}
 		return ""
 	}
 }
 
+// formatInteger formats v with fn if v is an integer that fits in an int64.
+func formatInteger(v any, fn func(int64) string) (string, bool) {
+	if fn == nil {
+		return "", false
+	}
+	switch val := v.(type) {
+	case int:
+		return fn(int64(val)), true
+	case int64:
+		return fn(val), true
+	case int32:
+		return fn(int64(val)), true
+	case int16:
+		return fn(int64(val)), true
+	case int8:
+		return fn(int64(val)), true
+	case uint:
+		if uint64(val) <= math.MaxInt64 {
+			return fn(int64(val)), true
+		}
+	case uint64:
+		if val <= math.MaxInt64 {
+			return fn(int64(val)), true
+		}
+	case uint32:
+		return fn(int64(val)), true
+	case uint16:
+		return fn(int64(val)), true
+	case uint8:
+		return fn(int64(val)), true
+	}
+	return "", false
+}
+
+// isValidBoolFormat reports whether f is a known BoolFormat.
+func isValidBoolFormat(f BoolFormat) bool {
+	_, ok := boolFormatValues[f]
+	return ok || f == BoolFlag
+}
+
+// orderedKeys returns the keys of m, in the order keyOrder gives if it
+// lists exactly the keys of m.
+func orderedKeys(m map[string]any, keyOrder func(map[string]any) []string) []string {
+	if keyOrder != nil {
+		if order := keyOrder(m); order != nil && len(order) == len(m) && hasKeys(m, order) {
+			return append([]string(nil), order...)
+		}
+	}
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	return keys
+}
+
+// hasChildren reports whether v is a container whose entries are written
+// under their own keys: a non-empty map, or a non-empty slice unless comma
+// joins its elements under one key.
+func hasChildren(v any, comma bool) bool {
+	switch t := v.(type) {
+	case map[string]any:
+		return len(t) > 0
+	case []any:
+		return len(t) > 0 && !comma
+	}
+	if isSlice(v) {
+		return len(toSlice(v)) > 0 && !comma
+	}
+	return false
+}
+
+// hasKeys reports whether m holds all of keys.
+func hasKeys(m map[string]any, keys []string) bool {
+	for _, k := range keys {
+		if _, ok := m[k]; !ok {
+			return false
+		}
+	}
+	return true
+}
+
+// unwrapContainer converts Array and Object to their plain container
+// types, and a []map[string]any to a []any holding its maps.
+func unwrapContainer(v any) any {
+	switch t := v.(type) {
+	case Array:
+		return []any(t)
+	case Object:
+		return map[string]any(t)
+	case []map[string]any:
+		if t == nil {
+			return []any(nil)
+		}
+		arr := make([]any, len(t))
+		for i, m := range t {
+			arr[i] = m
+		}
+		return arr
+	}
+	return v
+}
+
+// bareValueKey returns the key of m that BoolKeyAsBareValue writes as a
+// bare value: of the keys holding true, the first in sort order, or in
+// byte order if sort is nil. It returns false if no key holds true or all
+// keys do.
+func bareValueKey(m map[string]any, sort SortFunc) (string, bool) {
+	var key string
+	found, others := false, false
+	for k, v := range m {
+		if b, ok := v.(bool); !ok || !b {
+			others = true
+			continue
+		}
+		if !found || (sort != nil && sort(k, key)) || (sort == nil && k < key) {
+			key, found = k, true
+		}
+	}
+	return key, found && others
+}
+
+// stringerValue returns v.String() if v implements fmt.Stringer and is not
+// a primitive or a nil pointer.
+func stringerValue(v any) (string, bool) {
+	if isNonNullishPrimitive(v) {
+		return "", false
+	}
+	s, ok := v.(fmt.Stringer)
+	if !ok {
+		return "", false
+	}
+	if rv := reflect.ValueOf(v); rv.Kind() == reflect.Ptr && rv.IsNil() {
+		return "", false
+	}
+	return s.String(), true
+}
+
 // toInt converts a value to int.
 func toInt(v any) (int, bool) {
 	switch val := v.(type) {
@@ -1049,12 +2442,30 @@ func toInt(v any) (int, bool) {
 	return 0, false
 }
 
-// sortStrings sorts a slice of strings using a custom comparison function.
-func sortStrings(slice []string, less SortFunc) {
-	// Simple insertion sort for small arrays (typical case)
-	for i := 1; i < len(slice); i++ {
-		for j := i; j > 0 && less(slice[j], slice[j-1]); j-- {
-			slice[j], slice[j-1] = slice[j-1], slice[j]
+// sortMapElements returns a copy of arr sorted stably by less if all its
+// elements are maps, or arr itself otherwise.
+func sortMapElements(arr []any, less func(a, b map[string]any) bool) []any {
+	if len(arr) < 2 {
+		return arr
+	}
+	maps := make([]map[string]any, len(arr))
+	for i, v := range arr {
+		m, ok := unwrapContainer(v).(map[string]any)
+		if !ok {
+			return arr
 		}
+		maps[i] = m
 	}
+	sort.SliceStable(maps, func(i, j int) bool { return less(maps[i], maps[j]) })
+	sorted := make([]any, len(maps))
+	for i, m := range maps {
+		sorted[i] = m
+	}
+	return sorted
+}
+
+// sortStrings sorts a slice of strings using a custom comparison function.
+// The sort is stable, so strings that compare equal keep their order.
+func sortStrings(slice []string, less SortFunc) {
+	sort.SliceStable(slice, func(i, j int) bool { return less(slice[i], slice[j]) })
 }
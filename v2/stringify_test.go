@@ -7,6 +7,14 @@
 package qs
 
 import (
+	"errors"
+	"fmt"
+	"math"
+	"math/big"
+	"net"
+	"reflect"
+	"regexp"
+	"strconv"
 	"strings"
 	"testing"
 	"time"
@@ -781,6 +789,54 @@ func TestJSStringifyEncodeDotInKeysWithStringifyEncodeValuesOnly(t *testing.T) {
 	}
 }
 
+// TestStringifyEncodeDotInKeysDeep pins encodeDotInKeys beyond the depths
+// the JS tests cover: each key's dots are encoded once, and the dots
+// allowDots adds between keys never are.
+func TestStringifyEncodeDotInKeysDeep(t *testing.T) {
+	deep := map[string]any{"a.b": map[string]any{"c.d": map[string]any{"e.f": map[string]any{"g.h": "x.y"}}}}
+	withArrays := map[string]any{"a.b": []any{map[string]any{"c.d": []any{map[string]any{"e.f": "x"}}}}}
+
+	tests := []struct {
+		name     string
+		input    map[string]any
+		opts     []StringifyOption
+		expected string
+	}{
+		{"values only, 4 levels", deep, []StringifyOption{WithStringifyEncodeValuesOnly(true)}, "a%2Eb.c%2Ed.e%2Ef.g%2Eh=x.y"},
+		{"encoded, 4 levels", deep, nil, "a%252Eb.c%252Ed.e%252Ef.g%252Eh=x.y"},
+		{"values only, arrays", withArrays, []StringifyOption{WithStringifyEncodeValuesOnly(true)}, "a%2Eb[0].c%2Ed[0].e%2Ef=x"},
+		{"values only, brackets", deep, []StringifyOption{WithStringifyEncodeValuesOnly(true), WithStringifyAllowDots(false)}, "a%2Eb[c.d][e.f][g.h]=x.y"},
+		{"encoded, brackets", deep, []StringifyOption{WithStringifyAllowDots(false)}, "a%252Eb%5Bc.d%5D%5Be.f%5D%5Bg.h%5D=x.y"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := Stringify(tt.input, append(tt.opts, WithStringifyEncodeDotInKeys(true))...)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if result != tt.expected {
+				t.Errorf("expected %q, got %q", tt.expected, result)
+			}
+		})
+	}
+
+	// Encoded output parses back with decodeDotInKeys
+	for _, input := range []map[string]any{deep, withArrays} {
+		result, err := Stringify(input, WithStringifyEncodeDotInKeys(true))
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		parsed, err := Parse(result, WithParseDecodeDotInKeys(true))
+		if err != nil {
+			t.Fatalf("Parse() error = %v", err)
+		}
+		if !reflect.DeepEqual(parsed, input) {
+			t.Errorf("round trip of %q = %v, want %v", result, parsed, input)
+		}
+	}
+}
+
 // TestJSStringifyAddQueryPrefix tests adding query prefix
 func TestJSStringifyAddQueryPrefix(t *testing.T) {
 	result, err := Stringify(map[string]any{"a": "b"}, WithStringifyAddQueryPrefix(true))
@@ -3056,3 +3112,1472 @@ func TestSortArrayIndices(t *testing.T) {
 		t.Errorf("With SortArrayIndices:\nGot:      %s\nExpected: %s", resultString, expectedString)
 	}
 }
+
+func TestStringifyEscapePercentOnly(t *testing.T) {
+	tests := []struct {
+		name  string
+		input map[string]any
+		opts  []StringifyOption
+		want  string
+	}{
+		{"off by default", map[string]any{"a": "c,d%"}, []StringifyOption{WithStringifyEncode(false)}, "a=c,d%"},
+		{"trailing percent", map[string]any{"a": "c,d%"}, []StringifyOption{WithStringifyEncode(false), WithStringifyEscapePercentOnly(true)}, "a=c,d%25"},
+		{"incomplete escape", map[string]any{"a": "%2x%"}, []StringifyOption{WithStringifyEncode(false), WithStringifyEscapePercentOnly(true)}, "a=%252x%25"},
+		{"pre-encoded kept", map[string]any{"a": "b%20c"}, []StringifyOption{WithStringifyEncode(false), WithStringifyEscapePercentOnly(true)}, "a=b%20c"},
+		{"other chars raw", map[string]any{"a[b]": "c d&"}, []StringifyOption{WithStringifyEncode(false), WithStringifyEscapePercentOnly(true)}, "a[b]=c d&"},
+		{"keys escaped", map[string]any{"a%": map[string]any{"b%": "c%"}}, []StringifyOption{WithStringifyEncode(false), WithStringifyEscapePercentOnly(true)}, "a%25[b%25]=c%25"},
+		{"pre-encoded keys kept", map[string]any{"a%20b": "c"}, []StringifyOption{WithStringifyEncode(false), WithStringifyEscapePercentOnly(true)}, "a%20b=c"},
+		{"literal escape-like text is ambiguous", map[string]any{"a": "%41"}, []StringifyOption{WithStringifyEncode(false), WithStringifyEscapePercentOnly(true)}, "a=%41"},
+		{"comma array", map[string]any{"a": []any{"x%", "y"}}, []StringifyOption{WithStringifyEncode(false), WithStringifyEscapePercentOnly(true), WithStringifyArrayFormat(ArrayFormatComma)}, "a=x%25,y"},
+		{"no effect when encoding", map[string]any{"a": "c%"}, []StringifyOption{WithStringifyEscapePercentOnly(true)}, "a=c%25"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Stringify(tt.input, tt.opts...)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+type testColor int
+
+func (c testColor) String() string {
+	return [...]string{"red", "green"}[c]
+}
+
+type testPoint struct{ X, Y int }
+
+func (p *testPoint) String() string {
+	return strconv.Itoa(p.X) + ":" + strconv.Itoa(p.Y)
+}
+
+func TestStringifyStringer(t *testing.T) {
+	var nilPoint *testPoint
+
+	tests := []struct {
+		name  string
+		input map[string]any
+		opts  []StringifyOption
+		want  string
+	}{
+		{"net.IP", map[string]any{"ip": net.ParseIP("192.168.0.1")}, nil, "ip=192.168.0.1"},
+		{"big.Rat", map[string]any{"r": big.NewRat(3, 4)}, nil, "r=3%2F4"},
+		{"named primitive", map[string]any{"c": testColor(1)}, nil, "c=green"},
+		{"pointer receiver", map[string]any{"p": &testPoint{1, 2}}, nil, "p=1%3A2"},
+		{"nested", map[string]any{"a": map[string]any{"b": testColor(0)}}, []StringifyOption{WithStringifyEncode(false)}, "a[b]=red"},
+		{"array elements", map[string]any{"a": []any{testColor(0), testColor(1)}}, []StringifyOption{WithStringifyEncode(false)}, "a[0]=red&a[1]=green"},
+		{"comma array", map[string]any{"a": []any{testColor(0), testColor(1)}}, []StringifyOption{WithStringifyEncode(false), WithStringifyArrayFormat(ArrayFormatComma)}, "a=red,green"},
+		{"nil pointer skipped", map[string]any{"p": nilPoint}, nil, ""},
+		{"time uses SerializeDate", map[string]any{"t": time.Date(2024, 1, 2, 0, 0, 0, 0, time.UTC)}, []StringifyOption{WithStringifySerializeDate(func(t time.Time) string { return "d" })}, "t=d"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Stringify(tt.input, tt.opts...)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestStringifySafeCharFunc(t *testing.T) {
+	keepColon := func(r rune) bool {
+		return r == ':' || (r >= 'a' && r <= 'z')
+	}
+
+	tests := []struct {
+		name  string
+		input map[string]any
+		opts  []StringifyOption
+		want  string
+	}{
+		{"keys and values", map[string]any{"a:b": "c:d-e"}, []StringifyOption{WithStringifySafeCharFunc(keepColon)}, "a:b=c:d%2De"},
+		{"nested brackets encoded", map[string]any{"a": map[string]any{"b": "c"}}, []StringifyOption{WithStringifySafeCharFunc(keepColon)}, "a%5Bb%5D=c"},
+		{"brackets made safe", map[string]any{"a": map[string]any{"b": "c"}}, []StringifyOption{WithStringifySafeCharFunc(func(r rune) bool { return keepColon(r) || r == '[' || r == ']' })}, "a[b]=c"},
+		{"custom encoder wins", map[string]any{"a": "b:"}, []StringifyOption{WithStringifySafeCharFunc(keepColon), WithStringifyEncoder(func(str string, charset Charset, kind string, format Format) string { return "x" })}, "x=x"},
+		{"ignored without encoding", map[string]any{"a": "b-"}, []StringifyOption{WithStringifySafeCharFunc(keepColon), WithStringifyEncode(false)}, "a=b-"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Stringify(tt.input, tt.opts...)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestStringifySlice(t *testing.T) {
+	input := []any{"a", "b"}
+
+	tests := []struct {
+		format ArrayFormat
+		want   string
+	}{
+		{ArrayFormatIndices, "ids[0]=a&ids[1]=b"},
+		{ArrayFormatBrackets, "ids[]=a&ids[]=b"},
+		{ArrayFormatRepeat, "ids=a&ids=b"},
+		{ArrayFormatComma, "ids=a,b"},
+	}
+
+	for _, tt := range tests {
+		t.Run(string(tt.format), func(t *testing.T) {
+			got, err := StringifySlice("ids", input, WithStringifyArrayFormat(tt.format), WithStringifyEncode(false))
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+
+	t.Run("nested elements", func(t *testing.T) {
+		got, err := StringifySlice("a", []any{map[string]any{"b": "c"}, []any{"d"}})
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if want := "a%5B0%5D%5Bb%5D=c&a%5B1%5D%5B0%5D=d"; got != want {
+			t.Errorf("got %q, want %q", got, want)
+		}
+	})
+
+	t.Run("empty slice", func(t *testing.T) {
+		got, err := StringifySlice("a", []any{})
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if got != "" {
+			t.Errorf("got %q, want empty", got)
+		}
+	})
+
+	t.Run("empty key", func(t *testing.T) {
+		if _, err := StringifySlice("", input); err != ErrEmptyRootKey {
+			t.Errorf("got %v, want %v", err, ErrEmptyRootKey)
+		}
+	})
+}
+
+func TestStringifyJSONPointer(t *testing.T) {
+	tests := []struct {
+		name  string
+		input map[string]any
+		opts  []StringifyOption
+		want  string
+	}{
+		{"nested map and array", map[string]any{"a": map[string]any{"b": []any{"c"}}}, []StringifyOption{WithStringifyEncode(false)}, "/a/b/0=c"},
+		{"top-level scalar", map[string]any{"a": "b"}, []StringifyOption{WithStringifyEncode(false)}, "/a=b"},
+		{"array of maps", map[string]any{"a": []any{map[string]any{"b": "c"}, map[string]any{"b": "d"}}}, []StringifyOption{WithStringifyEncode(false)}, "/a/0/b=c&/a/1/b=d"},
+		{"escapes tilde and slash", map[string]any{"a/b": map[string]any{"c~d": "e"}}, []StringifyOption{WithStringifyEncode(false)}, "/a~1b/c~0d=e"},
+		{"ignores allow dots", map[string]any{"a": map[string]any{"b": "c"}}, []StringifyOption{WithStringifyEncode(false), WithStringifyAllowDots(true)}, "/a/b=c"},
+		{"encode values only", map[string]any{"a": map[string]any{"b": "c d"}}, []StringifyOption{WithStringifyEncodeValuesOnly(true)}, "/a/b=c%20d"},
+		{"encoded keys", map[string]any{"a": []any{"b"}}, nil, "%2Fa%2F0=b"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			opts := append([]StringifyOption{WithStringifyArrayFormat(ArrayFormatJSONPointer), WithStringifySort(func(a, b string) bool { return a < b })}, tt.opts...)
+			got, err := Stringify(tt.input, opts...)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestStringifyArrayObjectMarkers(t *testing.T) {
+	tests := []struct {
+		name  string
+		input any
+		opts  []StringifyOption
+		want  string
+	}{
+		{"object with index keys", map[string]any{"a": Object{"0": "a"}}, []StringifyOption{WithStringifyArrayFormat(ArrayFormatBrackets)}, "a[0]=a"},
+		{"array", map[string]any{"a": Array{"a"}}, []StringifyOption{WithStringifyArrayFormat(ArrayFormatBrackets)}, "a[]=a"},
+		{"array comma", map[string]any{"a": Array{"x", "y"}}, []StringifyOption{WithStringifyArrayFormat(ArrayFormatComma)}, "a=x,y"},
+		{"object with dots", map[string]any{"a": Object{"0": "b"}}, []StringifyOption{WithStringifyAllowDots(true)}, "a.0=b"},
+		{"nested markers", map[string]any{"a": Array{Object{"b": Array{"c"}}}}, []StringifyOption{WithStringifyArrayFormat(ArrayFormatBrackets)}, "a[][b][]=c"},
+		{"root object", Object{"a": "b"}, nil, "a=b"},
+		{"empty array", map[string]any{"a": Array{}, "b": []any{}}, nil, "a[]"},
+		{"nil array like empty", map[string]any{"a": Array(nil), "b": []any(nil)}, nil, "a[]"},
+		{"single element comma", map[string]any{"a": Array{"x"}, "b": []any{"y"}}, []StringifyOption{WithStringifyArrayFormat(ArrayFormatComma), WithStringifySort(SortKeysAsc())}, "a[]=x&b=y"},
+		{"nested single element comma", map[string]any{"a": map[string]any{"b": Array{"x"}}}, []StringifyOption{WithStringifyArrayFormat(ArrayFormatComma)}, "a[b][]=x"},
+		{"empty object", map[string]any{"a": Object{}, "b": "c"}, nil, "b=c"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Stringify(tt.input, append([]StringifyOption{WithStringifyEncode(false)}, tt.opts...)...)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestStringifyArrayMarkerRoundTrip(t *testing.T) {
+	tests := []struct {
+		name  string
+		input map[string]any
+		opts  []StringifyOption
+		popts []ParseOption
+		want  map[string]any
+	}{
+		{"empty", map[string]any{"a": Array{}}, nil, []ParseOption{WithParseAllowEmptyArrays(true)}, map[string]any{"a": []any{}}},
+		{"single element comma", map[string]any{"a": Array{"x"}}, []StringifyOption{WithStringifyArrayFormat(ArrayFormatComma)},
+			[]ParseOption{WithParseComma(true)}, map[string]any{"a": []any{"x"}}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			str, err := Stringify(tt.input, tt.opts...)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			got, err := Parse(str, tt.popts...)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Parse(%q) = %v, want %v", str, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestStringifyQuoteValues(t *testing.T) {
+	tests := []struct {
+		name  string
+		input map[string]any
+		opts  []StringifyOption
+		want  string
+	}{
+		{"never by default", map[string]any{"a": "b&c"}, nil, "a=b&c"},
+		{"when needed with delimiter", map[string]any{"a": "b&c", "d": "e"}, []StringifyOption{WithStringifyQuoteValues(QuoteWhenNeeded)}, `a="b&c"&d=e`},
+		{"when needed with equals", map[string]any{"a": "b=c"}, []StringifyOption{WithStringifyQuoteValues(QuoteWhenNeeded)}, `a="b=c"`},
+		{"custom delimiter", map[string]any{"a": "b;c", "d": "e&f"}, []StringifyOption{WithStringifyQuoteValues(QuoteWhenNeeded), WithStringifyDelimiter(";")}, `a="b;c";d=e&f`},
+		{"always", map[string]any{"a": "b", "c": "d"}, []StringifyOption{WithStringifyQuoteValues(QuoteAlways)}, `a="b"&c="d"`},
+		{"quote char", map[string]any{"a": "b&c"}, []StringifyOption{WithStringifyQuoteValues(QuoteWhenNeeded), WithStringifyQuoteChar('\'')}, `a='b&c'`},
+		{"nested and arrays", map[string]any{"a": map[string]any{"b": []any{"x&y"}}}, []StringifyOption{WithStringifyQuoteValues(QuoteWhenNeeded)}, `a[b][0]="x&y"`},
+		{"with escape percent", map[string]any{"a": "5%&"}, []StringifyOption{WithStringifyQuoteValues(QuoteWhenNeeded), WithStringifyEscapePercentOnly(true)}, `a="5%25&"`},
+		{"no effect when encoding", map[string]any{"a": "b&c"}, []StringifyOption{WithStringifyEncode(true), WithStringifyQuoteValues(QuoteAlways)}, "a=b%26c"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			opts := append([]StringifyOption{WithStringifyEncode(false), WithStringifySort(func(a, b string) bool { return a < b })}, tt.opts...)
+			got, err := Stringify(tt.input, opts...)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+
+	t.Run("invalid mode", func(t *testing.T) {
+		_, err := Stringify(map[string]any{"a": "b"}, WithStringifyQuoteValues("sometimes"))
+		if err != ErrInvalidQuoteMode {
+			t.Errorf("got %v, want %v", err, ErrInvalidQuoteMode)
+		}
+	})
+}
+
+func TestStringifyRawKeyChars(t *testing.T) {
+	tests := []struct {
+		name  string
+		input map[string]any
+		opts  []StringifyOption
+		want  string
+	}{
+		{"slash preserved in key", map[string]any{"a/b": "c/d"}, []StringifyOption{WithStringifyRawKeyChars("/")}, "a/b=c%2Fd"},
+		{"multiple chars", map[string]any{"ns:a/b": "x"}, []StringifyOption{WithStringifyRawKeyChars("/:")}, "ns:a/b=x"},
+		{"brackets still encoded", map[string]any{"a/b": map[string]any{"c": "d"}}, []StringifyOption{WithStringifyRawKeyChars("/")}, "a/b%5Bc%5D=d"},
+		{"raw brackets", map[string]any{"a": map[string]any{"b/c": "d"}}, []StringifyOption{WithStringifyRawKeyChars("[]/")}, "a[b/c]=d"},
+		{"rfc1738 unreserved kept", map[string]any{"a(b)/": "c d"}, []StringifyOption{WithStringifyRawKeyChars("/"), WithStringifyFormat(FormatRFC1738)}, "a(b)/=c+d"},
+		{"combines with safe chars", map[string]any{"a/b-c": "d-e"}, []StringifyOption{WithStringifyRawKeyChars("/"), WithStringifySafeCharFunc(func(r rune) bool { return r >= 'a' && r <= 'z' })}, "a/b%2Dc=d%2De"},
+		{"default encodes slash", map[string]any{"a/b": "c"}, nil, "a%2Fb=c"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Stringify(tt.input, tt.opts...)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestStringifyBoolKeys(t *testing.T) {
+	input := map[string]any{"active": true, "verbose": true, "debug": false, "enabled": false}
+	sortOpt := WithStringifySort(func(a, b string) bool { return a < b })
+
+	tests := []struct {
+		name  string
+		input map[string]any
+		opts  []StringifyOption
+		want  string
+	}{
+		{"default true false", input, nil, "active=true&debug=false&enabled=false&verbose=true"},
+		{"global one zero", input, []StringifyOption{WithStringifyBoolFormat(BoolOneZero)}, "active=1&debug=0&enabled=0&verbose=1"},
+		{"per key", input, []StringifyOption{
+			WithStringifyBoolKeys(map[string]BoolFormat{"active": BoolOneZero, "verbose": BoolFlag, "debug": BoolFlag}),
+		}, "active=1&enabled=false&verbose"},
+		{"per key with global fallback", input, []StringifyOption{
+			WithStringifyBoolFormat(BoolYesNo),
+			WithStringifyBoolKeys(map[string]BoolFormat{"active": BoolOnOff}),
+		}, "active=on&debug=no&enabled=no&verbose=yes"},
+		{"nested key path", map[string]any{"f": map[string]any{"on": true, "x": true}}, []StringifyOption{
+			WithStringifyBoolKeys(map[string]BoolFormat{"f[on]": BoolFlag}),
+		}, "f%5Bon%5D&f%5Bx%5D=true"},
+		{"nested key path allow dots", map[string]any{"f": map[string]any{"on": true}}, []StringifyOption{
+			WithStringifyAllowDots(true),
+			WithStringifyBoolKeys(map[string]BoolFormat{"f.on": BoolOneZero}),
+		}, "f.on=1"},
+		{"flag values only", map[string]any{"a b": true}, []StringifyOption{
+			WithStringifyEncodeValuesOnly(true),
+			WithStringifyBoolFormat(BoolFlag),
+		}, "a b"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Stringify(tt.input, append(tt.opts, sortOpt)...)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+
+	if _, err := Stringify(input, WithStringifyBoolFormat("bogus")); !errors.Is(err, ErrInvalidBoolFormat) {
+		t.Errorf("expected ErrInvalidBoolFormat, got %v", err)
+	}
+	if _, err := Stringify(input, WithStringifyBoolKeys(map[string]BoolFormat{"a": "bogus"})); !errors.Is(err, ErrInvalidBoolFormat) {
+		t.Errorf("expected ErrInvalidBoolFormat, got %v", err)
+	}
+}
+
+func TestStringifyPreserveNumericKeys(t *testing.T) {
+	promoted := map[string]any{"a": map[string]any{"10": "d", "2": "b", "5": "c"}}
+
+	tests := []struct {
+		name  string
+		input map[string]any
+		opts  []StringifyOption
+		want  string
+	}{
+		{"numeric order", promoted, nil, "a[2]=b&a[5]=c&a[10]=d"},
+		{"brackets format keeps keys", promoted, []StringifyOption{WithStringifyArrayFormat(ArrayFormatBrackets)}, "a[2]=b&a[5]=c&a[10]=d"},
+		{"overrides sort", promoted, []StringifyOption{WithStringifySort(func(a, b string) bool { return a < b })}, "a[2]=b&a[5]=c&a[10]=d"},
+		{"mixed keys use sort", map[string]any{"a": map[string]any{"2": "b", "x": "c"}}, []StringifyOption{WithStringifySort(func(a, b string) bool { return a > b })}, "a[x]=c&a[2]=b"},
+		{"non-canonical index", map[string]any{"a": map[string]any{"02": "b"}}, nil, "a[02]=b"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			opts := append([]StringifyOption{WithStringifyEncodeValuesOnly(true), WithStringifyPreserveNumericKeys(true)}, tt.opts...)
+			got, err := Stringify(tt.input, opts...)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+
+	// Round-trips the object Parse produces for indices beyond ArrayLimit
+	parsed, err := Parse("a[25]=b&a[30]=c&a[100]=d")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	got, err := Stringify(parsed, WithStringifyEncodeValuesOnly(true), WithStringifyPreserveNumericKeys(true))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got != "a[25]=b&a[30]=c&a[100]=d" {
+		t.Errorf("got %q, want %q", got, "a[25]=b&a[30]=c&a[100]=d")
+	}
+}
+
+func TestStringifyEncoders(t *testing.T) {
+	var kinds []string
+	record := func(str string, charset Charset, kind string, format Format) string {
+		kinds = append(kinds, kind+":"+str)
+		return str
+	}
+	replaceTabs := func(str string, charset Charset, kind string, format Format) string {
+		return strings.ReplaceAll(str, "\t", "  ")
+	}
+	upperValues := func(str string, charset Charset, kind string, format Format) string {
+		if kind == "value" {
+			return strings.ToUpper(str)
+		}
+		return str
+	}
+
+	tests := []struct {
+		name  string
+		input map[string]any
+		opts  []StringifyOption
+		want  string
+	}{
+		{"replace then encode", map[string]any{"a": "b\tc"}, []StringifyOption{WithStringifyEncoders(replaceTabs, DefaultEncoder)}, "a=b%20%20c"},
+		{"encode then replace", map[string]any{"a": "b\tc"}, []StringifyOption{WithStringifyEncoders(DefaultEncoder, replaceTabs)}, "a=b%09c"},
+		{"kind propagates", map[string]any{"k": "v w"}, []StringifyOption{WithStringifyEncoders(upperValues, DefaultEncoder)}, "k=V%20W"},
+		{"nested keys", map[string]any{"a": map[string]any{"b": "c"}}, []StringifyOption{WithStringifyEncoders(DefaultEncoder, upperValues)}, "a%5Bb%5D=C"},
+		{"formatter applies after chain", map[string]any{"a": "b c"}, []StringifyOption{WithStringifyEncoders(DefaultEncoder), WithStringifyFormat(FormatRFC1738)}, "a=b+c"},
+		{"empty chain uses default", map[string]any{"a": "b c"}, []StringifyOption{WithStringifyEncoders()}, "a=b%20c"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Stringify(tt.input, tt.opts...)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+
+	// Each stage sees the previous output and the same kind
+	_, err := Stringify(map[string]any{"a": "b c"}, WithStringifyEncoders(record, DefaultEncoder, record))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	want := "key:a,key:a,value:b c,value:b%20c"
+	if got := strings.Join(kinds, ","); got != want {
+		t.Errorf("stages saw %q, want %q", got, want)
+	}
+}
+
+func TestStringifyLevelDelimiters(t *testing.T) {
+	nested := map[string]any{"a": map[string]any{"b": map[string]any{"c": map[string]any{"d": "v"}}}}
+	withArray := map[string]any{"a": map[string]any{"b": []any{"x", "y"}}}
+
+	tests := []struct {
+		name  string
+		input map[string]any
+		opts  []StringifyOption
+		want  string
+	}{
+		{"per level", nested, []StringifyOption{WithStringifyLevelDelimiters(".", "/", ":")}, "a.b/c:d=v"},
+		{"last repeats", nested, []StringifyOption{WithStringifyLevelDelimiters(".", "/")}, "a.b/c/d=v"},
+		{"single", nested, []StringifyOption{WithStringifyLevelDelimiters("_")}, "a_b_c_d=v"},
+		{"indices", withArray, []StringifyOption{WithStringifyLevelDelimiters(".", "/")}, "a.b/0=x&a.b/1=y"},
+		{"brackets", withArray, []StringifyOption{WithStringifyLevelDelimiters(".", "/"), WithStringifyArrayFormat(ArrayFormatBrackets)}, "a.b/=x&a.b/=y"},
+		{"repeat", withArray, []StringifyOption{WithStringifyLevelDelimiters(".", "/"), WithStringifyArrayFormat(ArrayFormatRepeat)}, "a.b=x&a.b=y"},
+		{"comma", withArray, []StringifyOption{WithStringifyLevelDelimiters(".", "/"), WithStringifyArrayFormat(ArrayFormatComma)}, "a.b=x,y"},
+		{"repeat does not consume a level", map[string]any{"a": []any{map[string]any{"b": map[string]any{"c": "v"}}}}, []StringifyOption{WithStringifyLevelDelimiters(".", "/"), WithStringifyArrayFormat(ArrayFormatRepeat)}, "a.b/c=v"},
+		{"delimiters are encoded with keys", nested, []StringifyOption{WithStringifyLevelDelimiters(".", "/"), WithStringifyEncodeValuesOnly(false)}, "a.b%2Fc%2Fd=v"},
+		{"jsonpointer ignores levels", nested, []StringifyOption{WithStringifyLevelDelimiters(".", "/"), WithStringifyArrayFormat(ArrayFormatJSONPointer)}, "/a/b/c/d=v"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			opts := append([]StringifyOption{WithStringifyEncodeValuesOnly(true)}, tt.opts...)
+			got, err := Stringify(tt.input, opts...)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+
+	if _, err := Stringify(nested, WithStringifyLevelDelimiters(".", "")); !errors.Is(err, ErrInvalidLevelDelimiters) {
+		t.Errorf("expected ErrInvalidLevelDelimiters, got %v", err)
+	}
+}
+
+func TestStringifyKeyPriority(t *testing.T) {
+	obj := map[string]any{
+		"b": "2", "a": "1", "sig": "x", "ts": "9", "z": map[string]any{"ts": "n", "m": "o"},
+	}
+	byName := func(a, b string) bool { return a < b }
+
+	tests := []struct {
+		name string
+		opts []StringifyOption
+		want string
+	}{
+		{"with sort", []StringifyOption{WithStringifyKeyPriority([]string{"ts", "sig", "missing"}), WithStringifySort(byName)}, "ts=9&sig=x&a=1&b=2&z%5Bm%5D=o&z%5Bts%5D=n"},
+		{"duplicate entries", []StringifyOption{WithStringifyKeyPriority([]string{"sig", "a", "sig"}), WithStringifySort(byName)}, "sig=x&a=1&b=2&ts=9&z%5Bm%5D=o&z%5Bts%5D=n"},
+		{"empty list", []StringifyOption{WithStringifyKeyPriority(nil), WithStringifySort(byName)}, "a=1&b=2&sig=x&ts=9&z%5Bm%5D=o&z%5Bts%5D=n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Stringify(obj, tt.opts...)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+
+	// Without Sort, only the leading keys are fixed
+	got, err := Stringify(obj, WithStringifyKeyPriority([]string{"sig", "ts"}))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !strings.HasPrefix(got, "sig=x&ts=9&") {
+		t.Errorf("got %q, want prefix %q", got, "sig=x&ts=9&")
+	}
+}
+
+func TestStringifyNestedArrayFormat(t *testing.T) {
+	obj := map[string]any{
+		"a": []any{
+			map[string]any{"b": []any{"x", "y"}},
+			map[string]any{"b": []any{"z"}},
+		},
+		"c": []any{"1", "2"},
+		"d": map[string]any{"e": []any{"3"}},
+	}
+
+	tests := []struct {
+		name string
+		opts []StringifyOption
+		want string
+	}{
+		{
+			"brackets outside, indices inside",
+			[]StringifyOption{WithStringifyArrayFormat(ArrayFormatBrackets), WithStringifyNestedArrayFormat(ArrayFormatIndices)},
+			"a[][b][0]=x&a[][b][1]=y&a[][b][0]=z&c[]=1&c[]=2&d[e][]=3",
+		},
+		{
+			"indices outside, comma inside",
+			[]StringifyOption{WithStringifyNestedArrayFormat(ArrayFormatComma)},
+			"a[0][b]=x,y&a[1][b]=z&c[0]=1&c[1]=2&d[e][0]=3",
+		},
+		{
+			"comma round trip applies to nested comma",
+			[]StringifyOption{WithStringifyNestedArrayFormat(ArrayFormatComma), WithStringifyCommaRoundTrip(true)},
+			"a[0][b]=x,y&a[1][b][]=z&c[0]=1&c[1]=2&d[e][0]=3",
+		},
+		{
+			"unset keeps one format",
+			[]StringifyOption{WithStringifyArrayFormat(ArrayFormatBrackets)},
+			"a[][b][]=x&a[][b][]=y&a[][b][]=z&c[]=1&c[]=2&d[e][]=3",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			opts := append([]StringifyOption{WithStringifyEncode(false), WithStringifySort(func(a, b string) bool { return a < b })}, tt.opts...)
+			got, err := Stringify(obj, opts...)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+
+	// Arrays directly inside arrays use the nested format too
+	got, err := Stringify(map[string]any{"m": []any{[]any{"1", "2"}}},
+		WithStringifyEncode(false), WithStringifyArrayFormat(ArrayFormatBrackets), WithStringifyNestedArrayFormat(ArrayFormatIndices))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if want := "m[][0]=1&m[][1]=2"; got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+
+	if _, err := Stringify(obj, WithStringifyNestedArrayFormat(ArrayFormatJSONPointer)); !errors.Is(err, ErrInvalidArrayFormat) {
+		t.Errorf("expected ErrInvalidArrayFormat, got %v", err)
+	}
+}
+
+func TestStringifyCompactIndices(t *testing.T) {
+	tests := []struct {
+		name string
+		obj  map[string]any
+		opts []StringifyOption
+		want string
+	}{
+		{"dense", map[string]any{"a": []any{"b", "c"}}, nil, "a[]=b&a[]=c"},
+		{"sparse", map[string]any{"a": []any{nil, "b", nil, "c"}}, nil, "a[1]=b&a[3]=c"},
+		{"objects", map[string]any{"a": []any{map[string]any{"b": "1"}}}, nil, "a[0][b]=1"},
+		{"nested dense", map[string]any{"a": []any{[]any{"x", "y"}}}, nil, "a[0][]=x&a[0][]=y"},
+		{"skip nulls gap", map[string]any{"a": []any{"b", ExplicitNullValue, "c"}}, []StringifyOption{WithStringifySkipNulls(true)}, "a[0]=b&a[2]=c"},
+		{"other formats unaffected", map[string]any{"a": []any{"b"}}, []StringifyOption{WithStringifyArrayFormat(ArrayFormatRepeat)}, "a=b"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			opts := append([]StringifyOption{WithStringifyEncode(false), WithStringifyCompactIndices(true)}, tt.opts...)
+			got, err := Stringify(tt.obj, opts...)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+
+	// Output parses back to the same structure
+	obj := map[string]any{
+		"a": []any{"1", "2", "3"},
+		"b": []any{map[string]any{"c": []any{"x", "y"}}, map[string]any{"c": []any{"z"}}},
+	}
+	str, err := Stringify(obj, WithStringifyCompactIndices(true))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	got, err := Parse(str)
+	if err != nil {
+		t.Fatalf("unexpected parse error: %v", err)
+	}
+	if !reflect.DeepEqual(got, obj) {
+		t.Errorf("round trip of %q: got %v, want %v", str, got, obj)
+	}
+}
+
+func TestStringifyMaxValueLength(t *testing.T) {
+	got, err := Stringify(map[string]any{"a": "12345", "b": []any{"xy"}},
+		WithStringifyMaxValueLength(5), WithStringifySort(func(a, b string) bool { return a < b }))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if want := "a=12345&b%5B0%5D=xy"; got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+
+	tests := []struct {
+		name string
+		obj  map[string]any
+		opts []StringifyOption
+		key  string
+	}{
+		{"plain value", map[string]any{"blob": "123456"}, nil, `"blob"`},
+		{"nested value", map[string]any{"a": map[string]any{"b": []any{"ok", "toolong"}}}, nil, `"a[b][1]"`},
+		// "a b c" is 5 bytes raw but 9 once encoded as a%20b%20c
+		{"after encoding", map[string]any{"q": "a b c"}, nil, `"q"`},
+		{"without encoding", map[string]any{"q": "123456"}, []StringifyOption{WithStringifyEncode(false)}, `"q"`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Stringify(tt.obj, append(tt.opts, WithStringifyMaxValueLength(5))...)
+			if !errors.Is(err, ErrValueTooLong) {
+				t.Fatalf("expected ErrValueTooLong, got %v", err)
+			}
+			if !strings.Contains(err.Error(), tt.key) {
+				t.Errorf("error %q should name key %s", err, tt.key)
+			}
+		})
+	}
+}
+
+func TestStringifyNilSlices(t *testing.T) {
+	obj := map[string]any{"n": []any(nil), "e": []any{}, "v": "1"}
+	nested := map[string]any{"a": map[string]any{"b": []any(nil), "c": Array(nil)}, "v": "1"}
+	byName := WithStringifySort(func(a, b string) bool { return a < b })
+
+	tests := []struct {
+		name string
+		obj  map[string]any
+		opts []StringifyOption
+		want string
+	}{
+		{"default like empty", obj, nil, "v=1"},
+		{"default like empty with AllowEmptyArrays", obj, []StringifyOption{WithStringifyAllowEmptyArrays(true)}, "e[]&n[]&v=1"},
+		{"skip", obj, []StringifyOption{WithStringifyNilSlices(NilSliceSkip), WithStringifyAllowEmptyArrays(true)}, "e[]&v=1"},
+		{"null", obj, []StringifyOption{WithStringifyNilSlices(NilSliceAsNull)}, "n=&v=1"},
+		{"null strict", obj, []StringifyOption{WithStringifyNilSlices(NilSliceAsNull), WithStringifyStrictNullHandling(true)}, "n&v=1"},
+		{"null skip nulls", obj, []StringifyOption{WithStringifyNilSlices(NilSliceAsNull), WithStringifySkipNulls(true)}, "v=1"},
+		{"nested and Array", nested, []StringifyOption{WithStringifyNilSlices(NilSliceAsNull)}, "a[b]=&a[c]=&v=1"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Stringify(tt.obj, append(tt.opts, byName, WithStringifyEncode(false))...)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+
+	if _, err := Stringify(obj, WithStringifyNilSlices("bogus")); !errors.Is(err, ErrInvalidNilSlices) {
+		t.Errorf("expected ErrInvalidNilSlices, got %v", err)
+	}
+}
+
+func TestStringifyChecksum(t *testing.T) {
+	var seen []string
+	sum := func(content []byte) string {
+		seen = append(seen, string(content))
+		return strconv.Itoa(len(content))
+	}
+	byName := WithStringifySort(func(a, b string) bool { return a < b })
+
+	tests := []struct {
+		name        string
+		obj         map[string]any
+		opts        []StringifyOption
+		want        string
+		wantContent string
+	}{
+		{"appended last", map[string]any{"b": "2", "a": "x y"}, nil, "a=x%20y&b=2&sig=11", "a=x%20y&b=2"},
+		{"query prefix excluded", map[string]any{"a": "1"}, []StringifyOption{WithStringifyAddQueryPrefix(true)}, "?a=1&sig=3", "a=1"},
+		{"sentinel included", map[string]any{"a": "1"}, []StringifyOption{WithStringifyCharsetSentinel(true)}, "utf8=%E2%9C%93&a=1&sig=18", "utf8=%E2%9C%93&a=1"},
+		{"custom delimiter", map[string]any{"a": "1", "b": "2"}, []StringifyOption{WithStringifyDelimiter(";")}, "a=1;b=2;sig=7", "a=1;b=2"},
+		{"empty object", map[string]any{}, []StringifyOption{WithStringifyAddQueryPrefix(true), WithStringifyCharsetSentinel(true)}, "?sig=0", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			seen = nil
+			opts := append([]StringifyOption{byName, WithStringifyChecksum("sig", sum)}, tt.opts...)
+			got, err := Stringify(tt.obj, opts...)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("got %q, want %q", got, tt.want)
+			}
+			if len(seen) != 1 || seen[0] != tt.wantContent {
+				t.Errorf("checksum content = %q, want %q", seen, tt.wantContent)
+			}
+		})
+	}
+
+	if _, err := Stringify(map[string]any{"a": "1"}, WithStringifyChecksum("", sum)); !errors.Is(err, ErrInvalidChecksum) {
+		t.Errorf("expected ErrInvalidChecksum, got %v", err)
+	}
+}
+
+func TestStringifyBoolKeyAsBareValue(t *testing.T) {
+	byName := WithStringifySort(func(a, b string) bool { return a < b })
+
+	tests := []struct {
+		name  string
+		query string
+		obj   map[string]any
+		want  string
+	}{
+		{"added key", "a[b]=c&a=d", map[string]any{"a": map[string]any{"b": "c", "d": true}}, "a[b]=c&a=d"},
+		{"several added keys", "a[b]=c&a[e]=true&a=d", map[string]any{"a": map[string]any{"b": "c", "d": true, "e": "true"}}, "a[b]=c&a[e]=true&a=d"},
+		{"nested", "x[a][b]=c&x[a]=d", map[string]any{"x": map[string]any{"a": map[string]any{"b": "c", "d": true}}}, "x[a][b]=c&x[a]=d"},
+		{"encoded", "a[b]=c&a=d%20e", map[string]any{"a": map[string]any{"b": "c", "d e": true}}, "a%5Bb%5D=c&a=d%20e"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			parsed, err := Parse(tt.query)
+			if err != nil {
+				t.Fatalf("Parse() error = %v", err)
+			}
+			if !reflect.DeepEqual(parsed, tt.obj) {
+				t.Fatalf("Parse() = %v, want %v", parsed, tt.obj)
+			}
+
+			opts := []StringifyOption{byName, WithStringifyBoolKeyAsBareValue(true)}
+			if tt.name != "encoded" {
+				opts = append(opts, WithStringifyEncode(false))
+			}
+			got, err := Stringify(tt.obj, opts...)
+			if err != nil {
+				t.Fatalf("Stringify() error = %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("Stringify() = %q, want %q", got, tt.want)
+			}
+
+			reparsed, err := Parse(got)
+			if err != nil {
+				t.Fatalf("Parse() error = %v", err)
+			}
+			if !reflect.DeepEqual(reparsed, tt.obj) {
+				t.Errorf("round trip = %v, want %v", reparsed, tt.obj)
+			}
+		})
+	}
+
+	unchanged := []struct {
+		name string
+		obj  map[string]any
+		want string
+	}{
+		{"top-level true", map[string]any{"a": true}, "a=true"},
+		{"only true values", map[string]any{"a": map[string]any{"d": true}}, "a[d]=true"},
+		{"false", map[string]any{"a": map[string]any{"b": "c", "d": false}}, "a[b]=c&a[d]=false"},
+	}
+	for _, tt := range unchanged {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Stringify(tt.obj, byName, WithStringifyEncode(false), WithStringifyBoolKeyAsBareValue(true))
+			if err != nil {
+				t.Fatalf("Stringify() error = %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("Stringify() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+
+	// Of several keys holding true, the first in Sort or byte order is chosen
+	several := map[string]any{"a": map[string]any{"b": "c", "e": true, "d": true, "f": true}}
+	for i := 0; i < 50; i++ {
+		got, err := Stringify(several, WithStringifyEncode(false), WithStringifyBoolKeyAsBareValue(true))
+		if err != nil {
+			t.Fatalf("Stringify() error = %v", err)
+		}
+		if !strings.HasSuffix(got, "&a=d") {
+			t.Fatalf("Stringify() = %q, want bare value a=d", got)
+		}
+	}
+	got, err := Stringify(several, WithStringifyEncode(false), WithStringifyBoolKeyAsBareValue(true), WithStringifySort(SortKeysDesc()))
+	if err != nil {
+		t.Fatalf("Stringify() error = %v", err)
+	}
+	if want := "a[e]=true&a[d]=true&a[b]=c&a=f"; got != want {
+		t.Errorf("Stringify() = %q, want %q", got, want)
+	}
+
+	// Without the option the added key does not round-trip
+	got, err = Stringify(map[string]any{"a": map[string]any{"b": "c", "d": true}}, byName, WithStringifyEncode(false))
+	if err != nil {
+		t.Fatalf("Stringify() error = %v", err)
+	}
+	if want := "a[b]=c&a[d]=true"; got != want {
+		t.Errorf("Stringify() = %q, want %q", got, want)
+	}
+}
+
+func TestStringifyASCIIOnly(t *testing.T) {
+	obj := map[string]any{"ключ": "é✓"}
+	raw := func(str string, charset Charset, kind string, format Format) string { return str }
+
+	tests := []struct {
+		name string
+		opts []StringifyOption
+		want string
+	}{
+		{"already encoded", nil, "%D0%BA%D0%BB%D1%8E%D1%87=%C3%A9%E2%9C%93"},
+		{"encode disabled", []StringifyOption{WithStringifyEncode(false)}, "%D0%BA%D0%BB%D1%8E%D1%87=%C3%A9%E2%9C%93"},
+		{"custom encoder", []StringifyOption{WithStringifyEncoder(raw)}, "%D0%BA%D0%BB%D1%8E%D1%87=%C3%A9%E2%9C%93"},
+		{"iso-8859-1", []StringifyOption{WithStringifyEncode(false), WithStringifyCharset(CharsetISO88591)}, "%26%231082%3B%26%231083%3B%26%231102%3B%26%231095%3B=%E9%26%2310003%3B"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Stringify(obj, append(tt.opts, WithStringifyASCIIOnly(true))...)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+
+	// Output round-trips through Parse
+	got, err := Stringify(obj, WithStringifyEncode(false), WithStringifyASCIIOnly(true))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	parsed, err := Parse(got)
+	if err != nil {
+		t.Fatalf("Parse() error = %v", err)
+	}
+	if !reflect.DeepEqual(parsed, obj) {
+		t.Errorf("round trip = %v, want %v", parsed, obj)
+	}
+
+	if _, err := Stringify(obj, WithStringifyASCIIOnly(true), WithStringifyDelimiter("·")); !errors.Is(err, ErrNonASCIIDelimiter) {
+		t.Errorf("expected ErrNonASCIIDelimiter, got %v", err)
+	}
+}
+
+func TestStringifyIndexedRepeat(t *testing.T) {
+	obj := map[string]any{"a": []any{"ccc", "b", "dd", "e", "f", "g", "h", "i", "j", "k", "l"}, "z": "1"}
+	base := []StringifyOption{
+		WithStringifyArrayFormat(ArrayFormatRepeat),
+		WithStringifyEncode(false),
+		WithStringifySort(func(a, b string) bool { return a < b }),
+		WithStringifySortArrayIndices(true),
+		WithStringifySortPairs(SortByValueLength()),
+	}
+
+	got, err := Stringify(obj, base...)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	// Indices sort as strings ("10" before "2"), then pairs by value length
+	if want := "a=b&a=l&a=e&a=f&a=g&a=h&a=i&a=j&a=k&z=1&a=dd&a=ccc"; got != want {
+		t.Fatalf("without IndexedRepeat got %q, want %q", got, want)
+	}
+
+	got, err = Stringify(obj, append(base, WithStringifyIndexedRepeat(true))...)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	// Keys move as a whole, ordered by their first pair ("a=ccc" after "z=1")
+	if want := "z=1&a=ccc&a=b&a=dd&a=e&a=f&a=g&a=h&a=i&a=j&a=k&a=l"; got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+
+	for _, tt := range []struct {
+		duplicates DuplicateHandling
+		want       any
+	}{
+		{DuplicateCombine, obj["a"]},
+		{DuplicateFirst, "ccc"},
+		{DuplicateLast, "l"},
+	} {
+		parsed, err := Parse(got, WithParseDuplicates(tt.duplicates))
+		if err != nil {
+			t.Fatalf("Parse() error = %v", err)
+		}
+		if !reflect.DeepEqual(parsed["a"], tt.want) {
+			t.Errorf("Parse() with %s: a = %v, want %v", tt.duplicates, parsed["a"], tt.want)
+		}
+	}
+}
+
+func TestStringifyIntegerFormatter(t *testing.T) {
+	padded := WithStringifyIntegerFormatter(func(n int64) string { return fmt.Sprintf("%010d", n) })
+	tests := []struct {
+		name string
+		obj  map[string]any
+		opts []StringifyOption
+		want string
+	}{
+		{"scalar", map[string]any{"id": int64(42)}, nil, "id=0000000042"},
+		{"array", map[string]any{"ids": []any{1, uint8(2)}}, nil, "ids[0]=0000000001&ids[1]=0000000002"},
+		{"comma", map[string]any{"ids": []any{1, 2}}, []StringifyOption{WithStringifyArrayFormat(ArrayFormatComma)},
+			"ids=0000000001,0000000002"},
+		{"nested", map[string]any{"user": map[string]any{"id": int32(7), "name": "x"}}, nil, "user[id]=0000000007&user[name]=x"},
+		{"other values unchanged", map[string]any{"a": 1.5, "b": "3", "c": true}, nil, "a=1.5&b=3&c=true"},
+		{"uint64 above int64 range", map[string]any{"n": uint64(math.MaxUint64)}, nil, "n=18446744073709551615"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			opts := append([]StringifyOption{padded, WithStringifyEncodeValuesOnly(true), WithStringifySort(SortKeysAsc())}, tt.opts...)
+			got, err := Stringify(tt.obj, opts...)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+
+	// Without a formatter large integers stay plain decimal
+	got, err := Stringify(map[string]any{"a": int64(math.MaxInt64), "b": uint64(1e19)}, WithStringifySort(SortKeysAsc()))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if want := "a=9223372036854775807&b=10000000000000000000"; got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
+
+func TestStringifyPreserveSparseIndices(t *testing.T) {
+	obj := map[string]any{
+		"a": []any{nil, "2", nil, nil, "1"},
+		"b": []any{map[string]any{"c": []any{nil, "x"}}},
+		"n": []any{"1", ExplicitNullValue, "2"},
+	}
+	base := []StringifyOption{WithStringifyEncodeValuesOnly(true), WithStringifySort(SortKeysAsc())}
+
+	tests := []struct {
+		name string
+		opts []StringifyOption
+		want string
+	}{
+		{"preserved", nil, "a[1]=2&a[4]=1&b[0][c][1]=x&n[0]=1&n[1]=&n[2]=2"},
+		{"closed", []StringifyOption{WithStringifyPreserveSparseIndices(false)}, "a[0]=2&a[1]=1&b[0][c][0]=x&n[0]=1&n[1]=&n[2]=2"},
+		{"closed with skip nulls", []StringifyOption{WithStringifyPreserveSparseIndices(false), WithStringifySkipNulls(true)},
+			"a[0]=2&a[1]=1&b[0][c][0]=x&n[0]=1&n[1]=2"},
+		{"brackets unaffected", []StringifyOption{WithStringifyPreserveSparseIndices(false), WithStringifyArrayFormat(ArrayFormatBrackets)},
+			"a[]=2&a[]=1&b[][c][]=x&n[]=1&n[]=&n[]=2"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Stringify(obj, append(base, tt.opts...)...)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestStringifyFilterRegexp(t *testing.T) {
+	obj := map[string]any{
+		"utm_source": "mail",
+		"utm":        map[string]any{"id": "1", "utm_x": "2"},
+		"page":       "3",
+		"tags":       []any{"a", "b"},
+		"empty":      []any{},
+	}
+	base := []StringifyOption{WithStringifyEncode(false), WithStringifySort(SortKeysAsc())}
+
+	tests := []struct {
+		name string
+		opts []StringifyOption
+		want string
+	}{
+		{"top-level", []StringifyOption{WithStringifyFilterRegexp(regexp.MustCompile(`^utm`))},
+			"utm[id]=1&utm[utm_x]=2&utm_source=mail"},
+		{"full path", []StringifyOption{WithStringifyFilterRegexp(regexp.MustCompile(`utm_`)), WithStringifyFilterRegexpPath(true)},
+			"utm[utm_x]=2&utm_source=mail"},
+		{"array elements", []StringifyOption{WithStringifyFilterRegexp(regexp.MustCompile(`^tags\[0\]$`)), WithStringifyFilterRegexpPath(true)},
+			"tags[0]=a"},
+		{"comma joined", []StringifyOption{WithStringifyFilterRegexp(regexp.MustCompile(`^tags$`)), WithStringifyFilterRegexpPath(true),
+			WithStringifyArrayFormat(ArrayFormatComma)}, "tags=a,b"},
+		{"empty array", []StringifyOption{WithStringifyFilterRegexp(regexp.MustCompile(`^empty$`)), WithStringifyFilterRegexpPath(true),
+			WithStringifyAllowEmptyArrays(true)}, "empty[]"},
+		{"with filter", []StringifyOption{WithStringifyFilterRegexp(regexp.MustCompile(`^utm`)), WithStringifyFilter([]string{"utm_source", "page"})},
+			"utm_source=mail"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Stringify(obj, append(base, tt.opts...)...)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestStringifyPairSpacing(t *testing.T) {
+	obj := map[string]any{"db": map[string]any{"host": "localhost", "port": 5432}, "debug": true}
+	base := []StringifyOption{
+		WithStringifyEncode(false),
+		WithStringifyAllowDots(true),
+		WithStringifyDelimiter("\n"),
+		WithStringifySort(func(a, b string) bool { return a < b }),
+		WithStringifyPairSpacing(true),
+	}
+
+	tests := []struct {
+		name string
+		opts []StringifyOption
+		want string
+	}{
+		{"spaced", base, "db.host = localhost\ndb.port = 5432\ndebug = true"},
+		{"checksum", append(base, WithStringifyChecksum("sum", func([]byte) string { return "x" })),
+			"db.host = localhost\ndb.port = 5432\ndebug = true\nsum = x"},
+		{"ignored when encoding", append(base, WithStringifyEncode(true)), "db.host=localhost\ndb.port=5432\ndebug=true"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Stringify(obj, tt.opts...)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestStringifyDuplicateReducer(t *testing.T) {
+	// Drops repeated values and records the keys it was called with
+	var keys []string
+	unique := WithStringifyDuplicateReducer(func(key string, values []any) []any {
+		keys = append(keys, key)
+		var out []any
+		seen := map[any]bool{}
+		for _, v := range values {
+			if !seen[v] {
+				seen[v] = true
+				out = append(out, v)
+			}
+		}
+		return out
+	})
+	// Expands comma-separated tags into multiple values
+	split := WithStringifyFilter(FilterFunc(func(prefix string, v any) any {
+		if s, ok := v.(string); ok && prefix == "tags" && strings.Contains(s, ",") {
+			parts := strings.Split(s, ",")
+			out := make([]any, len(parts))
+			for i, p := range parts {
+				out[i] = p
+			}
+			return out
+		}
+		return v
+	}))
+
+	tests := []struct {
+		name     string
+		obj      map[string]any
+		opts     []StringifyOption
+		want     string
+		wantKeys []string
+	}{
+		{"repeat", map[string]any{"a": []any{"x", "y", "x"}}, []StringifyOption{WithStringifyArrayFormat(ArrayFormatRepeat)},
+			"a=x&a=y", []string{"a"}},
+		{"filter expansion", map[string]any{"tags": "go,qs,go"}, []StringifyOption{split, WithStringifyArrayFormat(ArrayFormatRepeat)},
+			"tags=go&tags=qs", []string{"tags"}},
+		{"nested", map[string]any{"a": map[string]any{"b": []any{1, 1, 2}}}, nil,
+			"a[b][0]=1&a[b][1]=2", []string{"a[b]"}},
+		{"comma", map[string]any{"a": []any{"x", "x"}}, []StringifyOption{WithStringifyArrayFormat(ArrayFormatComma)},
+			"a=x", []string{"a"}},
+		{"empty result", map[string]any{"a": []any{}, "b": "1"}, []StringifyOption{WithStringifyAllowEmptyArrays(true)},
+			"a[]&b=1", []string{"a"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			keys = nil
+			opts := append([]StringifyOption{unique, WithStringifyEncodeValuesOnly(true), WithStringifySort(SortKeysAsc())}, tt.opts...)
+			got, err := Stringify(tt.obj, opts...)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("got %q, want %q", got, tt.want)
+			}
+			if !reflect.DeepEqual(keys, tt.wantKeys) {
+				t.Errorf("reducer keys = %v, want %v", keys, tt.wantKeys)
+			}
+		})
+	}
+
+	t.Run("reorder", func(t *testing.T) {
+		reverse := WithStringifyDuplicateReducer(func(_ string, values []any) []any {
+			out := make([]any, len(values))
+			for i, v := range values {
+				out[len(values)-1-i] = v
+			}
+			return out
+		})
+		got, err := Stringify(map[string]any{"a": []any{"1", "2", "3"}}, reverse, WithStringifyArrayFormat(ArrayFormatRepeat))
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if want := "a=3&a=2&a=1"; got != want {
+			t.Errorf("got %q, want %q", got, want)
+		}
+	})
+}
+
+func TestStringifyStrictUnencoded(t *testing.T) {
+	tests := []struct {
+		name    string
+		obj     map[string]any
+		opts    []StringifyOption
+		want    string
+		wantErr string
+	}{
+		{"ampersand", map[string]any{"q": "a&b"}, nil, "", `"q" contains "&"`},
+		{"equals", map[string]any{"q": "a=b"}, nil, "", `"q" contains "="`},
+		{"nested", map[string]any{"a": map[string]any{"b": []any{"x", "y&z"}}}, nil, "", `"a[b][1]" contains "&"`},
+		{"comma", map[string]any{"a": []any{"x", "y&z"}}, []StringifyOption{WithStringifyArrayFormat(ArrayFormatComma)}, "", `"a" contains "&"`},
+		{"custom delimiter", map[string]any{"q": "a;b"}, []StringifyOption{WithStringifyDelimiter(";")}, "", `"q" contains ";"`},
+		{"other delimiter allowed", map[string]any{"q": "a&b"}, []StringifyOption{WithStringifyDelimiter(";")}, "q=a&b", ""},
+		{"safe values", map[string]any{"q": "a b", "r": "1"}, nil, "q=a b&r=1", ""},
+		{"encoded", map[string]any{"q": "a&b"}, []StringifyOption{WithStringifyEncode(true)}, "q=a%26b", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			opts := append([]StringifyOption{WithStringifyEncode(false), WithStringifyStrictUnencoded(true), WithStringifySort(SortKeysAsc())}, tt.opts...)
+			got, err := Stringify(tt.obj, opts...)
+			if tt.wantErr != "" {
+				if !errors.Is(err, ErrUnsafeValue) || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("got error %v, want ErrUnsafeValue mentioning %s", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+
+	// Without StrictUnencoded the value is written as is
+	got, err := Stringify(map[string]any{"q": "a&b"}, WithStringifyEncode(false))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if want := "q=a&b"; got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
+
+// countingWriter records each write and fails once limit writes were made.
+type countingWriter struct {
+	b      strings.Builder
+	writes int
+	limit  int
+}
+
+func (w *countingWriter) Write(p []byte) (int, error) {
+	if w.limit > 0 && w.writes == w.limit {
+		return 0, errors.New("write failed")
+	}
+	w.writes++
+	return w.b.Write(p)
+}
+
+func TestStringifyTo(t *testing.T) {
+	obj := map[string]any{
+		"b": map[string]any{"y": "2", "x": "1"},
+		"a": []any{"1", "2"},
+		"c": "ü",
+	}
+	sorted := WithStringifySort(SortKeysAsc())
+	tests := []struct {
+		name string
+		obj  map[string]any
+		opts []StringifyOption
+	}{
+		{"sorted", obj, []StringifyOption{sorted}},
+		{"query prefix and sentinel", obj, []StringifyOption{sorted, WithStringifyAddQueryPrefix(true), WithStringifyCharsetSentinel(true)}},
+		{"ascii only", obj, []StringifyOption{sorted, WithStringifyEncode(false), WithStringifyASCIIOnly(true), WithStringifyDelimiter(";")}},
+		{"sort pairs", obj, []StringifyOption{WithStringifySortPairs(SortKeysDesc())}},
+		{"checksum", obj, []StringifyOption{sorted, WithStringifyChecksum("sig", func(b []byte) string { return strconv.Itoa(len(b)) })}},
+		{"empty", map[string]any{}, []StringifyOption{WithStringifyAddQueryPrefix(true)}},
+		{"skipped values", map[string]any{"a": nil}, []StringifyOption{WithStringifySkipNulls(true), WithStringifyAddQueryPrefix(true)}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			want, err := Stringify(tt.obj, tt.opts...)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			var b strings.Builder
+			if err := StringifyTo(&b, tt.obj, tt.opts...); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got := b.String(); got != want {
+				t.Errorf("got %q, want %q", got, want)
+			}
+		})
+	}
+
+	t.Run("writes each pair", func(t *testing.T) {
+		w := &countingWriter{}
+		if err := StringifyTo(w, obj, sorted, WithStringifyEncode(false)); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if want := "a[0]=1&a[1]=2&b[x]=1&b[y]=2&c=ü"; w.b.String() != want {
+			t.Errorf("got %q, want %q", w.b.String(), want)
+		}
+		// Five pairs and the four delimiters between them
+		if w.writes != 9 {
+			t.Errorf("got %d writes, want 9", w.writes)
+		}
+	})
+
+	t.Run("writer error", func(t *testing.T) {
+		w := &countingWriter{limit: 3}
+		err := StringifyTo(w, obj, sorted, WithStringifyEncode(false))
+		if err == nil || err.Error() != "write failed" {
+			t.Fatalf("got error %v, want write failed", err)
+		}
+		if want := "a[0]=1&a[1]=2"; w.b.String() != want {
+			t.Errorf("got %q, want %q", w.b.String(), want)
+		}
+	})
+
+	t.Run("cyclic reference", func(t *testing.T) {
+		cyclic := map[string]any{}
+		cyclic["self"] = cyclic
+		var b strings.Builder
+		err := StringifyTo(&b, map[string]any{"a": "1", "b": cyclic}, sorted)
+		if !errors.Is(err, ErrCyclicReference) {
+			t.Fatalf("got error %v, want ErrCyclicReference", err)
+		}
+		if want := "a=1"; b.String() != want {
+			t.Errorf("partial output %q, want %q", b.String(), want)
+		}
+	})
+
+	t.Run("invalid options", func(t *testing.T) {
+		var b strings.Builder
+		if err := StringifyTo(&b, obj, WithStringifyFormat("bogus")); !errors.Is(err, ErrInvalidFormat) {
+			t.Errorf("got error %v, want ErrInvalidFormat", err)
+		}
+	})
+}
+
+func TestStringifyBytes(t *testing.T) {
+	obj := map[string]any{"a": []any{"1", "2"}, "b": map[string]any{"c": "ü"}}
+	tests := []struct {
+		name string
+		opts []StringifyOption
+	}{
+		{"default", nil},
+		{"query prefix", []StringifyOption{WithStringifyAddQueryPrefix(true), WithStringifyCharsetSentinel(true)}},
+		{"sort pairs", []StringifyOption{WithStringifySortPairs(SortKeysDesc())}},
+		{"filter everything", []StringifyOption{WithStringifyFilter([]string{"x"}), WithStringifyAddQueryPrefix(true)}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			opts := append([]StringifyOption{WithStringifySort(SortKeysAsc())}, tt.opts...)
+			want, err := Stringify(obj, opts...)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			got, err := StringifyBytes([]byte("x=1&"), obj, opts...)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if string(got) != "x=1&"+want {
+				t.Errorf("got %q, want %q", got, "x=1&"+want)
+			}
+		})
+	}
+
+	// Not a map, as in Stringify
+	got, err := StringifyBytes(nil, "value")
+	if err != nil || len(got) != 0 {
+		t.Errorf("got %q, %v, want empty", got, err)
+	}
+}
+
+func TestStringifyArrayElementSort(t *testing.T) {
+	byID := WithStringifyArrayElementSort(func(a, b map[string]any) bool {
+		return a["id"].(string) < b["id"].(string)
+	})
+	tests := []struct {
+		name string
+		obj  map[string]any
+		opts []StringifyOption
+		want string
+	}{
+		{"maps", map[string]any{"a": []any{map[string]any{"id": "2"}, map[string]any{"id": "1"}}}, nil,
+			"a[0][id]=1&a[1][id]=2"},
+		{"typed slice", map[string]any{"a": []map[string]any{{"id": "b", "n": "1"}, {"id": "a", "n": "2"}}}, nil,
+			"a[0][id]=a&a[0][n]=2&a[1][id]=b&a[1][n]=1"},
+		{"stable", map[string]any{"a": []any{map[string]any{"id": "1", "n": "x"}, map[string]any{"id": "0"}, map[string]any{"id": "1", "n": "y"}}}, nil,
+			"a[0][id]=0&a[1][id]=1&a[1][n]=x&a[2][id]=1&a[2][n]=y"},
+		{"nested", map[string]any{"o": map[string]any{"a": []any{Object{"id": "2"}, Object{"id": "1"}}}}, nil,
+			"o[a][0][id]=1&o[a][1][id]=2"},
+		{"brackets", map[string]any{"a": []any{map[string]any{"id": "2"}, map[string]any{"id": "1"}}}, []StringifyOption{WithStringifyArrayFormat(ArrayFormatBrackets)},
+			"a[][id]=1&a[][id]=2"},
+		{"mixed kept", map[string]any{"a": []any{map[string]any{"id": "2"}, "x"}}, nil, "a[0][id]=2&a[1]=x"},
+		{"scalars kept", map[string]any{"a": []any{"2", "1"}}, nil, "a[0]=2&a[1]=1"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			opts := append([]StringifyOption{byID, WithStringifyEncode(false), WithStringifySort(SortKeysAsc())}, tt.opts...)
+			got, err := Stringify(tt.obj, opts...)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+
+	// The input slice keeps its order
+	arr := []any{map[string]any{"id": "2"}, map[string]any{"id": "1"}}
+	if _, err := Stringify(map[string]any{"a": arr}, byID); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if arr[0].(map[string]any)["id"] != "2" {
+		t.Errorf("input slice was reordered: %v", arr)
+	}
+}
+
+func TestStringifyJSON(t *testing.T) {
+	tests := []struct {
+		name string
+		m    map[string]any
+		want string
+	}{
+		{"nested", map[string]any{"b": []any{1, "x"}, "a": map[string]any{"c": true}},
+			"%7B%22a%22%3A%7B%22c%22%3Atrue%7D%2C%22b%22%3A%5B1%2C%22x%22%5D%7D"},
+		{"explicit null", map[string]any{"a": ExplicitNullValue}, "%7B%22a%22%3Anull%7D"},
+		{"no html escaping", map[string]any{"q": "a&b<c"}, "%7B%22q%22%3A%22a%26b%3Cc%22%7D"},
+		{"nil", nil, "%7B%7D"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := StringifyJSON(tt.m)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+
+	t.Run("under key", func(t *testing.T) {
+		got, err := StringifyJSONUnder("data[x]", map[string]any{"a": map[string]any{"b": "ü"}})
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if want := "data%5Bx%5D=%7B%22a%22%3A%7B%22b%22%3A%22%C3%BC%22%7D%7D"; got != want {
+			t.Errorf("got %q, want %q", got, want)
+		}
+
+		// Round-trips through Parse
+		parsed, err := Parse(got)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if want := map[string]any{"data": map[string]any{"x": `{"a":{"b":"ü"}}`}}; !reflect.DeepEqual(parsed, want) {
+			t.Errorf("parsed %v, want %v", parsed, want)
+		}
+	})
+
+	t.Run("errors", func(t *testing.T) {
+		if _, err := StringifyJSONUnder("", map[string]any{}); !errors.Is(err, ErrEmptyRootKey) {
+			t.Errorf("got error %v, want ErrEmptyRootKey", err)
+		}
+		if _, err := StringifyJSON(map[string]any{"f": func() {}}); err == nil {
+			t.Error("expected an error for a value JSON cannot encode")
+		}
+	})
+}
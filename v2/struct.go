@@ -11,6 +11,45 @@ import (
 	"time"
 )
 
+// FieldError reports a value that could not be set on a struct field,
+// typically because it does not convert to the field's type. Use errors.As
+// to retrieve it from ParseToStruct, MapToStruct or Unmarshal.
+type FieldError struct {
+	// Key is the query key, e.g. "page" or "filter[page]" for nested structs.
+	Key string
+	// Field is the Go field path, e.g. "Page" or "Filter.Page".
+	Field string
+	// TargetType is the type of the field.
+	TargetType reflect.Type
+	// Err is the underlying conversion error.
+	Err error
+}
+
+// Error implements the error interface.
+func (e *FieldError) Error() string {
+	return fmt.Sprintf("error setting field %s from key %q (%s): %v", e.Field, e.Key, e.TargetType, e.Err)
+}
+
+// Unwrap returns the underlying error.
+func (e *FieldError) Unwrap() error {
+	return e.Err
+}
+
+// newFieldError returns a FieldError for the field at key. If err is itself
+// a FieldError from a nested struct, its key and field are joined onto
+// key and field so the result describes the full path.
+func newFieldError(key, field string, targetType reflect.Type, err error) *FieldError {
+	if inner, ok := err.(*FieldError); ok {
+		return &FieldError{
+			Key:        key + "[" + inner.Key + "]",
+			Field:      field + "." + inner.Field,
+			TargetType: inner.TargetType,
+			Err:        inner.Err,
+		}
+	}
+	return &FieldError{Key: key, Field: field, TargetType: targetType, Err: err}
+}
+
 // ParseToStruct parses a query string and fills a struct using query tags.
 //
 // The function parses the query string into a map using Parse(), then maps
@@ -39,6 +78,22 @@ import (
 //   - map[string]T (maps with string keys)
 //   - nested structs
 //   - pointers to any supported type
+//
+// A `query:"page,default=1"` tag sets the field from the default when the
+// key is absent, converting it like a parsed value. The default option must
+// come last, so the default itself may contain commas.
+//
+// Bracket keys such as "user[name]" fill nested structs, unexported fields
+// are skipped, and the fields of embedded structs are promoted as with
+// encoding/json. A value that does not convert to its field's type is
+// reported as a *FieldError naming the key and field.
+//
+// A map field tagged `query:",rest"` collects the top-level keys no other
+// field matches, e.g. map[string]any for extra parameters. Marshal writes
+// its entries back as top-level keys.
+//
+// Fields without a `query` tag fall back to their `qs` tag, then their
+// `json` tag, as in Marshal. Use WithParseTagName to read a different tag.
 func ParseToStruct(str string, dest any, opts ...ParseOption) error {
 	// Parse to map first
 	result, err := Parse(str, opts...)
@@ -47,7 +102,23 @@ func ParseToStruct(str string, dest any, opts ...ParseOption) error {
 	}
 
 	// Convert map to struct
-	return MapToStruct(result, dest)
+	return mapToStruct(result, dest, applyParseOptions(opts...).TagName)
+}
+
+// ParseInto is ParseToStruct, for callers expecting the name used by
+// other binding libraries.
+//
+// Example:
+//
+//	type Filter struct {
+//	    Name string `qs:"name"`
+//	    Tags []string `qs:"tags"`
+//	}
+//	var f Filter
+//	err := qs.ParseInto("name=a&tags[]=x&tags[]=y", &f)
+//	// f = Filter{Name: "a", Tags: []string{"x", "y"}}
+func ParseInto(query string, dst any, opts ...ParseOption) error {
+	return ParseToStruct(query, dst, opts...)
 }
 
 // MapToStruct converts a map[string]any to a struct using query tags.
@@ -61,6 +132,11 @@ func ParseToStruct(str string, dest any, opts ...ParseOption) error {
 //	var user User
 //	err := qs.MapToStruct(data, &user)
 func MapToStruct(data map[string]any, dest any) error {
+	return mapToStruct(data, dest, defaultTagName)
+}
+
+// mapToStruct is MapToStruct reading tagName tags.
+func mapToStruct(data map[string]any, dest any, tagName string) error {
 	destValue := reflect.ValueOf(dest)
 	if destValue.Kind() != reflect.Ptr {
 		return fmt.Errorf("destination must be a pointer to struct, got %T", dest)
@@ -71,7 +147,7 @@ func MapToStruct(data map[string]any, dest any) error {
 		return fmt.Errorf("destination must be a pointer to struct, got pointer to %s", destValue.Kind())
 	}
 
-	return fillStruct(data, destValue)
+	return fillStruct(data, destValue, tagName)
 }
 
 // StructToQueryString converts a struct to a query string using query tags.
@@ -80,21 +156,36 @@ func MapToStruct(data map[string]any, dest any) error {
 //
 //	user := User{Name: "John", Age: 30, Email: "john@example.com"}
 //	str, err := qs.StructToQueryString(user)
-//	// str = "age=30&email=john%40example.com&name=John"
+//	// str = "name=John&age=30&email=john%40example.com"
+//
+// Struct fields are written in declaration order, also in nested structs,
+// unless Sort is set. Keys of map fields have no set order.
 func StructToQueryString(obj any, opts ...StringifyOption) (string, error) {
-	data, err := StructToMap(obj)
+	m := &marshaler{tagName: applyStringifyOptions(opts...).TagName, fieldOrder: make(map[uintptr][]string)}
+	data, err := m.structToMap(obj)
 	if err != nil {
 		return "", err
 	}
 
-	return Stringify(data, opts...)
+	return stringifyInOrder(data, m.keyOrder, opts...)
 }
 
 // StructToMap converts a struct to a map[string]any using query tags.
 //
 // Fields are named by their `query` tag. If no tag is present, the lowercase
-// field name is used. Use `query:"-"` to skip a field.
+// field name is used. Use `query:"-"` to skip a field, and
+// `query:"name,omitempty"` to skip it when it holds an empty value (false,
+// 0, "", a nil pointer, or an empty slice or map), as in encoding/json.
+// Fields without a `query` tag fall back to their `json` tag, so types
+// already annotated for encoding/json need no second set of tags. Fields
+// of untagged embedded structs are promoted, as ParseToStruct reads them.
 func StructToMap(obj any) (map[string]any, error) {
+	m := &marshaler{tagName: defaultTagName}
+	return m.structToMap(obj)
+}
+
+// structToMap is StructToMap reading the marshaler's tags.
+func (m *marshaler) structToMap(obj any) (map[string]any, error) {
 	objValue := reflect.ValueOf(obj)
 	if objValue.Kind() == reflect.Ptr {
 		if objValue.IsNil() {
@@ -107,7 +198,7 @@ func StructToMap(obj any) (map[string]any, error) {
 		return nil, fmt.Errorf("object must be a struct or pointer to struct, got %s", objValue.Kind())
 	}
 
-	return marshalStruct(objValue)
+	return m.marshalStruct(objValue)
 }
 
 // Marshal converts a value to a query string.
@@ -120,17 +211,22 @@ func StructToMap(obj any) (map[string]any, error) {
 //	// Marshal struct
 //	user := User{Name: "John", Age: 30}
 //	str, err := qs.Marshal(user)
-//	// str = "age=30&name=John"
+//	// str = "name=John&age=30"
 //
 //	// Marshal map
 //	data := map[string]any{"name": "John", "age": 30}
 //	str, err := qs.Marshal(data)
+//
+// Struct fields are named as in StructToMap. Use WithStringifyTagName to
+// read a different tag, e.g. WithStringifyTagName("json"), and written in
+// order as in StructToQueryString.
 func Marshal(v any, opts ...StringifyOption) (string, error) {
 	if v == nil {
 		return "", nil
 	}
 
-	data, err := marshalValue(v)
+	m := &marshaler{tagName: applyStringifyOptions(opts...).TagName, fieldOrder: make(map[uintptr][]string)}
+	data, err := m.marshalValue(v)
 	if err != nil {
 		return "", err
 	}
@@ -141,58 +237,140 @@ func Marshal(v any, opts ...StringifyOption) (string, error) {
 		return "", nil
 	}
 
-	return Stringify(dataMap, opts...)
+	return stringifyInOrder(dataMap, m.keyOrder, opts...)
 }
 
 // fillStruct recursively fills struct fields from map data.
-func fillStruct(data map[string]any, structValue reflect.Value) error {
-	structType := structValue.Type()
+// Fields of untagged embedded structs are filled from the parent's keys.
+func fillStruct(data map[string]any, structValue reflect.Value, tagName string) error {
+	info := getStructInfo(structValue.Type(), tagName)
 
-	for i := 0; i < structValue.NumField(); i++ {
-		field := structValue.Field(i)
-		fieldType := structType.Field(i)
+	for _, name := range info.order {
+		fi := info.fields[name]
 
-		// Skip unexported fields
-		if !field.CanSet() {
-			continue
+		// Look for the value in data, falling back to the tag default
+		value, exists := data[name]
+		if !exists {
+			if !fi.hasDefault {
+				continue
+			}
+			value = fi.def
 		}
 
-		// Get query tag
-		queryTag := getQueryTag(fieldType)
-		if queryTag == "-" {
+		// Skip unexported fields
+		field, ok := fieldByIndex(structValue, fi.index)
+		if !ok || !field.CanSet() {
 			continue
 		}
 
-		// Look for the value in data
-		value, exists := data[queryTag]
-		if !exists {
-			continue
+		if err := setFieldValue(field, value, tagName); err != nil {
+			return newFieldError(name, fi.name, fi.fieldType, err)
 		}
+	}
 
-		if err := setFieldValue(field, value); err != nil {
-			return fmt.Errorf("error setting field %s: %w", fieldType.Name, err)
+	// Collect unmatched keys in the rest field
+	if info.rest != nil {
+		rest := make(map[string]any)
+		for k, v := range data {
+			if _, ok := info.fields[k]; !ok {
+				rest[k] = v
+			}
+		}
+		field, ok := fieldByIndex(structValue, info.rest.index)
+		if len(rest) > 0 && ok && field.CanSet() {
+			if err := setFieldValue(field, rest, tagName); err != nil {
+				return newFieldError(info.rest.name, info.rest.name, info.rest.fieldType, err)
+			}
 		}
 	}
 
 	return nil
 }
 
-// getQueryTag returns the query tag name for a struct field.
-// Falls back to lowercase field name if no tag is present.
-func getQueryTag(field reflect.StructField) string {
-	tag := field.Tag.Get("query")
-	if tag == "" {
-		return strings.ToLower(field.Name)
+// defaultTagName is the struct tag read when no other tag name is set.
+const defaultTagName = "query"
+
+// structTag returns the field's tagName tag, falling back to its qs tag
+// and then its json tag when the field has no tagName tag. Marshaling and
+// parsing both name fields through it, so they read the same tags.
+func structTag(field reflect.StructField, tagName string) string {
+	if tagName == "" {
+		tagName = defaultTagName
+	}
+	if tag, ok := field.Tag.Lookup(tagName); ok {
+		return tag
 	}
+	if tag, ok := field.Tag.Lookup("qs"); ok {
+		return tag
+	}
+	return field.Tag.Get("json")
+}
+
+// getQueryTag returns the tagName tag name for a struct field.
+// Falls back to lowercase field name if no tag is present.
+func getQueryTag(field reflect.StructField, tagName string) string {
+	tag := structTag(field, tagName)
 	// Handle comma-separated options (e.g., `query:"name,omitempty"`)
 	if idx := strings.Index(tag, ","); idx != -1 {
-		return tag[:idx]
+		tag = tag[:idx]
+	}
+	if tag == "" {
+		return strings.ToLower(field.Name)
 	}
 	return tag
 }
 
-// setFieldValue sets a struct field value from any data.
-func setFieldValue(field reflect.Value, value any) error {
+// hasQueryTagOption reports whether the field's tagName tag lists option
+// after the name, e.g. `query:"name,omitempty"`.
+func hasQueryTagOption(field reflect.StructField, tagName, option string) bool {
+	tag := structTag(field, tagName)
+	idx := strings.Index(tag, ",")
+	if idx == -1 {
+		return false
+	}
+	for _, opt := range strings.Split(tag[idx+1:], ",") {
+		if opt == option {
+			return true
+		}
+	}
+	return false
+}
+
+// queryTagDefault returns the value of the default option in a query tag,
+// e.g. "1" for `query:"page,default=1"`. The option must be last; the
+// default is the rest of the tag.
+func queryTagDefault(tag string) (string, bool) {
+	idx := strings.Index(tag, ",default=")
+	if idx == -1 {
+		return "", false
+	}
+	return tag[idx+len(",default="):], true
+}
+
+// isEmptyValue reports whether v is empty for omitempty, following
+// encoding/json: false, 0, a nil pointer or interface, and an empty string,
+// slice, array or map.
+func isEmptyValue(v reflect.Value) bool {
+	switch v.Kind() {
+	case reflect.Array, reflect.Map, reflect.Slice, reflect.String:
+		return v.Len() == 0
+	case reflect.Bool:
+		return !v.Bool()
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return v.Int() == 0
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return v.Uint() == 0
+	case reflect.Float32, reflect.Float64:
+		return v.Float() == 0
+	case reflect.Interface, reflect.Ptr:
+		return v.IsNil()
+	}
+	return false
+}
+
+// setFieldValue sets a struct field value from any data, naming the
+// fields of nested structs by their tagName tags.
+func setFieldValue(field reflect.Value, value any, tagName string) error {
 	if value == nil {
 		return nil
 	}
@@ -204,7 +382,7 @@ func setFieldValue(field reflect.Value, value any) error {
 		if field.IsNil() {
 			field.Set(reflect.New(fieldType.Elem()))
 		}
-		return setFieldValue(field.Elem(), value)
+		return setFieldValue(field.Elem(), value, tagName)
 	}
 
 	// Handle time.Time specially
@@ -231,17 +409,17 @@ func setFieldValue(field reflect.Value, value any) error {
 		return setBoolField(field, value)
 
 	case reflect.Slice:
-		return setSliceField(field, value)
+		return setSliceField(field, value, tagName)
 
 	case reflect.Struct:
 		if dataMap, ok := value.(map[string]any); ok {
-			return fillStruct(dataMap, field)
+			return fillStruct(dataMap, field, tagName)
 		}
 		return fmt.Errorf("cannot convert %T to struct", value)
 
 	case reflect.Map:
 		if fieldType.Key().Kind() == reflect.String {
-			return setMapField(field, value)
+			return setMapField(field, value, tagName)
 		}
 		return fmt.Errorf("unsupported map key type: %v", fieldType.Key().Kind())
 
@@ -412,7 +590,7 @@ func setTimeField(field reflect.Value, value any) error {
 }
 
 // setSliceField sets a slice field from any value.
-func setSliceField(field reflect.Value, value any) error {
+func setSliceField(field reflect.Value, value any, tagName string) error {
 	var sliceValue []any
 
 	switch v := value.(type) {
@@ -431,7 +609,7 @@ func setSliceField(field reflect.Value, value any) error {
 
 	for i, item := range sliceValue {
 		elemField := newSlice.Index(i)
-		if err := setFieldValue(elemField, item); err != nil {
+		if err := setFieldValue(elemField, item, tagName); err != nil {
 			return fmt.Errorf("error setting slice element %d: %w", i, err)
 		}
 	}
@@ -441,7 +619,7 @@ func setSliceField(field reflect.Value, value any) error {
 }
 
 // setMapField sets a map field from any value.
-func setMapField(field reflect.Value, value any) error {
+func setMapField(field reflect.Value, value any, tagName string) error {
 	dataMap, ok := value.(map[string]any)
 	if !ok {
 		return fmt.Errorf("cannot convert %T to map", value)
@@ -456,7 +634,7 @@ func setMapField(field reflect.Value, value any) error {
 		keyVal := reflect.ValueOf(k)
 		valueVal := reflect.New(valueType).Elem()
 
-		if err := setFieldValue(valueVal, v); err != nil {
+		if err := setFieldValue(valueVal, v, tagName); err != nil {
 			return fmt.Errorf("error setting map value for key %q: %w", k, err)
 		}
 
@@ -498,24 +676,50 @@ func mapToSlice(m map[string]any) []any {
 	return result
 }
 
+// marshaler converts Go values to the maps and slices Stringify takes.
+type marshaler struct {
+	tagName string
+	// fieldOrder holds the keys of each map built from a struct in field
+	// order, by map pointer. Nil when the order is not needed.
+	fieldOrder map[uintptr][]string
+}
+
+// keyOrder returns the field order of a map built from a struct, or nil
+// for other maps.
+func (m *marshaler) keyOrder(obj map[string]any) []string {
+	return m.fieldOrder[reflect.ValueOf(obj).Pointer()]
+}
+
+// popKeyOrder returns the field order of a map built from a struct and
+// forgets it, for maps merged into another.
+func (m *marshaler) popKeyOrder(obj map[string]any) []string {
+	if m.fieldOrder == nil {
+		return nil
+	}
+	ptr := reflect.ValueOf(obj).Pointer()
+	order := m.fieldOrder[ptr]
+	delete(m.fieldOrder, ptr)
+	return order
+}
+
 // marshalValue converts a value to a format suitable for Stringify.
-func marshalValue(v any) (any, error) {
+func (m *marshaler) marshalValue(v any) (any, error) {
 	if v == nil {
 		return nil, nil
 	}
 
 	rv := reflect.ValueOf(v)
-	return marshalReflectValue(rv)
+	return m.marshalReflectValue(rv)
 }
 
 // marshalReflectValue converts a reflect.Value to a format suitable for Stringify.
-func marshalReflectValue(rv reflect.Value) (any, error) {
+func (m *marshaler) marshalReflectValue(rv reflect.Value) (any, error) {
 	// Handle pointers
 	if rv.Kind() == reflect.Ptr {
 		if rv.IsNil() {
 			return nil, nil
 		}
-		return marshalReflectValue(rv.Elem())
+		return m.marshalReflectValue(rv.Elem())
 	}
 
 	// Handle time.Time specially
@@ -529,38 +733,86 @@ func marshalReflectValue(rv reflect.Value) (any, error) {
 
 	switch rv.Kind() {
 	case reflect.Struct:
-		return marshalStruct(rv)
+		return m.marshalStruct(rv)
 	case reflect.Map:
-		return marshalMap(rv)
-	case reflect.Slice:
-		return marshalSlice(rv)
+		return m.marshalMap(rv)
+	case reflect.Slice, reflect.Array:
+		return m.marshalSlice(rv)
 	case reflect.Interface:
 		if rv.IsNil() {
 			return nil, nil
 		}
-		return marshalReflectValue(rv.Elem())
+		return m.marshalReflectValue(rv.Elem())
 	default:
 		// Return primitive values as-is
 		return rv.Interface(), nil
 	}
 }
 
-// marshalStruct converts a struct to a map using query tags.
-func marshalStruct(rv reflect.Value) (map[string]any, error) {
+// marshalStruct converts a struct to a map using the marshaler's tags.
+//
+// Fields of embedded structs without a tag name are promoted to the parent
+// at the embedded field's position, as ParseToStruct reads them. Fields of
+// the parent take precedence over promoted ones with the same name, and
+// earlier embedded structs over later ones. The entries of a rest map are
+// merged the same way.
+func (m *marshaler) marshalStruct(rv reflect.Value) (map[string]any, error) {
 	result := make(map[string]any)
 	rt := rv.Type()
+	var order []string
+
+	// Promoted fields are merged once the parent's own fields are known
+	type promoted struct {
+		pos    int
+		fields map[string]any
+		order  []string
+	}
+	var embedded []promoted
 
 	for i := 0; i < rv.NumField(); i++ {
 		field := rv.Field(i)
 		fieldType := rt.Field(i)
 
+		// Promote fields of untagged embedded structs
+		if name, _, _ := strings.Cut(structTag(fieldType, m.tagName), ","); fieldType.Anonymous && name == "" {
+			ft := fieldType.Type
+			if ft.Kind() == reflect.Ptr {
+				ft = ft.Elem()
+			}
+			if ft.Kind() == reflect.Struct && ft != reflect.TypeOf(time.Time{}) {
+				if field.Kind() == reflect.Ptr {
+					if field.IsNil() {
+						continue
+					}
+					field = field.Elem()
+				}
+				fields, err := m.marshalStruct(field)
+				if err != nil {
+					return nil, fmt.Errorf("error marshaling field %s: %w", fieldType.Name, err)
+				}
+				fieldsOrder := m.popKeyOrder(fields)
+				embedded = append(embedded, promoted{pos: len(order), fields: fields, order: fieldsOrder})
+				continue
+			}
+		}
+
 		// Skip unexported fields
 		if !field.CanInterface() {
 			continue
 		}
 
+		// Write the entries of a rest map as the struct's own keys
+		if hasQueryTagOption(fieldType, m.tagName, "rest") && field.Kind() == reflect.Map {
+			fields, err := m.marshalMap(field)
+			if err != nil {
+				return nil, fmt.Errorf("error marshaling field %s: %w", fieldType.Name, err)
+			}
+			embedded = append(embedded, promoted{pos: len(order), fields: fields})
+			continue
+		}
+
 		// Get query tag
-		queryTag := getQueryTag(fieldType)
+		queryTag := getQueryTag(fieldType, m.tagName)
 		if queryTag == "-" {
 			continue
 		}
@@ -570,6 +822,11 @@ func marshalStruct(rv reflect.Value) (map[string]any, error) {
 			continue
 		}
 
+		// Skip empty values tagged omitempty
+		if hasQueryTagOption(fieldType, m.tagName, "omitempty") && isEmptyValue(field) {
+			continue
+		}
+
 		// Skip zero time.Time
 		if field.Type() == reflect.TypeOf(time.Time{}) {
 			t := field.Interface().(time.Time)
@@ -579,21 +836,43 @@ func marshalStruct(rv reflect.Value) (map[string]any, error) {
 		}
 
 		// Marshal field value
-		fieldValue, err := marshalReflectValue(field)
+		fieldValue, err := m.marshalReflectValue(field)
 		if err != nil {
 			return nil, fmt.Errorf("error marshaling field %s: %w", fieldType.Name, err)
 		}
 
 		if fieldValue != nil {
+			if _, seen := result[queryTag]; !seen {
+				order = append(order, queryTag)
+			}
 			result[queryTag] = fieldValue
 		}
 	}
 
+	if len(embedded) > 0 {
+		own, next := order, 0
+		order = nil
+		for _, e := range embedded {
+			order = append(order, own[next:e.pos]...)
+			next = e.pos
+			for _, k := range orderedKeys(e.fields, func(map[string]any) []string { return e.order }) {
+				if _, exists := result[k]; !exists {
+					result[k] = e.fields[k]
+					order = append(order, k)
+				}
+			}
+		}
+		order = append(order, own[next:]...)
+	}
+
+	if m.fieldOrder != nil {
+		m.fieldOrder[reflect.ValueOf(result).Pointer()] = order
+	}
 	return result, nil
 }
 
 // marshalMap converts a map to a format suitable for Stringify.
-func marshalMap(rv reflect.Value) (map[string]any, error) {
+func (m *marshaler) marshalMap(rv reflect.Value) (map[string]any, error) {
 	if rv.IsNil() {
 		return nil, nil
 	}
@@ -604,7 +883,7 @@ func marshalMap(rv reflect.Value) (map[string]any, error) {
 		keyStr := fmt.Sprintf("%v", key.Interface())
 		value := rv.MapIndex(key)
 
-		marshaledValue, err := marshalReflectValue(value)
+		marshaledValue, err := m.marshalReflectValue(value)
 		if err != nil {
 			return nil, fmt.Errorf("error marshaling map value for key %q: %w", keyStr, err)
 		}
@@ -617,9 +896,11 @@ func marshalMap(rv reflect.Value) (map[string]any, error) {
 	return result, nil
 }
 
-// marshalSlice converts a slice to []any.
-func marshalSlice(rv reflect.Value) ([]any, error) {
-	if rv.IsNil() {
+// marshalSlice converts a slice or array to []any. Struct elements, and
+// pointers to them, become maps, so a []Item field stringifies as
+// items[0][sku]=x. Nil pointer elements are skipped.
+func (m *marshaler) marshalSlice(rv reflect.Value) ([]any, error) {
+	if rv.Kind() == reflect.Slice && rv.IsNil() {
 		return nil, nil
 	}
 
@@ -627,7 +908,7 @@ func marshalSlice(rv reflect.Value) ([]any, error) {
 
 	for i := 0; i < rv.Len(); i++ {
 		elem := rv.Index(i)
-		marshaledElem, err := marshalReflectValue(elem)
+		marshaledElem, err := m.marshalReflectValue(elem)
 		if err != nil {
 			return nil, fmt.Errorf("error marshaling slice element %d: %w", i, err)
 		}
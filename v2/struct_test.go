@@ -4,7 +4,9 @@
 package qs
 
 import (
+	"errors"
 	"reflect"
+	"strconv"
 	"strings"
 	"testing"
 	"time"
@@ -367,6 +369,226 @@ func TestStructToQueryString(t *testing.T) {
 	}
 }
 
+// TestStructFieldOrder verifies struct fields are written in declaration order
+func TestStructFieldOrder(t *testing.T) {
+	type Item struct {
+		SKU string `query:"sku"`
+		Qty int    `query:"qty"`
+	}
+	type Filter struct {
+		Status string `query:"status"`
+		Min    int    `query:"min"`
+	}
+	type Request struct {
+		Zone   string         `query:"zone"`
+		Filter Filter         `query:"filter"`
+		Items  []Item         `query:"items"`
+		Extra  map[string]any `query:"extra"`
+		Alpha  string         `query:"alpha"`
+	}
+	req := Request{
+		Zone:   "eu",
+		Filter: Filter{Status: "open", Min: 2},
+		Items:  []Item{{SKU: "x", Qty: 1}},
+		Extra:  map[string]any{"k": "v"},
+		Alpha:  "a",
+	}
+	want := "zone=eu&filter[status]=open&filter[min]=2&items[0][sku]=x&items[0][qty]=1&extra[k]=v&alpha=a"
+
+	for name, stringify := range map[string]func(any, ...StringifyOption) (string, error){
+		"StructToQueryString": StructToQueryString,
+		"Marshal":             Marshal,
+	} {
+		t.Run(name, func(t *testing.T) {
+			// Repeat to catch map iteration order leaking through
+			for i := 0; i < 20; i++ {
+				got, err := stringify(req, WithStringifyEncode(false))
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				if got != want {
+					t.Fatalf("got %q, want %q", got, want)
+				}
+			}
+
+			got, err := stringify(req, WithStringifyEncode(false), WithStringifySort(SortKeysAsc()))
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if sorted := "alpha=a&extra[k]=v&filter[min]=2&filter[status]=open&items[0][qty]=1&items[0][sku]=x&zone=eu"; got != sorted {
+				t.Errorf("with Sort got %q, want %q", got, sorted)
+			}
+		})
+	}
+}
+
+// TestStructRestField verifies a rest field collects unmatched keys
+func TestStructRestField(t *testing.T) {
+	type Pagination struct {
+		Page int `query:"page"`
+	}
+	type Search struct {
+		Pagination
+		Query string         `query:"q"`
+		Extra map[string]any `query:",rest"`
+	}
+
+	for name, parse := range map[string]func(string, any) error{
+		"ParseToStruct": func(q string, dest any) error { return ParseToStruct(q, dest) },
+		"Unmarshal":     func(q string, dest any) error { return Unmarshal(q, dest) },
+	} {
+		t.Run(name, func(t *testing.T) {
+			var s Search
+			if err := parse("q=go&page=2&utm_source=mail&f[a]=1&f[b]=2&tag=x&tag=y", &s); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if s.Query != "go" || s.Page != 2 {
+				t.Errorf("got Query %q, Page %d; want go, 2", s.Query, s.Page)
+			}
+			want := map[string]any{
+				"utm_source": "mail",
+				"f":          map[string]any{"a": "1", "b": "2"},
+				"tag":        []any{"x", "y"},
+			}
+			if !reflect.DeepEqual(s.Extra, want) {
+				t.Errorf("Extra = %v, want %v", s.Extra, want)
+			}
+
+			var known Search
+			if err := parse("q=go", &known); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if known.Extra != nil {
+				t.Errorf("Extra = %v, want nil", known.Extra)
+			}
+		})
+	}
+
+	t.Run("typed map", func(t *testing.T) {
+		var s struct {
+			Query string            `query:"q"`
+			Rest  map[string]string `query:",rest"`
+		}
+		if err := ParseToStruct("q=go&a=1&b=2", &s); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if want := map[string]string{"a": "1", "b": "2"}; !reflect.DeepEqual(s.Rest, want) {
+			t.Errorf("Rest = %v, want %v", s.Rest, want)
+		}
+	})
+
+	t.Run("marshal", func(t *testing.T) {
+		s := Search{Query: "go", Extra: map[string]any{"q": "shadowed", "a": "1"}}
+		got, err := Marshal(s, WithStringifyEncode(false))
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if want := "page=0&q=go&a=1"; got != want {
+			t.Errorf("got %q, want %q", got, want)
+		}
+	})
+
+	t.Run("qs tag", func(t *testing.T) {
+		var s struct {
+			Query string         `qs:"q"`
+			Extra map[string]any `qs:",rest"`
+		}
+		if err := ParseInto("q=go&a=1&b[c]=2", &s); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if want := map[string]any{"a": "1", "b": map[string]any{"c": "2"}}; s.Query != "go" || !reflect.DeepEqual(s.Extra, want) {
+			t.Errorf("got Query %q, Extra %v; want go, %v", s.Query, s.Extra, want)
+		}
+	})
+
+	t.Run("custom tag name round trip", func(t *testing.T) {
+		type Form struct {
+			Query string         `form:"q"`
+			Extra map[string]any `form:",rest"`
+		}
+		in := Form{Query: "go", Extra: map[string]any{"a": "1"}}
+		query, err := Marshal(in, WithStringifyTagName("form"))
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if want := "q=go&a=1"; query != want {
+			t.Errorf("Marshal() = %q, want %q", query, want)
+		}
+		for name, parse := range map[string]func(string, any, ...ParseOption) error{
+			"ParseToStruct": ParseToStruct,
+			"Unmarshal":     Unmarshal,
+		} {
+			var out Form
+			if err := parse(query, &out, WithParseTagName("form")); err != nil {
+				t.Fatalf("%s: unexpected error: %v", name, err)
+			}
+			if !reflect.DeepEqual(out, in) {
+				t.Errorf("%s = %+v, want %+v", name, out, in)
+			}
+		}
+	})
+}
+
+// TestMarshalEmbedded verifies embedded struct fields are promoted
+func TestMarshalEmbedded(t *testing.T) {
+	type Pagination struct {
+		Page  int `query:"page"`
+		Limit int `query:"limit"`
+	}
+	type Sorting struct {
+		Sort string `query:"sort"`
+	}
+	type Tagged struct {
+		X string `query:"x"`
+	}
+	type ListParams struct {
+		Query string `query:"q"`
+		Pagination
+		*Sorting
+		Tagged `query:"tagged"`
+		Limit  int `query:"limit"`
+		secret string
+		Skip   string `query:"-"`
+	}
+	p := ListParams{
+		Query:      "go",
+		Pagination: Pagination{Page: 3, Limit: 50},
+		Sorting:    &Sorting{Sort: "name"},
+		Tagged:     Tagged{X: "1"},
+		Limit:      5,
+		secret:     "s",
+		Skip:       "s",
+	}
+
+	got, err := Marshal(p, WithStringifyEncode(false))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	// The outer limit shadows the promoted one
+	if want := "q=go&page=3&sort=name&tagged[x]=1&limit=5"; got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+
+	var back ListParams
+	if err := ParseToStruct(got, &back); err != nil {
+		t.Fatalf("ParseToStruct() error = %v", err)
+	}
+	if back.Page != 3 || back.Limit != 5 || back.Sorting == nil || back.Sort != "name" || back.Tagged.X != "1" {
+		t.Errorf("round trip got %+v", back)
+	}
+
+	// Nil embedded pointers are skipped
+	p.Sorting = nil
+	m, err := StructToMap(p)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	want := map[string]any{"q": "go", "page": 3, "limit": 5, "tagged": map[string]any{"x": "1"}}
+	if !reflect.DeepEqual(m, want) {
+		t.Errorf("StructToMap() = %v, want %v", m, want)
+	}
+}
+
 // TestStructToQueryStringSkipTag verifies skip tag works
 func TestStructToQueryStringSkipTag(t *testing.T) {
 	user := UserWithSkip{
@@ -549,6 +771,103 @@ func TestMarshalSliceStruct(t *testing.T) {
 	}
 }
 
+// TestMarshalJSONTags tests the json tag fallback and WithStringifyTagName
+func TestMarshalJSONTags(t *testing.T) {
+	type DTO struct {
+		ID      int    `json:"id"`
+		Name    string `json:"full_name,omitempty"`
+		Note    string `json:"note,omitempty"`
+		Secret  string `json:"-"`
+		Page    int    `query:"p" json:"page"`
+		Untyped string
+	}
+	dto := DTO{ID: 7, Note: "", Secret: "x", Page: 2, Untyped: "u"}
+
+	tests := []struct {
+		name string
+		opts []StringifyOption
+		want string
+	}{
+		{"json fallback", nil, "id=7&p=2&untyped=u"},
+		{"json tag name", []StringifyOption{WithStringifyTagName("json")}, "id=7&page=2&untyped=u"},
+		{"other tag name", []StringifyOption{WithStringifyTagName("form")}, "id=7&page=2&untyped=u"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			opts := append([]StringifyOption{WithStringifySort(func(a, b string) bool { return a < b })}, tt.opts...)
+			got, err := Marshal(dto, opts...)
+			if err != nil {
+				t.Fatalf("Marshal() error = %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("Marshal() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+
+	dto.Name = "Ann"
+	got, err := StructToQueryString(dto, WithStringifyTagName("json"), WithStringifySort(func(a, b string) bool { return a < b }))
+	if err != nil {
+		t.Fatalf("StructToQueryString() error = %v", err)
+	}
+	if want := "full_name=Ann&id=7&page=2&untyped=u"; got != want {
+		t.Errorf("StructToQueryString() = %q, want %q", got, want)
+	}
+}
+
+// TestJSONTagsRoundTrip verifies parsing reads the same tags as Marshal
+func TestJSONTagsRoundTrip(t *testing.T) {
+	type DTO struct {
+		UserName string `json:"user_name"`
+		Age      int    `json:"age"`
+		Page     int    `query:"p" json:"page"`
+	}
+	dto := DTO{UserName: "x", Age: 3, Page: 2}
+
+	tests := []struct {
+		name      string
+		tagName   string
+		wantQuery string
+	}{
+		{"json fallback", "", "user_name=x&age=3&p=2"},
+		{"json tag name", "json", "user_name=x&age=3&page=2"},
+		{"other tag name", "form", "user_name=x&age=3&page=2"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var sopts []StringifyOption
+			var popts []ParseOption
+			if tt.tagName != "" {
+				sopts = append(sopts, WithStringifyTagName(tt.tagName))
+				popts = append(popts, WithParseTagName(tt.tagName))
+			}
+			query, err := Marshal(dto, sopts...)
+			if err != nil {
+				t.Fatalf("Marshal() error = %v", err)
+			}
+			if query != tt.wantQuery {
+				t.Errorf("Marshal() = %q, want %q", query, tt.wantQuery)
+			}
+
+			var parsed, unmarshaled DTO
+			if err := ParseToStruct(query, &parsed, popts...); err != nil {
+				t.Fatalf("ParseToStruct() error = %v", err)
+			}
+			if parsed != dto {
+				t.Errorf("ParseToStruct() = %+v, want %+v", parsed, dto)
+			}
+			if err := Unmarshal(query, &unmarshaled, popts...); err != nil {
+				t.Fatalf("Unmarshal() error = %v", err)
+			}
+			if unmarshaled != dto {
+				t.Errorf("Unmarshal() = %+v, want %+v", unmarshaled, dto)
+			}
+		})
+	}
+}
+
 // TestStructToMap tests StructToMap conversion
 func TestStructToMap(t *testing.T) {
 	user := SimpleUser{
@@ -1613,3 +1932,363 @@ func TestMarshalWithStringifyFilter(t *testing.T) {
 		t.Errorf("c = %v, want 3", parsed["c"])
 	}
 }
+
+// TestMarshalSliceOfStructs tests slices and arrays of structs and struct pointers
+func TestMarshalSliceOfStructs(t *testing.T) {
+	type Item struct {
+		SKU string `query:"sku"`
+		Qty int    `query:"qty"`
+	}
+	type Order struct {
+		Items  []Item    `query:"items"`
+		Ptrs   []*Item   `query:"ptrs"`
+		Grid   [][]Item  `query:"grid"`
+		Pair   [2]Item   `query:"pair"`
+		Empty  []Item    `query:"empty"`
+		Skip   []*Item   `query:"skip"`
+		Hidden []Item    `query:"-"`
+		Multi  [][]*Item `query:"multi"`
+	}
+
+	order := Order{
+		Items: []Item{{SKU: "x", Qty: 2}, {SKU: "y", Qty: 1}},
+		Ptrs:  []*Item{{SKU: "p", Qty: 3}},
+		Grid:  [][]Item{{{SKU: "g", Qty: 4}}},
+		Pair:  [2]Item{{SKU: "a", Qty: 5}, {SKU: "b", Qty: 6}},
+		Skip:  []*Item{nil, {SKU: "s", Qty: 7}},
+		Multi: [][]*Item{{nil}, {{SKU: "m", Qty: 8}}},
+	}
+	sortKeys := WithStringifySort(func(a, b string) bool { return a < b })
+
+	tests := []struct {
+		format ArrayFormat
+		want   string
+	}{
+		{ArrayFormatIndices, "grid[0][0][qty]=4&grid[0][0][sku]=g&items[0][qty]=2&items[0][sku]=x&items[1][qty]=1&items[1][sku]=y&" +
+			"multi[1][0][qty]=8&multi[1][0][sku]=m&pair[0][qty]=5&pair[0][sku]=a&pair[1][qty]=6&pair[1][sku]=b&" +
+			"ptrs[0][qty]=3&ptrs[0][sku]=p&skip[1][qty]=7&skip[1][sku]=s"},
+		{ArrayFormatBrackets, "grid[][][qty]=4&grid[][][sku]=g&items[][qty]=2&items[][sku]=x&items[][qty]=1&items[][sku]=y&" +
+			"multi[][][qty]=8&multi[][][sku]=m&pair[][qty]=5&pair[][sku]=a&pair[][qty]=6&pair[][sku]=b&" +
+			"ptrs[][qty]=3&ptrs[][sku]=p&skip[][qty]=7&skip[][sku]=s"},
+		{ArrayFormatRepeat, "grid[qty]=4&grid[sku]=g&items[qty]=2&items[sku]=x&items[qty]=1&items[sku]=y&" +
+			"multi[qty]=8&multi[sku]=m&pair[qty]=5&pair[sku]=a&pair[qty]=6&pair[sku]=b&" +
+			"ptrs[qty]=3&ptrs[sku]=p&skip[qty]=7&skip[sku]=s"},
+	}
+
+	for _, tt := range tests {
+		t.Run(string(tt.format), func(t *testing.T) {
+			str, err := StructToQueryString(order, sortKeys, WithStringifyEncodeValuesOnly(true), WithStringifyArrayFormat(tt.format))
+			if err != nil {
+				t.Fatalf("StructToQueryString() error = %v", err)
+			}
+			if str != tt.want {
+				t.Errorf("StructToQueryString() =\n%s\nwant\n%s", str, tt.want)
+			}
+
+			str, err = Marshal(&order, sortKeys, WithStringifyEncodeValuesOnly(true), WithStringifyArrayFormat(tt.format))
+			if err != nil {
+				t.Fatalf("Marshal() error = %v", err)
+			}
+			if str != tt.want {
+				t.Errorf("Marshal() =\n%s\nwant\n%s", str, tt.want)
+			}
+		})
+	}
+}
+
+// TestMarshalOmitEmpty tests the omitempty tag option
+func TestMarshalOmitEmpty(t *testing.T) {
+	type Tagged struct {
+		Str   string         `query:"str,omitempty"`
+		Int   int            `query:"int,omitempty"`
+		Uint  uint           `query:"uint,omitempty"`
+		Float float64        `query:"float,omitempty"`
+		Bool  bool           `query:"bool,omitempty"`
+		Ptr   *string        `query:"ptr,omitempty"`
+		Slice []string       `query:"slice,omitempty"`
+		Map   map[string]any `query:"map,omitempty"`
+		Any   any            `query:"any,omitempty"`
+	}
+	type Untagged struct {
+		Str   string   `query:"str"`
+		Int   int      `query:"int"`
+		Float float64  `query:"float"`
+		Bool  bool     `query:"bool"`
+		Slice []string `query:"slice"`
+	}
+
+	m, err := StructToMap(Tagged{Slice: []string{}, Map: map[string]any{}})
+	if err != nil {
+		t.Fatalf("StructToMap() error = %v", err)
+	}
+	if len(m) != 0 {
+		t.Errorf("zero values tagged omitempty should be omitted, got %v", m)
+	}
+
+	s := "x"
+	m, err = StructToMap(Tagged{Str: "a", Int: 1, Uint: 2, Float: 1.5, Bool: true, Ptr: &s, Slice: []string{"b"}, Map: map[string]any{"c": "d"}, Any: 0})
+	if err != nil {
+		t.Fatalf("StructToMap() error = %v", err)
+	}
+	for _, key := range []string{"str", "int", "uint", "float", "bool", "ptr", "slice", "map", "any"} {
+		if _, ok := m[key]; !ok {
+			t.Errorf("non-empty %s should be included, got %v", key, m)
+		}
+	}
+
+	str, err := Marshal(Untagged{}, WithStringifySort(func(a, b string) bool { return a < b }))
+	if err != nil {
+		t.Fatalf("Marshal() error = %v", err)
+	}
+	if str != "bool=false&float=0&int=0&str=" {
+		t.Errorf("zero values without omitempty should be included, got %q", str)
+	}
+
+	// Tag without a name falls back to the field name
+	type NoName struct {
+		Name string `query:",omitempty"`
+	}
+	str, err = Marshal(NoName{Name: "a"})
+	if err != nil {
+		t.Fatalf("Marshal() error = %v", err)
+	}
+	if str != "name=a" {
+		t.Errorf("Marshal() = %q, want %q", str, "name=a")
+	}
+}
+
+// TestParseToStructDefaultTag tests the default tag option
+func TestParseToStructDefaultTag(t *testing.T) {
+	type Filter struct {
+		Sort  string `query:"sort,default=created"`
+		Order string `query:"order"`
+	}
+	type ListParams struct {
+		Page    int      `query:"page,default=1"`
+		Size    uint     `query:"size,default=20"`
+		Active  bool     `query:"active,default=true"`
+		Ratio   float64  `query:"ratio,default=0.5"`
+		Tags    []string `query:"tags,default=a,b"`
+		Name    *string  `query:"name,default=anon"`
+		Query   string   `query:"q"`
+		Filter  Filter   `query:"filter"`
+		Omitted string   `query:"omitted,omitempty,default=x"`
+	}
+
+	name := "anon"
+	defaults := ListParams{
+		Page: 1, Size: 20, Active: true, Ratio: 0.5, Tags: []string{"a,b"}, Name: &name,
+		Filter: Filter{Sort: "created"}, Omitted: "x",
+	}
+
+	tests := []struct {
+		name  string
+		query string
+		want  ListParams
+	}{
+		{"absent keys get defaults", "q=go&filter[order]=asc", func() ListParams {
+			p := defaults
+			p.Query = "go"
+			p.Filter.Order = "asc"
+			return p
+		}()},
+		{"present keys override", "page=3&size=50&active=false&ratio=2&tags[]=x&name=bob&filter[sort]=name&filter[order]=desc&omitted=y", func() ListParams {
+			bob := "bob"
+			return ListParams{
+				Page: 3, Size: 50, Active: false, Ratio: 2, Tags: []string{"x"}, Name: &bob,
+				Filter: Filter{Sort: "name", Order: "desc"}, Omitted: "y",
+			}
+		}()},
+		{"empty value is present", "page=&filter[sort]=", func() ListParams {
+			p := defaults
+			p.Page = 0
+			p.Filter = Filter{}
+			return p
+		}()},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var viaMap ListParams
+			if err := ParseToStruct(tt.query, &viaMap); err != nil {
+				t.Fatalf("ParseToStruct() error = %v", err)
+			}
+			if !reflect.DeepEqual(viaMap, tt.want) {
+				t.Errorf("ParseToStruct() = %+v, want %+v", viaMap, tt.want)
+			}
+
+			var direct ListParams
+			if err := Unmarshal(tt.query, &direct); err != nil {
+				t.Fatalf("Unmarshal() error = %v", err)
+			}
+			if !reflect.DeepEqual(direct, tt.want) {
+				t.Errorf("Unmarshal() = %+v, want %+v", direct, tt.want)
+			}
+		})
+	}
+
+	type Bad struct {
+		Page int `query:"page,default=abc"`
+	}
+	var bad Bad
+	if err := ParseToStruct("", &bad); err == nil {
+		t.Error("ParseToStruct() expected error for invalid default")
+	}
+	if err := Unmarshal("", &bad); err == nil {
+		t.Error("Unmarshal() expected error for invalid default")
+	}
+}
+
+// TestFieldError tests conversion errors carry key and field context
+func TestFieldError(t *testing.T) {
+	type Inner struct {
+		Size int `query:"size"`
+	}
+	type Params struct {
+		Page   int     `query:"page"`
+		Active bool    `query:"active"`
+		Ratio  float64 `query:"r"`
+		Filter Inner   `query:"filter"`
+		Limit  int     `query:"limit,default=ten"`
+	}
+
+	tests := []struct {
+		name      string
+		query     string
+		wantKey   string
+		wantField string
+		wantType  reflect.Type
+	}{
+		{"int", "page=abc", "page", "Page", reflect.TypeOf(0)},
+		{"bool", "active=maybe", "active", "Active", reflect.TypeOf(false)},
+		{"tag name differs", "r=x", "r", "Ratio", reflect.TypeOf(0.0)},
+		{"nested", "filter[size]=big", "filter[size]", "Filter.Size", reflect.TypeOf(0)},
+		{"invalid default", "page=1", "limit", "Limit", reflect.TypeOf(0)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for name, parse := range map[string]func(string, any) error{
+				"ParseToStruct": func(q string, dest any) error { return ParseToStruct(q, dest) },
+				"Unmarshal":     func(q string, dest any) error { return Unmarshal(q, dest) },
+			} {
+				var p Params
+				err := parse(tt.query, &p)
+				var fe *FieldError
+				if !errors.As(err, &fe) {
+					t.Fatalf("%s: expected *FieldError, got %v", name, err)
+				}
+				if fe.Key != tt.wantKey || fe.Field != tt.wantField || fe.TargetType != tt.wantType {
+					t.Errorf("%s: got key %q, field %q, type %v; want %q, %q, %v", name, fe.Key, fe.Field, fe.TargetType, tt.wantKey, tt.wantField, tt.wantType)
+				}
+				if fe.Err == nil || !strings.Contains(err.Error(), tt.wantField) || !strings.Contains(err.Error(), tt.wantKey) {
+					t.Errorf("%s: error %q should name key and field", name, err)
+				}
+			}
+		})
+	}
+
+	var p Params
+	err := ParseToStruct("page=abc", &p)
+	var numErr *strconv.NumError
+	if !errors.As(err, &numErr) {
+		t.Errorf("expected underlying *strconv.NumError, got %v", err)
+	}
+}
+
+// TestParseToStructEmbedded tests that embedded struct fields are promoted
+func TestParseToStructEmbedded(t *testing.T) {
+	type Pagination struct {
+		Page  int `query:"page,default=1"`
+		Limit int `query:"limit,default=20"`
+	}
+	type Sorting struct {
+		Sort string `query:"sort"`
+	}
+	type Nested struct {
+		Pagination
+	}
+	type ListParams struct {
+		Pagination
+		*Sorting
+		Query string `query:"q"`
+		Limit int    `query:"limit"`
+		Inner Nested `query:"inner"`
+	}
+
+	for name, parse := range map[string]func(string, any) error{
+		"ParseToStruct": func(q string, dest any) error { return ParseToStruct(q, dest) },
+		"Unmarshal":     func(q string, dest any) error { return Unmarshal(q, dest) },
+	} {
+		t.Run(name, func(t *testing.T) {
+			var p ListParams
+			if err := parse("page=3&sort=name&q=go&limit=5&inner[page]=7", &p); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if p.Page != 3 {
+				t.Errorf("Page = %d, want 3", p.Page)
+			}
+			if p.Sorting == nil || p.Sort != "name" {
+				t.Errorf("Sorting = %+v, want Sort name", p.Sorting)
+			}
+			if p.Query != "go" {
+				t.Errorf("Query = %q, want go", p.Query)
+			}
+			// The outer field shadows the promoted one, which is left untouched
+			if p.Limit != 5 || p.Pagination.Limit != 0 {
+				t.Errorf("Limit = %d, Pagination.Limit = %d; want 5, 0", p.Limit, p.Pagination.Limit)
+			}
+			if p.Inner.Page != 7 || p.Inner.Limit != 20 {
+				t.Errorf("Inner = %+v, want Page 7, Limit 20", p.Inner)
+			}
+
+			var empty ListParams
+			if err := parse("q=go", &empty); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if empty.Page != 1 || empty.Sorting != nil {
+				t.Errorf("got Page %d, Sorting %+v; want 1, nil", empty.Page, empty.Sorting)
+			}
+		})
+	}
+}
+
+func TestParseIntoTags(t *testing.T) {
+	type Profile struct {
+		Name string `qs:"name"`
+		Age  int    `qs:"age"`
+	}
+	type DTO struct {
+		Years   int      `qs:"years"`
+		User    Profile  `qs:"user"`
+		Tags    []string `qs:"tags"`
+		Label   string   `json:"label"`
+		Page    int      `query:"p" qs:"page" json:"pg"`
+		Skipped string   `qs:"-"`
+	}
+
+	for name, parse := range map[string]func(string, any) error{
+		"ParseInto":     func(q string, dest any) error { return ParseInto(q, dest) },
+		"ParseToStruct": func(q string, dest any) error { return ParseToStruct(q, dest) },
+		"Unmarshal":     func(q string, dest any) error { return Unmarshal(q, dest) },
+	} {
+		t.Run(name, func(t *testing.T) {
+			var d DTO
+			if err := parse("years=5&user[name]=x&user[age]=30&tags[]=a&tags[]=b&label=l&p=2&page=9&skipped=s", &d); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			want := DTO{Years: 5, User: Profile{Name: "x", Age: 30}, Tags: []string{"a", "b"}, Label: "l", Page: 2}
+			if !reflect.DeepEqual(d, want) {
+				t.Errorf("got %+v, want %+v", d, want)
+			}
+		})
+	}
+
+	t.Run("conversion error", func(t *testing.T) {
+		var d DTO
+		var fe *FieldError
+		if err := ParseInto("years=many", &d); !errors.As(err, &fe) || fe.Field != "Years" {
+			t.Errorf("got error %v, want *FieldError for Years", err)
+		}
+	})
+}
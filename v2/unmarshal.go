@@ -6,75 +6,165 @@ package qs
 import (
 	"fmt"
 	"reflect"
+	"sort"
 	"strings"
 	"sync"
+	"time"
 
 	"github.com/zaytracom/qs/v2/lang"
 )
 
 // structInfo caches field information for a struct type.
 type structInfo struct {
-	fields map[string]fieldInfo // query tag → field info
+	fields   map[string]fieldInfo // query tag → field info
+	order    []string             // query tags in field order
+	defaults []string             // query tags of fields with a default
+	rest     *fieldInfo           // map field collecting unmatched keys
 }
 
 // fieldInfo holds information about a single struct field.
 type fieldInfo struct {
-	index     []int        // field index path for embedded structs
-	fieldType reflect.Type // field type
+	index      []int        // field index path for embedded structs
+	name       string       // Go field name
+	fieldType  reflect.Type // field type
+	def        string       // default value from the tag
+	hasDefault bool         // whether the tag sets a default
+}
+
+// structInfoKey identifies cached struct info by type and tag name.
+type structInfoKey struct {
+	t       reflect.Type
+	tagName string
 }
 
 // typeCache caches struct information to avoid repeated reflection.
-var typeCache sync.Map // map[reflect.Type]*structInfo
+var typeCache sync.Map // map[structInfoKey]*structInfo
 
 // getStructInfo returns cached struct info or builds it.
-func getStructInfo(t reflect.Type) *structInfo {
-	if cached, ok := typeCache.Load(t); ok {
+func getStructInfo(t reflect.Type, tagName string) *structInfo {
+	key := structInfoKey{t: t, tagName: tagName}
+	if cached, ok := typeCache.Load(key); ok {
 		return cached.(*structInfo)
 	}
 
-	info := buildStructInfo(t)
-	typeCache.Store(t, info)
+	info := buildStructInfo(t, tagName)
+	typeCache.Store(key, info)
 	return info
 }
 
-// buildStructInfo builds struct info via reflection.
-func buildStructInfo(t reflect.Type) *structInfo {
+// buildStructInfo builds struct info via reflection, reading field names
+// from tags as structTag does.
+//
+// Fields of embedded structs without a tag name are promoted to the parent,
+// as in encoding/json. Shallower fields take precedence over promoted ones
+// with the same name.
+func buildStructInfo(t reflect.Type, tagName string) *structInfo {
 	info := &structInfo{
 		fields: make(map[string]fieldInfo),
 	}
 
-	for i := 0; i < t.NumField(); i++ {
-		field := t.Field(i)
+	// Walk embedded structs breadth-first so shallower fields win
+	type embedded struct {
+		t     reflect.Type
+		index []int
+	}
+	level := []embedded{{t: t}}
+	visited := map[reflect.Type]bool{}
+	for len(level) > 0 {
+		var next []embedded
+		for _, e := range level {
+			if visited[e.t] {
+				continue
+			}
+			visited[e.t] = true
 
-		// Skip unexported fields
-		if !field.IsExported() {
-			continue
-		}
+			for i := 0; i < e.t.NumField(); i++ {
+				field := e.t.Field(i)
+				index := append(e.index[:len(e.index):len(e.index)], i)
 
-		// Get query tag
-		tag := field.Tag.Get("query")
-		if tag == "-" {
-			continue
-		}
+				// Get query tag
+				tag := structTag(field, tagName)
+				if tag == "-" {
+					continue
+				}
 
-		// Parse tag options
-		name := tag
-		if idx := strings.Index(tag, ","); idx != -1 {
-			name = tag[:idx]
-		}
-		if name == "" {
-			name = strings.ToLower(field.Name)
-		}
+				// Parse tag options
+				name := tag
+				if idx := strings.Index(tag, ","); idx != -1 {
+					name = tag[:idx]
+				}
+
+				// Promote fields of untagged embedded structs
+				if field.Anonymous && name == "" {
+					ft := field.Type
+					if ft.Kind() == reflect.Ptr {
+						ft = ft.Elem()
+					}
+					if ft.Kind() == reflect.Struct && ft != reflect.TypeOf(time.Time{}) {
+						next = append(next, embedded{t: ft, index: index})
+						continue
+					}
+				}
+
+				// Skip unexported fields
+				if !field.IsExported() {
+					continue
+				}
+
+				// Collect unmatched keys in the first rest field
+				if hasQueryTagOption(field, tagName, "rest") && field.Type.Kind() == reflect.Map {
+					if info.rest == nil {
+						info.rest = &fieldInfo{index: index, name: field.Name, fieldType: field.Type}
+					}
+					continue
+				}
 
-		info.fields[name] = fieldInfo{
-			index:     field.Index,
-			fieldType: field.Type,
+				if name == "" {
+					name = strings.ToLower(field.Name)
+				}
+				if _, exists := info.fields[name]; exists {
+					continue
+				}
+
+				def, hasDefault := queryTagDefault(tag)
+				info.fields[name] = fieldInfo{
+					index:      index,
+					name:       field.Name,
+					fieldType:  field.Type,
+					def:        def,
+					hasDefault: hasDefault,
+				}
+				info.order = append(info.order, name)
+				if hasDefault {
+					info.defaults = append(info.defaults, name)
+				}
+			}
 		}
+		level = next
 	}
 
 	return info
 }
 
+// fieldByIndex returns the field of v at index like reflect.Value.FieldByIndex,
+// allocating nil embedded struct pointers on the way. It returns false if a
+// nil pointer cannot be allocated.
+func fieldByIndex(v reflect.Value, index []int) (reflect.Value, bool) {
+	for i, x := range index {
+		if i > 0 && v.Kind() == reflect.Ptr {
+			if v.IsNil() {
+				if !v.CanSet() {
+					return reflect.Value{}, false
+				}
+				v.Set(reflect.New(v.Type().Elem()))
+			}
+			v = v.Elem()
+		}
+		v = v.Field(x)
+	}
+	return v, true
+}
+
 // UnmarshalOptions configures the behavior of UnmarshalBytes/UnmarshalString.
 type UnmarshalOptions struct {
 	ParseOptions // embed ParseOptions
@@ -120,6 +210,9 @@ func UnmarshalBytes(data []byte, dest any, opts ...ParseOption) error {
 	if err != nil {
 		return err
 	}
+	if err := checkInputLength(len(data), &normalizedOpts); err != nil {
+		return err
+	}
 
 	// Build AST config
 	cfg := buildLangConfig(&normalizedOpts)
@@ -202,7 +295,7 @@ func (u *unmarshaler) unmarshalTo(rv reflect.Value) error {
 
 // unmarshalToStruct unmarshals AST directly into a struct.
 func (u *unmarshaler) unmarshalToStruct(rv reflect.Value) error {
-	info := getStructInfo(rv.Type())
+	info := getStructInfo(rv.Type(), u.opts.TagName)
 
 	// Group params by root key
 	type paramGroup struct {
@@ -234,26 +327,72 @@ func (u *unmarshaler) unmarshalToStruct(rv reflect.Value) error {
 	}
 
 	// Process each group
+	var rest []int
 	for _, rootKey := range groupOrder {
 		group := groups[rootKey]
 
 		// Find matching field
 		fi, ok := info.fields[rootKey]
 		if !ok {
+			if info.rest != nil {
+				rest = append(rest, group.params...)
+			}
 			continue // ignore unknown fields
 		}
 
-		field := rv.FieldByIndex(fi.index)
-		if !field.CanSet() {
+		field, ok := fieldByIndex(rv, fi.index)
+		if !ok || !field.CanSet() {
 			continue
 		}
 
 		// Handle based on field type and param structure
 		if err := u.setFieldFromParams(field, group.params); err != nil {
-			return fmt.Errorf("error setting field %s: %w", rootKey, err)
+			return newFieldError(rootKey, fi.name, fi.fieldType, err)
 		}
 	}
 
+	if err := u.setRestField(rv, info, rest); err != nil {
+		return err
+	}
+
+	return u.applyStructDefaults(rv, info, func(name string) bool {
+		_, ok := groups[name]
+		return ok
+	})
+}
+
+// setRestField sets the rest field of a struct, if it has one, to the
+// params no other field matched.
+func (u *unmarshaler) setRestField(rv reflect.Value, info *structInfo, params []int) error {
+	if info.rest == nil || len(params) == 0 {
+		return nil
+	}
+	field, ok := fieldByIndex(rv, info.rest.index)
+	if !ok || !field.CanSet() {
+		return nil
+	}
+	sort.Ints(params)
+	if err := setFieldValue(field, u.paramsToMap(params), u.opts.TagName); err != nil {
+		return newFieldError(info.rest.name, info.rest.name, info.rest.fieldType, err)
+	}
+	return nil
+}
+
+// applyStructDefaults sets fields with a tag default whose key is not present.
+func (u *unmarshaler) applyStructDefaults(rv reflect.Value, info *structInfo, present func(name string) bool) error {
+	for _, name := range info.defaults {
+		if present(name) {
+			continue
+		}
+		fi := info.fields[name]
+		field, ok := fieldByIndex(rv, fi.index)
+		if !ok || !field.CanSet() {
+			continue
+		}
+		if err := setFieldValue(field, fi.def, u.opts.TagName); err != nil {
+			return newFieldError(name, fi.name, fi.fieldType, fmt.Errorf("invalid default: %w", err))
+		}
+	}
 	return nil
 }
 
@@ -299,7 +438,7 @@ func (u *unmarshaler) setFieldFromParams(field reflect.Value, paramIndices []int
 // setSimpleValue sets a simple (non-nested) field value.
 func (u *unmarshaler) setSimpleValue(field reflect.Value, param lang.Param) error {
 	val := u.extractValue(param)
-	return setFieldValue(field, val)
+	return setFieldValue(field, val, u.opts.TagName)
 }
 
 // extractValue extracts value from param.
@@ -336,7 +475,7 @@ func (u *unmarshaler) extractValue(param lang.Param) any {
 
 // unmarshalNestedStruct handles nested struct fields.
 func (u *unmarshaler) unmarshalNestedStruct(field reflect.Value, paramIndices []int) error {
-	info := getStructInfo(field.Type())
+	info := getStructInfo(field.Type(), u.opts.TagName)
 
 	// Group by second segment
 	type paramGroup struct {
@@ -371,18 +510,21 @@ func (u *unmarshaler) unmarshalNestedStruct(field reflect.Value, paramIndices []
 			continue
 		}
 
-		nestedField := field.FieldByIndex(fi.index)
-		if !nestedField.CanSet() {
+		nestedField, ok := fieldByIndex(field, fi.index)
+		if !ok || !nestedField.CanSet() {
 			continue
 		}
 
 		// Shift segments and recurse
 		if err := u.setNestedFieldFromParams(nestedField, group.params, 1); err != nil {
-			return err
+			return newFieldError(key, fi.name, fi.fieldType, err)
 		}
 	}
 
-	return nil
+	return u.applyStructDefaults(field, info, func(name string) bool {
+		_, ok := groups[name]
+		return ok
+	})
 }
 
 // setNestedFieldFromParams sets a field considering segment depth.
@@ -439,7 +581,7 @@ func (u *unmarshaler) setNestedFieldFromParams(field reflect.Value, paramIndices
 
 // unmarshalNestedStructAtDepth handles struct at specific depth.
 func (u *unmarshaler) unmarshalNestedStructAtDepth(field reflect.Value, paramIndices []int, depth int) error {
-	info := getStructInfo(field.Type())
+	info := getStructInfo(field.Type(), u.opts.TagName)
 
 	groups := make(map[string][]int)
 	groupOrder := make([]string, 0)
@@ -467,17 +609,20 @@ func (u *unmarshaler) unmarshalNestedStructAtDepth(field reflect.Value, paramInd
 			continue
 		}
 
-		nestedField := field.FieldByIndex(fi.index)
-		if !nestedField.CanSet() {
+		nestedField, ok := fieldByIndex(field, fi.index)
+		if !ok || !nestedField.CanSet() {
 			continue
 		}
 
 		if err := u.setNestedFieldFromParams(nestedField, indices, depth+1); err != nil {
-			return err
+			return newFieldError(key, fi.name, fi.fieldType, err)
 		}
 	}
 
-	return nil
+	return u.applyStructDefaults(field, info, func(name string) bool {
+		_, ok := groups[name]
+		return ok
+	})
 }
 
 // unmarshalNestedMap handles map fields at root level.
@@ -638,11 +783,30 @@ func (u *unmarshaler) unmarshalToMap(rv reflect.Value) error {
 		rv.Set(reflect.MakeMap(rv.Type()))
 	}
 
+	// Set result
+	for k, v := range u.paramsToMap(nil) {
+		rv.SetMapIndex(reflect.ValueOf(k), reflect.ValueOf(v))
+	}
+
+	return nil
+}
+
+// paramsToMap builds the nested map of the params at indices, or of all
+// params if indices is nil.
+func (u *unmarshaler) paramsToMap(indices []int) map[string]any {
 	// For map, we need to build the nested structure
 	// This is similar to the current Parse but more direct
 	result := make(map[string]any)
 
-	for i := uint16(0); i < u.qs.ParamLen; i++ {
+	n := int(u.qs.ParamLen)
+	if indices != nil {
+		n = len(indices)
+	}
+	for k := 0; k < n; k++ {
+		i := k
+		if indices != nil {
+			i = indices[k]
+		}
 		param := u.arena.Params[i]
 		if param.Key.SegLen == 0 {
 			continue
@@ -696,11 +860,6 @@ func (u *unmarshaler) unmarshalToMap(rv reflect.Value) error {
 		result = Compact(result).(map[string]any)
 	}
 
-	// Set result
-	for k, v := range result {
-		rv.SetMapIndex(reflect.ValueOf(k), reflect.ValueOf(v))
-	}
-
-	return nil
+	return result
 }
 
@@ -190,6 +190,59 @@ func TestUnmarshalBytes_Pointer(t *testing.T) {
 	}
 }
 
+func TestUnmarshal_PointerStructs(t *testing.T) {
+	type Leaf struct {
+		Value string `query:"value"`
+	}
+	type Mid struct {
+		Name string `query:"name"`
+		Leaf *Leaf  `query:"leaf"`
+	}
+	type Request struct {
+		Mid   *Mid    `query:"mid"`
+		Other *Mid    `query:"other"`
+		Items []*Leaf `query:"items"`
+		PP    **Leaf  `query:"pp"`
+	}
+
+	var req Request
+	err := Unmarshal("mid[name]=a&mid[leaf][value]=b&items[0][value]=x&items[1][value]=y&pp[value]=z", &req)
+	if err != nil {
+		t.Fatalf("Unmarshal: %v", err)
+	}
+
+	if req.Mid == nil || req.Mid.Name != "a" {
+		t.Fatalf("Mid: got %+v, want Name %q", req.Mid, "a")
+	}
+	if req.Mid.Leaf == nil || req.Mid.Leaf.Value != "b" {
+		t.Errorf("Mid.Leaf: got %+v, want Value %q", req.Mid.Leaf, "b")
+	}
+	if req.Other != nil {
+		t.Errorf("Other: got %+v, want nil", req.Other)
+	}
+	if len(req.Items) != 2 || req.Items[0] == nil || req.Items[1] == nil {
+		t.Fatalf("Items: got %v, want 2 non-nil elements", req.Items)
+	}
+	if req.Items[0].Value != "x" || req.Items[1].Value != "y" {
+		t.Errorf("Items: got %q, %q, want %q, %q", req.Items[0].Value, req.Items[1].Value, "x", "y")
+	}
+	if req.PP == nil || *req.PP == nil || (*req.PP).Value != "z" {
+		t.Errorf("PP: got %v, want Value %q", req.PP, "z")
+	}
+
+	// Pointers without keys in the query stay nil
+	var empty Request
+	if err := Unmarshal("mid[name]=a", &empty); err != nil {
+		t.Fatalf("Unmarshal: %v", err)
+	}
+	if empty.Mid == nil || empty.Mid.Leaf != nil {
+		t.Errorf("Mid: got %+v, want non-nil with nil Leaf", empty.Mid)
+	}
+	if empty.Other != nil || empty.Items != nil || empty.PP != nil {
+		t.Errorf("got %+v, want nil Other, Items and PP", empty)
+	}
+}
+
 func TestUnmarshalBytes_URLEncoded(t *testing.T) {
 	type User struct {
 		Name  string `query:"name"`
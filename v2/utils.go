@@ -19,6 +19,11 @@ type explicitNull struct{}
 // ExplicitNullValue is the sentinel used to mark explicit null values.
 var ExplicitNullValue = explicitNull{}
 
+// MarshalJSON writes explicit nulls as JSON null.
+func (explicitNull) MarshalJSON() ([]byte, error) {
+	return []byte("null"), nil
+}
+
 // IsExplicitNull checks if a value is the explicit null marker.
 func IsExplicitNull(v any) bool {
 	_, ok := v.(explicitNull)
@@ -75,12 +80,27 @@ func isUnreservedChar(c byte, format Format) bool {
 // For UTF-8 (default), multi-byte characters are encoded as multiple %XX sequences.
 // For ISO-8859-1, characters outside the Latin-1 range are encoded as numeric entities (&#xxxx;).
 func Encode(str string, charset Charset, format Format) string {
+	return EncodeSafe(str, charset, format, nil)
+}
+
+// SafeCharFunc reports whether a rune may be left unencoded.
+type SafeCharFunc func(r rune) bool
+
+// EncodeSafe encodes a string like Encode, but uses safe instead of the
+// format's unreserved set to decide which runes are written as-is.
+// All other runes are percent-encoded (or written as numeric entities for
+// ISO-8859-1 runes outside Latin-1). A nil safe behaves like Encode.
+func EncodeSafe(str string, charset Charset, format Format, safe SafeCharFunc) string {
 	if len(str) == 0 {
 		return str
 	}
 
 	if charset == CharsetISO88591 {
-		return encodeISO88591(str)
+		return encodeISO88591(str, safe)
+	}
+
+	if safe != nil {
+		return encodeUTF8Safe(str, safe)
 	}
 
 	// UTF-8 encoding
@@ -123,14 +143,37 @@ func Encode(str string, charset Charset, format Format) string {
 	return result.String()
 }
 
+// encodeUTF8Safe percent-encodes every rune of str for which safe returns false.
+func encodeUTF8Safe(str string, safe SafeCharFunc) string {
+	var result strings.Builder
+	result.Grow(len(str) * 3)
+
+	for i := 0; i < len(str); {
+		r, size := utf8.DecodeRuneInString(str[i:])
+		if !(r == utf8.RuneError && size == 1) && safe(r) {
+			result.WriteString(str[i : i+size])
+		} else {
+			for j := 0; j < size; j++ {
+				result.WriteString(hexTable[str[i+j]])
+			}
+		}
+		i += size
+	}
+
+	return result.String()
+}
+
 // encodeISO88591 encodes a string using ISO-8859-1 charset.
 // Characters outside the Latin-1 range (0x00-0xFF) are encoded as numeric HTML entities.
-func encodeISO88591(str string) string {
+// If safe is non-nil, runes it accepts are written as-is.
+func encodeISO88591(str string, safe SafeCharFunc) string {
 	var result strings.Builder
 	result.Grow(len(str) * 6) // Pre-allocate for entities
 
 	for _, r := range str {
-		if r <= 0xFF {
+		if safe != nil && safe(r) {
+			result.WriteRune(r)
+		} else if r <= 0xFF {
 			// Character is in Latin-1 range
 			if isUnreservedChar(byte(r), FormatRFC3986) {
 				result.WriteRune(r)
@@ -212,6 +255,72 @@ func Decode(str string, charset Charset) string {
 	return decoded
 }
 
+// escapeBarePercent replaces each % that does not start a valid %XX escape with %25.
+func escapeBarePercent(str string) string {
+	if strings.IndexByte(str, '%') < 0 {
+		return str
+	}
+
+	var b strings.Builder
+	b.Grow(len(str) + 4)
+	for i := 0; i < len(str); i++ {
+		if str[i] == '%' && (i+2 >= len(str) || unhex(str[i+1]) < 0 || unhex(str[i+2]) < 0) {
+			b.WriteString("%25")
+			continue
+		}
+		b.WriteByte(str[i])
+	}
+	return b.String()
+}
+
+// isASCII reports whether str contains only ASCII bytes.
+func isASCII(str string) bool {
+	for i := 0; i < len(str); i++ {
+		if str[i] >= utf8.RuneSelf {
+			return false
+		}
+	}
+	return true
+}
+
+// escapeNonASCII percent-encodes the non-ASCII runes in str as Encode
+// would for charset, leaving ASCII bytes as they are. Under UTF-8 each
+// byte is escaped, so invalid UTF-8 is preserved.
+func escapeNonASCII(str string, charset Charset) string {
+	if isASCII(str) {
+		return str
+	}
+
+	var b strings.Builder
+	b.Grow(len(str) * 3)
+	for i := 0; i < len(str); {
+		c := str[i]
+		if c < utf8.RuneSelf {
+			b.WriteByte(c)
+			i++
+			continue
+		}
+		if charset != CharsetISO88591 {
+			b.WriteString(hexTable[c])
+			i++
+			continue
+		}
+		r, size := utf8.DecodeRuneInString(str[i:])
+		switch {
+		case r == utf8.RuneError && size == 1:
+			b.WriteString(hexTable[c])
+		case r <= 0xFF:
+			b.WriteString(hexTable[byte(r)])
+		default:
+			b.WriteString("%26%23")
+			writeDecimal(&b, int(r))
+			b.WriteString("%3B")
+		}
+		i += size
+	}
+	return b.String()
+}
+
 // decodeISO88591 decodes a percent-encoded string as ISO-8859-1.
 // Each %XX is interpreted as a Latin-1 byte, which maps directly to Unicode U+0000-U+00FF.
 func decodeISO88591(str string) string {
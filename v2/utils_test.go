@@ -92,6 +92,45 @@ func TestEncode(t *testing.T) {
 	}
 }
 
+// TestEncodeSafe tests encoding with a caller-provided safe-character predicate.
+func TestEncodeSafe(t *testing.T) {
+	alnum := func(r rune) bool {
+		return (r >= 'a' && r <= 'z') || (r >= 'A' && r <= 'Z') || (r >= '0' && r <= '9')
+	}
+	keepColonSlash := func(r rune) bool {
+		return alnum(r) || r == ':' || r == '/'
+	}
+	keepUnicode := func(r rune) bool {
+		return r > 0x7F
+	}
+
+	tests := []struct {
+		name     string
+		input    string
+		charset  Charset
+		safe     SafeCharFunc
+		expected string
+	}{
+		{"nil behaves like Encode", "a b~", CharsetUTF8, nil, "a%20b~"},
+		{"unreserved not implied", "a-b_c.d~", CharsetUTF8, alnum, "a%2Db%5Fc%2Ed%7E"},
+		{"extra safe chars", "http://x/y?z", CharsetUTF8, keepColonSlash, "http://x/y%3Fz"},
+		{"safe multi-byte rune", "é☺", CharsetUTF8, keepUnicode, "é☺"},
+		{"unsafe multi-byte rune", "é", CharsetUTF8, alnum, "%C3%A9"},
+		{"invalid utf8", "a\xffb", CharsetUTF8, func(rune) bool { return true }, "a%FFb"},
+		{"iso safe", "é:", CharsetISO88591, keepColonSlash, "%E9:"},
+		{"iso non-latin safe", "☺", CharsetISO88591, keepUnicode, "☺"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := EncodeSafe(tt.input, tt.charset, FormatRFC3986, tt.safe)
+			if result != tt.expected {
+				t.Errorf("EncodeSafe(%q, %q) = %q, want %q", tt.input, tt.charset, result, tt.expected)
+			}
+		})
+	}
+}
+
 // TestDecode tests URL decoding with different charsets.
 func TestDecode(t *testing.T) {
 	tests := []struct {
@@ -0,0 +1,119 @@
+// Copyright 2025 Zaytra
+// SPDX-License-Identifier: Apache-2.0
+
+package qs
+
+import (
+	"fmt"
+	"strings"
+)
+
+// WarningCode identifies the kind of a parse Warning.
+type WarningCode string
+
+const (
+	// WarningParameterLimit reports parameters dropped because the input
+	// has more than ParameterLimit of them.
+	WarningParameterLimit WarningCode = "parameter_limit"
+)
+
+// Warning describes input that Parse accepted but did not fully honor.
+type Warning struct {
+	Code    WarningCode
+	Message string
+
+	// Index is the zero-based position, among the input's parameters, of
+	// the first one that was dropped.
+	Index int
+
+	// Dropped is how many parameters were dropped.
+	Dropped int
+}
+
+// String returns the warning message.
+func (w Warning) String() string {
+	return w.Message
+}
+
+// ParseWithWarnings parses str like Parse and also reports problems that
+// Parse handles silently, such as parameters dropped by ParameterLimit.
+// With ThrowOnLimitExceeded, exceeding the limit is an error as in Parse.
+//
+// Example:
+//
+//	result, warnings, err := qs.ParseWithWarnings("a=1&b=2&c=3", qs.WithParseParameterLimit(2))
+//	// result = map[string]any{"a": "1", "b": "2"}
+//	// warnings[0].Index = 2, warnings[0].Dropped = 1
+func ParseWithWarnings(str string, opts ...ParseOption) (map[string]any, []Warning, error) {
+	options := applyParseOptions(opts...)
+
+	normalizedOpts, err := normalizeParseOptions(&options)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	result, err := parseNormalized(str, &normalizedOpts)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var warnings []Warning
+	if dropped := droppedParams(str, &normalizedOpts); dropped > 0 {
+		warnings = append(warnings, Warning{
+			Code:    WarningParameterLimit,
+			Message: fmt.Sprintf("parameter limit %d exceeded: dropped %d parameters from index %d", normalizedOpts.ParameterLimit, dropped, normalizedOpts.ParameterLimit),
+			Index:   normalizedOpts.ParameterLimit,
+			Dropped: dropped,
+		})
+	}
+	return result, warnings, nil
+}
+
+// droppedParams returns how many non-empty parameters of str were dropped
+// by ParameterLimit, counting them the way the parser that handles opts
+// does: the split-based parser limits raw parts, while lang.Parse limits
+// non-empty parameters other than the charset sentinel.
+func droppedParams(str string, opts *ParseOptions) int {
+	if opts.IgnoreQueryPrefix && strings.HasPrefix(str, "?") {
+		str = str[1:]
+	}
+	limit := opts.ParameterLimit
+
+	if needsSplitParse(opts) {
+		parts := splitParams(str, opts, 0)
+		if len(parts) <= limit {
+			return 0
+		}
+		dropped := 0
+		for _, part := range parts[limit:] {
+			if part != "" {
+				dropped++
+			}
+		}
+		return dropped
+	}
+
+	// Cheap check before scanning: fewer delimiters than the limit means
+	// nothing can have been dropped
+	delimiter := opts.Delimiter
+	if strings.Count(str, delimiter) < limit {
+		return 0
+	}
+
+	seen := 0
+	for str != "" {
+		part, rest, _ := strings.Cut(str, delimiter)
+		str = rest
+		if part == "" || (opts.CharsetSentinel && isSentinel(part, opts)) {
+			continue
+		}
+		seen++
+	}
+	return max(seen-limit, 0)
+}
+
+// isSentinel reports whether part is a known charset sentinel.
+func isSentinel(part string, opts *ParseOptions) bool {
+	_, ok := sentinelCharset(part, opts)
+	return ok
+}
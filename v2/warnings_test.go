@@ -0,0 +1,65 @@
+// Copyright 2025 Zaytra
+// SPDX-License-Identifier: Apache-2.0
+
+package qs
+
+import (
+	"errors"
+	"reflect"
+	"regexp"
+	"testing"
+)
+
+func TestParseWithWarnings(t *testing.T) {
+	tests := []struct {
+		name        string
+		input       string
+		opts        []ParseOption
+		want        map[string]any
+		wantIndex   int
+		wantDropped int
+	}{
+		{"within limit", "a=1&b=2", []ParseOption{WithParseParameterLimit(2)}, map[string]any{"a": "1", "b": "2"}, 0, 0},
+		{"truncated", "a=1&b=2&c=3&d=4", []ParseOption{WithParseParameterLimit(2)}, map[string]any{"a": "1", "b": "2"}, 2, 2},
+		{"empty parts not counted", "a=1&&b=2&c=3&", []ParseOption{WithParseParameterLimit(2)}, map[string]any{"a": "1", "b": "2"}, 2, 1},
+		{"query prefix", "?a=1&b=2", []ParseOption{WithParseParameterLimit(1), WithParseIgnoreQueryPrefix(true)}, map[string]any{"a": "1"}, 1, 1},
+		{"sentinel not counted", "utf8=%E2%9C%93&a=1&b=2", []ParseOption{WithParseParameterLimit(2), WithParseCharsetSentinel(true)}, map[string]any{"a": "1", "b": "2"}, 0, 0},
+		{"split parser counts raw parts", "a=1;;;;b=2;;c=3", []ParseOption{WithParseParameterLimit(2), WithParseDelimiter(";;")}, map[string]any{"a": "1"}, 2, 2},
+		{"regexp delimiter", "a=1;b=2,c=3", []ParseOption{WithParseParameterLimit(1), WithParseDelimiterRegexp(regexp.MustCompile(`[;,]`))}, map[string]any{"a": "1"}, 1, 2},
+		{"multiple delimiters", "a=1;b=2&c=3", []ParseOption{WithParseParameterLimit(2), WithParseDelimiters([]string{";", "&"})}, map[string]any{"a": "1", "b": "2"}, 2, 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, warnings, err := ParseWithWarnings(tt.input, tt.opts...)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got %v, want %v", got, tt.want)
+			}
+			if tt.wantDropped == 0 {
+				if len(warnings) != 0 {
+					t.Errorf("unexpected warnings: %v", warnings)
+				}
+				return
+			}
+			if len(warnings) != 1 {
+				t.Fatalf("got %d warnings, want 1: %v", len(warnings), warnings)
+			}
+			w := warnings[0]
+			if w.Code != WarningParameterLimit || w.Index != tt.wantIndex || w.Dropped != tt.wantDropped {
+				t.Errorf("got %+v, want index %d, dropped %d", w, tt.wantIndex, tt.wantDropped)
+			}
+		})
+	}
+
+	_, warnings, _ := ParseWithWarnings("a=1&b=2&c=3", WithParseParameterLimit(1))
+	if want := "parameter limit 1 exceeded: dropped 2 parameters from index 1"; len(warnings) != 1 || warnings[0].String() != want {
+		t.Errorf("got %v, want %q", warnings, want)
+	}
+
+	if _, _, err := ParseWithWarnings("a=1&b=2", WithParseParameterLimit(1), WithParseThrowOnLimitExceeded(true)); !errors.Is(err, ErrParameterLimitExceeded) {
+		t.Errorf("expected ErrParameterLimitExceeded, got %v", err)
+	}
+}